@@ -0,0 +1,73 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+	"sync/atomic"
+
+	"github.com/costamauricio/6502-emulator/internal/cli"
+	"github.com/costamauricio/6502-emulator/pkg/acia6551"
+	"github.com/costamauricio/6502-emulator/pkg/apple1"
+	"github.com/costamauricio/6502-emulator/pkg/clock"
+)
+
+func main() {
+	at := flag.String("at", "E000", "Address in hexadecimal to load the program at")
+	frequency := flag.Int("freq", clock.FREQUENCY_1MHZ, "CPU clock in Hz, 0 runs unlimited")
+	flag.Usage = func() {
+		log.Print("usage: apple1 [-at address] [-freq hz] [program.bin]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	computer := apple1.New(acia6551.Stdio())
+
+	if flag.NArg() > 0 {
+		program, err := os.ReadFile(flag.Arg(0))
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		address, err := cli.ParseAddress(*at)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		computer.Load(program, address)
+	}
+
+	// the keys go straight to the keyboard, WozMon echoes them
+	restore := rawTerminal()
+	defer restore()
+
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+
+	var stopped atomic.Bool
+	go func() {
+		<-interrupts
+		stopped.Store(true)
+	}()
+
+	clock.New(*frequency, computer.Tick).Run(stopped.Load)
+	os.Stdout.WriteString("\n")
+}
+
+// Disables the line buffering and the echo of the terminal, returning the restore
+func rawTerminal() func() {
+	if err := stty("-icanon", "-echo", "min", "1"); err != nil {
+		return func() {}
+	}
+
+	return func() { stty("icanon", "echo") }
+}
+
+func stty(args ...string) error {
+	command := exec.Command("stty", args...)
+	command.Stdin = os.Stdin
+
+	return command.Run()
+}
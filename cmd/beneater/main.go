@@ -0,0 +1,113 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"sync/atomic"
+
+	"github.com/costamauricio/6502-emulator/pkg/acia6551"
+	"github.com/costamauricio/6502-emulator/pkg/beneater"
+	"github.com/costamauricio/6502-emulator/pkg/blockdev"
+	"github.com/costamauricio/6502-emulator/pkg/clock"
+	"github.com/costamauricio/6502-emulator/pkg/sdcard"
+)
+
+func main() {
+	lcd := flag.Int("lcd", 8, "LCD interface of the videos, 8 on the ports A and B or 4 on the port B")
+	serial := flag.String("serial", "", "ACIA connection: stdio, pty or a TCP address to listen, none by default")
+	frequency := flag.Int("freq", clock.FREQUENCY_1MHZ, "CPU clock in Hz, 0 runs unlimited")
+	sd := flag.String("sd", "", "Image of an SD card bit-banged on the port A, it needs the 4 bit LCD")
+	flag.Usage = func() {
+		log.Print("usage: beneater [-lcd 8|4] [-serial stdio|pty|address] [-freq hz] [-sd image] rom.bin")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	rom, err := os.ReadFile(flag.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	wiring := beneater.WIRING_8BIT
+	if *lcd == 4 {
+		wiring = beneater.WIRING_4BIT
+	}
+
+	computer := beneater.New(rom, openSerial(*serial), wiring)
+
+	if *sd != "" {
+		if *lcd != 4 {
+			log.Fatal("the SD card takes the port A, it needs -lcd 4")
+		}
+
+		image, err := blockdev.Open(*sd)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer image.Close()
+
+		sdcard.NewSPI(sdcard.New(image), sdcard.WIRING).ConnectA(computer.Via)
+	}
+
+	// the serial port on stdio takes the terminal, so the LCD isn't shown
+	if *serial != "stdio" {
+		shown := ""
+		computer.Lcd.OnChange = func() {
+			if frame := computer.Lcd.String(); frame != shown {
+				if shown != "" {
+					fmt.Printf("\x1b[%dA", strings.Count(shown, "\n"))
+				}
+
+				fmt.Print(frame)
+				shown = frame
+			}
+		}
+
+		computer.Lcd.OnChange()
+	}
+
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+
+	var stopped atomic.Bool
+	go func() {
+		<-interrupts
+		stopped.Store(true)
+	}()
+
+	clock.New(*frequency, computer.Tick).Run(stopped.Load)
+}
+
+func openSerial(serial string) acia6551.Endpoint {
+	switch serial {
+	case "":
+		return nil
+	case "stdio":
+		return acia6551.Stdio()
+	case "pty":
+		pty, err := acia6551.OpenPTY()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		log.Print("serial port on ", pty.Name)
+		return pty
+	}
+
+	listener, err := acia6551.Listen(serial)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Print("serial port listening on ", listener.Addr())
+	return listener
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/costamauricio/6502-emulator/internal/cli"
+	"github.com/costamauricio/6502-emulator/pkg/c64"
+)
+
+func main() {
+	entry := flag.String("entry", "", "Address in hexadecimal to call, defaults to the SYS of the BASIC stub or the load address")
+	directory := flag.String("dir", "", "Host directory of the files loaded by the program, defaults to the program one")
+	cycles := flag.Uint64("cycles", 0, "Cicles the program can run, 0 is unlimited")
+	flag.Usage = func() {
+		log.Print("usage: c64run [-entry address] [-dir path] [-cycles count] program.prg")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	program, err := os.ReadFile(flag.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	runner := c64.New(os.Stdout, os.Stdin)
+	runner.Limit = *cycles
+
+	runner.Directory = *directory
+	if runner.Directory == "" {
+		runner.Directory = filepath.Dir(flag.Arg(0))
+	}
+
+	start, err := runner.LoadPRG(program)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *entry != "" {
+		if start, err = cli.ParseAddress(*entry); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	reason, err := runner.Run(start)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if reason == c64.EXIT_BREAK {
+		log.Printf("BRK at $%04X", runner.Cpu.PC)
+	}
+}
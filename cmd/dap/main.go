@@ -0,0 +1,29 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/costamauricio/6502-emulator/pkg/dap"
+)
+
+func main() {
+	listen := flag.String("listen", "", "TCP address to listen for the editor, e.g. localhost:4711, uses stdin/stdout when empty")
+	verbose := flag.Bool("v", false, "Logs the received requests to stderr")
+	flag.Parse()
+
+	server := dap.New()
+	if *verbose {
+		server.Logger = log.New(os.Stderr, "dap: ", log.LstdFlags)
+	}
+
+	if *listen != "" {
+		log.Print("waiting for the editor on ", *listen)
+		log.Fatal(server.ListenAndServe(*listen))
+	}
+
+	if err := server.Serve(os.Stdin, os.Stdout); err != nil {
+		log.Fatal(err)
+	}
+}
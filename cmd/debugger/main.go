@@ -1,20 +1,143 @@
 package main
 
 import (
+	"flag"
+	"github.com/costamauricio/6502-emulator/internal/cli"
+	"github.com/costamauricio/6502-emulator/internal/visualizer"
 	"github.com/costamauricio/6502-emulator/pkg/bus"
 	"github.com/costamauricio/6502-emulator/pkg/cpu6502"
-	"github.com/costamauricio/6502-emulator/internal/visualizer"
+	"github.com/costamauricio/6502-emulator/pkg/debugger"
+	"github.com/costamauricio/6502-emulator/pkg/debuginfo"
+	"github.com/costamauricio/6502-emulator/pkg/framebuffer"
+	"github.com/costamauricio/6502-emulator/pkg/input"
+	"github.com/costamauricio/6502-emulator/pkg/replay"
+	"github.com/costamauricio/6502-emulator/pkg/symbols"
 	"log"
+	"os"
+	"strings"
 )
 
 func main() {
+	breakpoints := flag.String("break", "", "Comma separated list of breakpoint addresses in hexadecimal, e.g. 8007,$8009")
+	debugInfo := flag.String("dbg", "", "Debug information file (ca65 --dbgfile or JSON) to show labels and source lines")
+	symbolFiles := flag.String("symbols", "", "Comma separated label files (VICE, ld65 -Ln or name = $addr) or memory maps ("+strings.Join(symbols.Machines(), ", ")+")")
+	at := flag.String("at", "8000", "Address in hexadecimal to load the program at")
+	screen := flag.String("screen", "", "Size of the framebuffer, e.g. 32x32 or 64x64, none by default")
+	screenAt := flag.String("screen-at", "0200", "Address in hexadecimal of the framebuffer pixels")
+	paletteAt := flag.String("palette-at", "D000", "Address in hexadecimal of the framebuffer palette registers")
+	keyboardAt := flag.String("keyboard", "", "Address in hexadecimal of the keyboard key register, the strobe is the next one, none by default")
+	latch := flag.Bool("latch", false, "Maps only the keyboard key register without the strobe bit, like the easy6502 one at FF")
+	buttonsAt := flag.String("buttons", "", "Address in hexadecimal of the buttons register, none by default")
+	layout := flag.String("layout", "nes", "Layout of the buttons, nes, joystick or the name=bit bindings like Up=0,Down=1")
+	activeLow := flag.Bool("active-low", false, "The pressed buttons read 0")
+	record := flag.String("record", "", "Replay file logging the interrupts, resets and inputs, written on quit")
+	replayFile := flag.String("replay", "", "Replay file injecting the logged events at their cicles")
+	flag.Parse()
+
 	dataBus := bus.Bus{}
-	dataBus.LoadRamFromString("A9 0A 69 02 AA 86 01 E9 02 D0 FC 00", 0x8000)
-	dataBus.LoadRamFromString("00 80", 0xFFFC)
+	loadAt, err := cli.ParseAddress(*at)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// a sample program when none is given
+	program := []byte{0xA9, 0x0A, 0x69, 0x02, 0xAA, 0x86, 0x01, 0xE9, 0x02, 0xD0, 0xFC, 0x00}
+
+	if flag.NArg() > 0 {
+		if program, err = os.ReadFile(flag.Arg(0)); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	dataBus.LoadRam(program, loadAt)
+
+	// only sets the reset vector when the program didn't provide one
+	if dataBus.Read(0xFFFC) == 0 && dataBus.Read(0xFFFD) == 0 {
+		dataBus.Write(0xFFFC, byte(loadAt&0x00FF))
+		dataBus.Write(0xFFFD, byte(loadAt>>8))
+	}
+
+	// the screen can't shadow the program or the vectors
+	ranges := &cli.Ranges{}
+	ranges.Reserve("program", loadAt, len(program))
+	ranges.Reserve("vectors", 0xFFFA, 6)
+
+	var display *framebuffer.Framebuffer
+	if *screen != "" {
+		if display, err = cli.MapScreen(&dataBus, ranges, *screen, *screenAt, *paletteAt); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	keyboard, buttons, err := cli.MapInput(&dataBus, ranges, *keyboardAt, *latch, *buttonsAt, *layout, *activeLow)
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	cpu := cpu6502.New(&dataBus)
+	dbg := debugger.New(cpu, &dataBus)
+
+	for _, address := range strings.Split(*breakpoints, ",") {
+		if address = strings.TrimSpace(address); address == "" {
+			continue
+		}
+
+		parsed, err := cli.ParseAddress(address)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		dbg.Breakpoints.Add(debugger.Breakpoint{Address: parsed})
+	}
+
+	if *debugInfo != "" {
+		info, err := debuginfo.Load(*debugInfo)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		dbg.DebugInfo = info
+	}
+
+	for _, source := range strings.Split(*symbolFiles, ",") {
+		if source = strings.TrimSpace(source); source == "" {
+			continue
+		}
+
+		table, err := symbols.Open(source)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if dbg.Symbols == nil {
+			dbg.Symbols = symbols.New()
+		}
+
+		dbg.Symbols.Merge(table)
+	}
+
+	target := replay.Target{Cpu: cpu, Bus: &dataBus, Input: input.Apply(keyboard, buttons)}
+
+	if *record != "" {
+		dbg.Recorder = replay.NewRecorder(target)
+	}
+
+	if *replayFile != "" {
+		events, err := replay.Load(*replayFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		dbg.Tick = replay.NewReplayer(target, events).Tick
+	}
 
 	log.Print("CPU: ", cpu)
-	visualizer := visualizer.Visualizer{Cpu: cpu, Bus: &dataBus}
-	visualizer.Run(0x8000)
+	visualizer := visualizer.Visualizer{Cpu: cpu, Bus: &dataBus, Debugger: dbg, Framebuffer: display, Keyboard: keyboard, Buttons: buttons}
+	visualizer.Run(loadAt)
+
+	if dbg.Recorder != nil {
+		if err := dbg.Recorder.Save(*record); err != nil {
+			log.Fatal(err)
+		}
+	}
 }
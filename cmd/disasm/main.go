@@ -0,0 +1,101 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/costamauricio/6502-emulator/internal/cli"
+	"github.com/costamauricio/6502-emulator/pkg/bus"
+	"github.com/costamauricio/6502-emulator/pkg/disasm"
+	"github.com/costamauricio/6502-emulator/pkg/symbols"
+)
+
+func main() {
+	at := flag.String("at", "8000", "Address in hexadecimal to load the program at")
+	entries := flag.String("entry", "", "Comma separated entry points in hexadecimal, defaults to the load address when the program doesn't have the vectors")
+	symbolFiles := flag.String("symbols", "", "Comma separated label files (VICE, ld65 -Ln or name = $addr) or memory maps ("+strings.Join(symbols.Machines(), ", ")+")")
+	output := flag.String("o", "", "Output file for the source, defaults to the standard output")
+	flag.Usage = func() {
+		log.Print("usage: disasm [-at address] [-entry addresses] [-symbols files] [-o file.s] program.bin")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	program, err := os.ReadFile(flag.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	loadAt, err := cli.ParseAddress(*at)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if len(program) == 0 || int(loadAt)+len(program) > 0x10000 {
+		log.Fatalf("%d bytes don't fit at $%04X", len(program), loadAt)
+	}
+
+	dataBus := &bus.Bus{}
+	dataBus.LoadRam(program, loadAt)
+
+	disassembler := disasm.New(dataBus.Read, loadAt, uint16(int(loadAt)+len(program)-1))
+
+	table := symbols.New()
+	for _, source := range strings.Split(*symbolFiles, ",") {
+		if source = strings.TrimSpace(source); source == "" {
+			continue
+		}
+
+		loaded, err := symbols.Open(source)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		table.Merge(loaded)
+	}
+
+	disassembler.Symbols = table.Label
+
+	var traced bool
+	for _, entry := range strings.Split(*entries, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+
+		address, err := cli.ParseAddress(entry)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		disassembler.AddEntry(address, "")
+		traced = true
+	}
+
+	if int(loadAt)+len(program) == 0x10000 {
+		disassembler.AddVectors()
+		traced = true
+	}
+
+	if !traced {
+		disassembler.AddEntry(loadAt, "start")
+	}
+
+	out := os.Stdout
+	if *output != "" {
+		if out, err = os.Create(*output); err != nil {
+			log.Fatal(err)
+		}
+		defer out.Close()
+	}
+
+	if err := disassembler.WriteSource(out); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/costamauricio/6502-emulator/internal/cli"
+	"github.com/costamauricio/6502-emulator/pkg/bus"
+	"github.com/costamauricio/6502-emulator/pkg/cpu6502"
+	"github.com/costamauricio/6502-emulator/pkg/debugger"
+	"github.com/costamauricio/6502-emulator/pkg/gdbstub"
+)
+
+func main() {
+	listen := flag.String("listen", "localhost:1234", "TCP address to listen for the debugger")
+	at := flag.String("at", "8000", "Address in hexadecimal to load the program at")
+	entry := flag.String("entry", "", "Address in hexadecimal to start the execution, defaults to the load address when the reset vector is empty")
+	verbose := flag.Bool("v", false, "Logs the exchanged packets")
+	history := flag.Int("history", 0, "Instructions recorded for the reverse execution, 0 disables it")
+	flag.Usage = func() {
+		log.Print("usage: gdbserver [-listen address] [-at address] [-entry address] [-history depth] program.bin")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	program, err := os.ReadFile(flag.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	dataBus := &bus.Bus{}
+	loadAt, err := cli.ParseAddress(*at)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	dataBus.LoadRam(program, loadAt)
+
+	start := loadAt
+	if *entry != "" {
+		if start, err = cli.ParseAddress(*entry); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// only sets the reset vector when the program didn't provide one
+	if *entry != "" || dataBus.Read(0xFFFC) == 0 && dataBus.Read(0xFFFD) == 0 {
+		dataBus.Write(0xFFFC, byte(start&0x00FF))
+		dataBus.Write(0xFFFD, byte(start>>8))
+	}
+
+	watchBus := debugger.NewWatchBus(dataBus)
+	dbg := debugger.New(cpu6502.New(watchBus), watchBus)
+	dbg.Step()
+
+	if *history > 0 {
+		if err := dbg.EnableHistory(*history); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	server := gdbstub.New(dbg)
+	if *verbose {
+		server.Logger = log.Default()
+	}
+
+	log.Print("waiting for the debugger on ", *listen)
+	log.Fatal(server.ListenAndServe(*listen))
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"sync/atomic"
+
+	"github.com/costamauricio/6502-emulator/pkg/acia6551"
+	"github.com/costamauricio/6502-emulator/pkg/clock"
+	"github.com/costamauricio/6502-emulator/pkg/kim1"
+)
+
+// Cicles between the display redraws
+const REFRESH = 33000
+
+func main() {
+	tty := flag.Bool("tty", false, "Talks to the monitor through the terminal instead of the keypad and the display")
+	frequency := flag.Int("freq", clock.FREQUENCY_1MHZ, "CPU clock in Hz, 0 runs unlimited")
+	flag.Usage = func() {
+		log.Print("usage: kim1 [-tty] [-freq hz] rom.bin")
+		log.Print("the ROM is the 2K image of $1800-$1FFF, the 6530-003 followed by the 6530-002")
+		log.Print("keypad: 0-9 A-F, + and Ctrl+A AD, Ctrl+D DA, Ctrl+G GO, Ctrl+P PC, Ctrl+S ST, Ctrl+R RS, Ctrl+T SST")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	rom, err := os.ReadFile(flag.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	computer := kim1.New(rom, acia6551.Stdio(), *tty)
+
+	restore := rawTerminal()
+	defer restore()
+
+	tick := computer.Tick
+	if !*tty {
+		shown := ""
+		cycles := 0
+
+		tick = func() {
+			computer.Tick()
+
+			if cycles++; cycles < REFRESH {
+				return
+			}
+
+			cycles = 0
+			if display := computer.Display(); display != shown {
+				if shown != "" {
+					fmt.Printf("\x1b[%dA", strings.Count(shown, "\n"))
+				}
+
+				fmt.Print(display)
+				shown = display
+			}
+		}
+	}
+
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+
+	var stopped atomic.Bool
+	go func() {
+		<-interrupts
+		stopped.Store(true)
+	}()
+
+	clock.New(*frequency, tick).Run(stopped.Load)
+}
+
+// Disables the line buffering, the echo and the control keys of the terminal, returning the restore
+func rawTerminal() func() {
+	if err := stty("-icanon", "-echo", "-ixon", "min", "1"); err != nil {
+		return func() {}
+	}
+
+	return func() { stty("icanon", "echo", "ixon") }
+}
+
+func stty(args ...string) error {
+	command := exec.Command("stty", args...)
+	command.Stdin = os.Stdin
+
+	return command.Run()
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/costamauricio/6502-emulator/internal/cli"
+	"github.com/costamauricio/6502-emulator/pkg/bus"
+	"github.com/costamauricio/6502-emulator/pkg/cpu6502"
+	"github.com/costamauricio/6502-emulator/pkg/debugger"
+	"github.com/costamauricio/6502-emulator/pkg/debuginfo"
+	"github.com/costamauricio/6502-emulator/pkg/monitor"
+	"github.com/costamauricio/6502-emulator/pkg/replay"
+	"github.com/costamauricio/6502-emulator/pkg/symbols"
+)
+
+func main() {
+	at := flag.String("at", "8000", "Address in hexadecimal to load the program at")
+	entry := flag.String("entry", "", "Address in hexadecimal to start the execution, defaults to the load address when the reset vector is empty")
+	debugInfo := flag.String("dbg", "", "Debug information file (ca65 --dbgfile or JSON) to show labels and source lines")
+	symbolFiles := flag.String("symbols", "", "Comma separated label files (VICE, ld65 -Ln or name = $addr) or memory maps ("+strings.Join(symbols.Machines(), ", ")+")")
+	history := flag.Int("history", 0, "Instructions recorded to step back, 0 disables the history")
+	record := flag.String("record", "", "Replay file logging the interrupts and resets requested, written on quit")
+	replayFile := flag.String("replay", "", "Replay file injecting the logged events at their cicles")
+	flag.Usage = func() {
+		log.Print("usage: monitor [-at address] [-entry address] [-dbg file] [-symbols files] [-history depth] [-record file] [-replay file] [program.bin]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	dataBus := &bus.Bus{}
+
+	loadAt, err := cli.ParseAddress(*at)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if flag.NArg() > 0 {
+		program, err := os.ReadFile(flag.Arg(0))
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		dataBus.LoadRam(program, loadAt)
+	}
+
+	start := loadAt
+	if *entry != "" {
+		if start, err = cli.ParseAddress(*entry); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// only sets the reset vector when the program didn't provide one
+	if *entry != "" || dataBus.Read(0xFFFC) == 0 && dataBus.Read(0xFFFD) == 0 {
+		dataBus.Write(0xFFFC, byte(start&0x00FF))
+		dataBus.Write(0xFFFD, byte(start>>8))
+	}
+
+	watchBus := debugger.NewWatchBus(dataBus)
+	cpu := cpu6502.New(watchBus)
+	dbg := debugger.New(cpu, watchBus)
+
+	if *debugInfo != "" {
+		info, err := debuginfo.Load(*debugInfo)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		dbg.DebugInfo = info
+	}
+
+	for _, source := range strings.Split(*symbolFiles, ",") {
+		if source = strings.TrimSpace(source); source == "" {
+			continue
+		}
+
+		table, err := symbols.Open(source)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if dbg.Symbols == nil {
+			dbg.Symbols = symbols.New()
+		}
+
+		dbg.Symbols.Merge(table)
+	}
+
+	target := replay.Target{Cpu: cpu, Bus: watchBus}
+
+	if *record != "" {
+		dbg.Recorder = replay.NewRecorder(target)
+	}
+
+	if *replayFile != "" {
+		events, err := replay.Load(*replayFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		dbg.Tick = replay.NewReplayer(target, events).Tick
+	}
+
+	// runs out the reset cicles so the monitor starts at the first instruction
+	dbg.Step()
+
+	if *history > 0 {
+		if err := dbg.EnableHistory(*history); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// Ctrl+C halts the running program instead of exiting
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	go func() {
+		for range interrupts {
+			dbg.Interrupt()
+		}
+	}()
+
+	if err := monitor.New(dbg, os.Stdin, os.Stdout).Run(); err != nil {
+		log.Fatal(err)
+	}
+
+	if dbg.Recorder != nil {
+		if err := dbg.Recorder.Save(*record); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
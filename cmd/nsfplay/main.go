@@ -0,0 +1,78 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/costamauricio/6502-emulator/pkg/nsf"
+	"github.com/costamauricio/6502-emulator/pkg/wav"
+)
+
+func main() {
+	song := flag.Int("song", 0, "Song to play from 1, defaults to the first one of the file")
+	seconds := flag.Float64("seconds", 120, "Length of the rendered audio")
+	output := flag.String("o", "", "WAV file written, defaults to the NSF name with the .wav extension")
+	rate := flag.Int("rate", 44100, "Sample rate")
+	pal := flag.Bool("pal", false, "Plays on the PAL clock and speed")
+	flag.Usage = func() {
+		log.Print("usage: nsfplay [-song number] [-seconds length] [-o output.wav] [-rate hz] [-pal] music.nsf")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	data, err := os.ReadFile(flag.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	file, err := nsf.Parse(data)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *song == 0 {
+		*song = int(file.Start)
+	}
+
+	if *output == "" {
+		*output = strings.TrimSuffix(flag.Arg(0), filepath.Ext(flag.Arg(0))) + ".wav"
+	}
+
+	if file.Chips != 0 {
+		log.Printf("expansion chips $%02X aren't emulated", file.Chips)
+	}
+
+	player := nsf.NewPlayer(file, *rate)
+	player.PAL = player.PAL || *pal
+
+	if *song < 1 || *song > 0xFF {
+		log.Fatal(nsf.ErrSong)
+	}
+
+	if err := player.Init(byte(*song)); err != nil {
+		log.Fatal(err)
+	}
+
+	log.Printf("%s - %s, song %d of %d", file.Name, file.Artist, *song, file.Songs)
+
+	samples := player.Render(time.Duration(*seconds * float64(time.Second)))
+
+	writer, err := os.Create(*output)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer writer.Close()
+
+	if err := wav.Write(writer, samples, *rate); err != nil {
+		log.Fatal(err)
+	}
+}
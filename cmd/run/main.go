@@ -0,0 +1,183 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/costamauricio/6502-emulator/internal/cli"
+	"github.com/costamauricio/6502-emulator/pkg/blockdev"
+	"github.com/costamauricio/6502-emulator/pkg/bus"
+	"github.com/costamauricio/6502-emulator/pkg/console"
+	"github.com/costamauricio/6502-emulator/pkg/cpu6502"
+	"github.com/costamauricio/6502-emulator/pkg/framebuffer"
+	"github.com/costamauricio/6502-emulator/pkg/input"
+	"github.com/costamauricio/6502-emulator/pkg/system"
+)
+
+func main() {
+	at := flag.String("at", "8000", "Address in hexadecimal to load the program at")
+	entry := flag.String("entry", "", "Address in hexadecimal to start the execution, defaults to the load address when the reset vector is empty")
+	consoleAt := flag.String("console", "F000", "Address in hexadecimal of the console data register, the status is the next one")
+	cycles := flag.Uint64("cycles", 0, "Cicles the program can run, 0 is unlimited")
+	disk := flag.String("disk", "", "Image file of the block device, none by default")
+	diskAt := flag.String("disk-at", "F010", "Address in hexadecimal of the block device registers")
+	screen := flag.String("screen", "", "Size of the framebuffer, e.g. 32x32 or 64x64, none by default")
+	screenAt := flag.String("screen-at", "0200", "Address in hexadecimal of the framebuffer pixels")
+	paletteAt := flag.String("palette-at", "D000", "Address in hexadecimal of the framebuffer palette registers")
+	snapshot := flag.String("png", "", "PNG file of the framebuffer written when the run ends, also at the cycle limit")
+	scale := flag.Int("scale", 1, "Scale of the PNG pixels")
+	keyboardAt := flag.String("keyboard", "", "Address in hexadecimal of the keyboard key register, the strobe is the next one, none by default")
+	latch := flag.Bool("latch", false, "Maps only the keyboard key register without the strobe bit, like the easy6502 one at FF")
+	buttonsAt := flag.String("buttons", "", "Address in hexadecimal of the buttons register, none by default")
+	layout := flag.String("layout", "nes", "Layout of the buttons, nes, joystick or the name=bit bindings like Up=0,Down=1")
+	activeLow := flag.Bool("active-low", false, "The pressed buttons read 0")
+	scriptFile := flag.String("script", "", "Script of the keys and buttons pressed at given cicles")
+	flag.Usage = func() {
+		log.Print("usage: run [-at address] [-entry address] [-console address] [-cycles count] [-disk image] [-disk-at address] [-screen WxH] [-screen-at address] [-palette-at address] [-png file] [-scale factor] [-keyboard address] [-latch] [-buttons address] [-layout layout] [-active-low] [-script file] program.bin")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if *snapshot != "" && *screen == "" {
+		log.Fatal("-png needs the framebuffer of -screen")
+	}
+
+	program, err := os.ReadFile(flag.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	dataBus := &bus.Bus{}
+	loadAt, err := cli.ParseAddress(*at)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	dataBus.LoadRam(program, loadAt)
+
+	start := loadAt
+	if *entry != "" {
+		if start, err = cli.ParseAddress(*entry); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// only sets the reset vector when the program didn't provide one
+	if *entry != "" || dataBus.Read(0xFFFC) == 0 && dataBus.Read(0xFFFD) == 0 {
+		dataBus.Write(0xFFFC, byte(start&0x00FF))
+		dataBus.Write(0xFFFD, byte(start>>8))
+	}
+
+	// the devices can't shadow the program, the vectors or each other
+	ranges := &cli.Ranges{}
+	ranges.Reserve("program", loadAt, len(program))
+	ranges.Reserve("vectors", 0xFFFA, 6)
+
+	address, err := cli.ParseAddress(*consoleAt)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := ranges.Take("console", address, console.REG_STATUS+1); err != nil {
+		log.Fatal(err)
+	}
+
+	dataBus.Map(address, address+console.REG_STATUS, console.New(os.Stdout, os.Stdin))
+
+	if *disk != "" {
+		image, err := blockdev.Open(*disk)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer image.Close()
+
+		device := blockdev.New(image)
+		device.Memory = dataBus
+
+		address, err := cli.ParseAddress(*diskAt)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if err := ranges.Take("block device", address, blockdev.REGISTERS); err != nil {
+			log.Fatal(err)
+		}
+
+		dataBus.Map(address, address+blockdev.REGISTERS-1, device)
+	}
+
+	var display *framebuffer.Framebuffer
+	if *screen != "" {
+		if display, err = cli.MapScreen(dataBus, ranges, *screen, *screenAt, *paletteAt); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	keyboard, buttons, err := cli.MapInput(dataBus, ranges, *keyboardAt, *latch, *buttonsAt, *layout, *activeLow)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var script *input.Script
+	if *scriptFile != "" {
+		file, err := os.Open(*scriptFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		script, err = input.ParseScript(file, keyboard, buttons)
+		file.Close()
+
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	cpu := cpu6502.New(dataBus)
+	cpu.Reset()
+
+	next := system.NEVER
+	if script != nil {
+		next = script.Run(0)
+	}
+
+	// runs until BRK
+	limited := false
+	for !cpu.InstructionCompleted() || dataBus.Peek(cpu.PC) != 0x00 {
+		if cpu.Cycles() >= next {
+			next = script.Run(cpu.Cycles())
+		}
+
+		cpu.Tick()
+
+		if *cycles > 0 && cpu.Cycles() >= *cycles {
+			limited = true
+			break
+		}
+	}
+
+	// the programs drawing forever are taken at the cycle limit
+	if display != nil && *snapshot != "" {
+		file, err := os.Create(*snapshot)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer file.Close()
+
+		if err := display.WritePNG(file, *scale); err != nil {
+			log.Fatal(err)
+		}
+
+		return
+	}
+
+	if limited {
+		log.Fatalf("cycle limit reached at $%04X", cpu.PC)
+	}
+}
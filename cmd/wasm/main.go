@@ -3,20 +3,26 @@ package main
 import (
 	"github.com/costamauricio/6502-emulator/pkg/bus"
 	"github.com/costamauricio/6502-emulator/pkg/cpu6502"
+	"github.com/costamauricio/6502-emulator/pkg/debugger"
 	"syscall/js"
 )
 
 var (
 	dataBus *bus.Bus
 	cpu     *cpu6502.CPU
+	dbg     *debugger.Debugger
 )
 
 func main() {
 	dataBus = &bus.Bus{}
 	cpu = cpu6502.New(dataBus)
+	dbg = debugger.New(cpu, dataBus)
 
 	js.Global().Set("getRegisters", js.FuncOf(getRegisters))
 	js.Global().Set("getFlags", js.FuncOf(getFlags))
+	js.Global().Set("addBreakpoint", js.FuncOf(addBreakpoint))
+	js.Global().Set("removeBreakpoint", js.FuncOf(removeBreakpoint))
+	js.Global().Set("continueExecution", js.FuncOf(continueExecution))
 
 	<-make(chan bool)
 }
@@ -28,12 +34,51 @@ func reset() {
 
 //export stepInstruction
 func stepInstruction() {
-	for {
-		cpu.Tick()
-		if cpu.InstructionCompleted() {
-			break
+	dbg.Step()
+}
+
+// addBreakpoint(address, {ignoreCount, temporary}) returns the breakpoint id
+func addBreakpoint(this js.Value, args []js.Value) interface{} {
+	breakpoint := debugger.Breakpoint{Address: uint16(args[0].Int())}
+
+	if len(args) > 1 && args[1].Type() == js.TypeObject {
+		if ignoreCount := args[1].Get("ignoreCount"); ignoreCount.Type() == js.TypeNumber {
+			breakpoint.IgnoreCount = ignoreCount.Int()
+		}
+
+		if temporary := args[1].Get("temporary"); temporary.Type() == js.TypeBoolean {
+			breakpoint.Temporary = temporary.Bool()
 		}
 	}
+
+	return dbg.Breakpoints.Add(breakpoint).Id
+}
+
+// removeBreakpoint(id) returns false when the breakpoint doesn't exist
+func removeBreakpoint(this js.Value, args []js.Value) interface{} {
+	return dbg.Breakpoints.Remove(args[0].Int())
+}
+
+// continueExecution(maxInstructions) runs until a breakpoint or the instructions limit
+// returns the reason it stopped
+func continueExecution(this js.Value, args []js.Value) interface{} {
+	count := 0
+	if len(args) > 0 {
+		count = args[0].Int()
+	}
+
+	stop := dbg.Continue(count)
+
+	result := map[string]interface{}{
+		"reason":          string(stop.Reason),
+		"program_counter": cpu.PC,
+	}
+
+	if stop.Breakpoint != nil {
+		result["breakpoint"] = stop.Breakpoint.Id
+	}
+
+	return result
 }
 
 func getRegisters(this js.Value, args []js.Value) interface{} {
@@ -0,0 +1,153 @@
+// Parsing of the flags shared by the commands and the ranges their devices take on the bus
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/costamauricio/6502-emulator/pkg/bus"
+	"github.com/costamauricio/6502-emulator/pkg/framebuffer"
+	"github.com/costamauricio/6502-emulator/pkg/input"
+)
+
+// Width and height of the WxH size, they take at most the 64K
+func ParseSize(size string) (int, int, error) {
+	width, height, found := strings.Cut(size, "x")
+	parsedWidth, errWidth := strconv.Atoi(width)
+	parsedHeight, errHeight := strconv.Atoi(height)
+
+	if !found || errWidth != nil || errHeight != nil || parsedWidth < 1 || parsedHeight < 1 || parsedWidth*parsedHeight > 0x10000 {
+		return 0, 0, fmt.Errorf("invalid size: %s", size)
+	}
+
+	return parsedWidth, parsedHeight, nil
+}
+
+type span struct {
+	name       string
+	start, end int
+}
+
+// Address ranges in use on the bus, the later mappings shadow the earlier ones so the devices that
+// would hide the program, the vectors or other devices are refused
+type Ranges struct {
+	spans []span
+}
+
+// Marks the range in use without checking it, e.g. the program that may include the vectors
+func (r *Ranges) Reserve(name string, start uint16, size int) {
+	if size > 0 {
+		r.spans = append(r.spans, span{name, int(start), int(start) + size - 1})
+	}
+}
+
+// Marks the range in use, failing when it goes past $FFFF or overlaps a range in use
+func (r *Ranges) Take(name string, start uint16, size int) error {
+	end := int(start) + size - 1
+
+	if size < 1 || end > 0xFFFF {
+		return fmt.Errorf("%s at $%04X with %d bytes doesn't fit below $FFFF", name, start, size)
+	}
+
+	for _, taken := range r.spans {
+		if int(start) <= taken.end && end >= taken.start {
+			return fmt.Errorf("%s $%04X-$%04X overlaps the %s $%04X-$%04X", name, start, end, taken.name, taken.start, taken.end)
+		}
+	}
+
+	r.Reserve(name, start, size)
+	return nil
+}
+
+// Maps the framebuffer of the WxH size and its palette registers, refusing the ranges in use
+func MapScreen(dataBus *bus.Bus, ranges *Ranges, size string, pixelsAt string, paletteAt string) (*framebuffer.Framebuffer, error) {
+	width, height, err := ParseSize(size)
+	if err != nil {
+		return nil, err
+	}
+
+	pixels, err := ParseAddress(pixelsAt)
+	if err != nil {
+		return nil, err
+	}
+
+	palette, err := ParseAddress(paletteAt)
+	if err != nil {
+		return nil, err
+	}
+
+	display := framebuffer.New(width, height)
+
+	if err := ranges.Take("screen", pixels, display.Size()); err != nil {
+		return nil, err
+	}
+
+	if err := ranges.Take("palette", palette, framebuffer.REGISTERS); err != nil {
+		return nil, err
+	}
+
+	dataBus.Map(pixels, pixels+uint16(display.Size()-1), display)
+	dataBus.Map(palette, palette+framebuffer.REGISTERS-1, display.Registers())
+
+	return display, nil
+}
+
+// Maps the keyboard and the buttons at the given addresses, the empty ones aren't mapped
+func MapInput(dataBus *bus.Bus, ranges *Ranges, keyboardAt string, latch bool, buttonsAt string, layout string, activeLow bool) (*input.Keyboard, *input.Buttons, error) {
+	var keyboard *input.Keyboard
+	var buttons *input.Buttons
+
+	if keyboardAt != "" {
+		address, err := ParseAddress(keyboardAt)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		keyboard = input.NewKeyboard()
+		registers := uint16(input.REG_STROBE)
+
+		if latch {
+			keyboard.Strobe, registers = 0, input.REG_KEY
+		}
+
+		if err := ranges.Take("keyboard", address, int(registers)+1); err != nil {
+			return nil, nil, err
+		}
+
+		dataBus.Map(address, address+registers, keyboard)
+	}
+
+	if buttonsAt != "" {
+		address, err := ParseAddress(buttonsAt)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		bindings, err := input.LayoutOf(layout)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		buttons = input.NewButtons(bindings)
+		buttons.ActiveLow = activeLow
+
+		if err := ranges.Take("buttons", address, 1); err != nil {
+			return nil, nil, err
+		}
+
+		dataBus.Map(address, address, buttons)
+	}
+
+	return keyboard, buttons, nil
+}
+
+// Hexadecimal address, optionally prefixed by $
+func ParseAddress(address string) (uint16, error) {
+	parsed, err := strconv.ParseUint(strings.TrimPrefix(address, "$"), 16, 16)
+	if err != nil {
+		return 0, fmt.Errorf("invalid address: %s", address)
+	}
+
+	return uint16(parsed), nil
+}
@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/costamauricio/6502-emulator/pkg/bus"
+)
+
+func TestParseSize(t *testing.T) {
+	if width, height, err := ParseSize("256x240"); err != nil || width != 256 || height != 240 {
+		t.Errorf("expected 256x240, got %dx%d %v", width, height, err)
+	}
+
+	for _, invalid := range []string{"32", "0x32", "x", "257x256"} {
+		if _, _, err := ParseSize(invalid); err == nil {
+			t.Errorf("expected %q invalid", invalid)
+		}
+	}
+}
+
+func TestRanges(t *testing.T) {
+	var ranges Ranges
+	ranges.Reserve("program", 0x8000, 0x8000)
+	ranges.Reserve("vectors", 0xFFFA, 6)
+	ranges.Reserve("console", 0x7000, 2)
+
+	if err := ranges.Take("screen", 0x0200, 0x400); err != nil {
+		t.Error(err)
+	}
+
+	// 256x240 from $0600 reaches the program
+	if err := ranges.Take("pixels", 0x0600, 256*240); err == nil {
+		t.Error("expected the overlap refused")
+	}
+
+	if err := ranges.Take("palette", 0x01FF, 2); err == nil {
+		t.Error("expected the overlap with the screen refused")
+	}
+
+	if err := ranges.Take("pixels", 0x0000, 0); err == nil {
+		t.Error("expected the empty range refused")
+	}
+
+	var empty Ranges
+	if err := empty.Take("pixels", 0xFF00, 0x200); err == nil {
+		t.Error("expected the range wrapping past $FFFF refused")
+	}
+}
+
+func TestMapInput(t *testing.T) {
+	dataBus := &bus.Bus{}
+	ranges := &Ranges{}
+	ranges.Reserve("console", 0xC010, 2)
+
+	keyboard, buttons, err := MapInput(dataBus, ranges, "$C000", false, "C002", "Up=0", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyboard.Press('A')
+	buttons.Set("Up", true)
+
+	if dataBus.Read(0xC000) != 'A'|0x80 || dataBus.Read(0xC002) != 0xFE {
+		t.Errorf("expected the key and the active low button, got $%02X $%02X", dataBus.Read(0xC000), dataBus.Read(0xC002))
+	}
+
+	if _, _, err := MapInput(dataBus, ranges, "C010", false, "", "nes", false); err == nil {
+		t.Error("expected the keyboard over the console refused")
+	}
+
+	if _, _, err := MapInput(dataBus, ranges, "", false, "C100", "Up", false); err == nil {
+		t.Error("expected the invalid layout refused")
+	}
+}
@@ -3,12 +3,20 @@ package visualizer
 import (
 	"github.com/costamauricio/6502-emulator/pkg/bus"
 	"github.com/costamauricio/6502-emulator/pkg/cpu6502"
+	"github.com/costamauricio/6502-emulator/pkg/debugger"
+	"github.com/costamauricio/6502-emulator/pkg/disasm"
+	"github.com/costamauricio/6502-emulator/pkg/framebuffer"
+	"github.com/costamauricio/6502-emulator/pkg/input"
 	"fmt"
+	"unsafe"
 
 	"github.com/veandco/go-sdl2/sdl"
 	"github.com/veandco/go-sdl2/ttf"
 )
 
+// Largest side of the framebuffer on the window, it's scaled by an integer factor
+const screenSide = 512
+
 const (
 	red        string = "RED"
 	green      string = "GREEN"
@@ -20,8 +28,23 @@ type Visualizer struct {
 	Cpu *cpu6502.CPU
 	Bus *bus.Bus
 
+	// Optional, created on Run when not provided
+	Debugger *debugger.Debugger
+
+	// Optional, drawn at the right of the panels
+	Framebuffer *framebuffer.Framebuffer
+
+	// Optional, take the keys and the game controller buttons of the host
+	// With a keyboard the typed text goes to the program and the commands move to the function keys
+	Keyboard *input.Keyboard
+	Buttons  *input.Buttons
+
 	font     *ttf.Font
 	renderer *sdl.Renderer
+	screen   *sdl.Texture
+
+	program *disasm.Disassembler
+	code    []uint16 // Addresses of the disassembled instructions in order
 
 	colors map[string]*sdl.Color
 }
@@ -53,11 +76,16 @@ func (v *Visualizer) Run(initMemory uint16) error {
 	}
 	defer v.font.Close()
 
+	width := int32(800)
+	if v.Framebuffer != nil {
+		width += int32(v.Framebuffer.Width)*v.screenScale() + 40
+	}
+
 	window, err := sdl.CreateWindow(
 		"6502 Emulator",
 		sdl.WINDOWPOS_CENTERED_MASK,
 		sdl.WINDOWPOS_CENTERED_MASK,
-		800,
+		width,
 		600,
 		sdl.WINDOW_SHOWN|sdl.WINDOW_RESIZABLE)
 
@@ -71,11 +99,48 @@ func (v *Visualizer) Run(initMemory uint16) error {
 	}
 	defer v.renderer.Destroy()
 
-	parsedCode, codeOrder := v.Cpu.DisassembleInstructions(0x0000, 0xFFFF)
+	if v.Framebuffer != nil {
+		v.screen, err = v.renderer.CreateTexture(
+			sdl.PIXELFORMAT_ABGR8888,
+			sdl.TEXTUREACCESS_STREAMING,
+			int32(v.Framebuffer.Width),
+			int32(v.Framebuffer.Height))
+
+		if err != nil {
+			return err
+		}
+		defer v.screen.Destroy()
+	}
+
+	for index := 0; index < sdl.NumJoysticks(); index++ {
+		if sdl.IsGameController(index) {
+			if controller := sdl.GameControllerOpen(index); controller != nil {
+				defer controller.Close()
+			}
+		}
+	}
+
+	if v.Debugger == nil {
+		v.Debugger = debugger.New(v.Cpu, v.Bus)
+	}
+
+	// only the code reachable from the vectors is disassembled, the rest is added as the PC reaches it
+	v.program = disasm.New(v.Debugger.Peek, 0x0000, 0xFFFF)
+	v.program.Symbols = v.Debugger.Label
+	v.program.AddVectors()
+	v.code = v.program.Code()
+
 	v.Cpu.Reset()
 
 	running := true
+	continuing := false
 	for running {
+		if continuing {
+			// runs in chunks so the window keeps responding
+			stop := v.Debugger.Continue(1000)
+			continuing = stop.Reason == debugger.STOP_STEP
+		}
+
 		v.setDrawColor(v.colors[background])
 		v.renderer.Clear()
 
@@ -83,31 +148,42 @@ func (v *Visualizer) Run(initMemory uint16) error {
 		v.drawRam(" Stack ", 20, 318, 4, 0x01BF)
 		v.drawRam(" Program ", 20, 414, 8, initMemory)
 		v.drawCpu()
-		v.drawInstructions(parsedCode, codeOrder)
+		v.drawInstructions()
 		v.drawCommands()
+		v.drawScreen()
 
 		v.renderer.Present()
 
-		event := sdl.PollEvent()
-
-		if event == nil {
-			continue
-		}
-
-		switch event := event.(type) {
-		case *sdl.QuitEvent:
-			println("Quit")
-			running = false
-		case *sdl.TextInputEvent:
-			switch event.GetText() {
-			case " ":
-				v.stepInstruction()
-			case "r", "R":
-				v.Cpu.Reset()
-			case "i", "I":
-				v.Cpu.InterruptRequest()
-			case "n", "N":
-				v.Cpu.NonMaskableInterrupt()
+		for event := sdl.PollEvent(); event != nil; event = sdl.PollEvent() {
+			switch event := event.(type) {
+			case *sdl.QuitEvent:
+				println("Quit")
+				running = false
+			case *sdl.KeyboardEvent:
+				v.keyboardEvent(event, &continuing)
+			case *sdl.TextInputEvent:
+				if v.Keyboard != nil {
+					for _, code := range []byte(event.GetText()) {
+						v.input(input.DEVICE_KEYBOARD, code)
+					}
+					continue
+				}
+
+				switch event.GetText() {
+				case " ":
+					continuing = false
+					v.Debugger.Step()
+				case "c", "C":
+					continuing = !continuing
+				case "r", "R":
+					v.Debugger.Reset()
+				case "i", "I":
+					v.Debugger.InterruptRequest()
+				case "n", "N":
+					v.Debugger.NonMaskableInterrupt()
+				}
+			case *sdl.ControllerButtonEvent:
+				v.setButton(sdl.GameControllerGetStringForButton(sdl.GameControllerButton(event.Button)), event.State == sdl.PRESSED)
 			}
 		}
 	}
@@ -115,15 +191,59 @@ func (v *Visualizer) Run(initMemory uint16) error {
 	return nil
 }
 
-func (v *Visualizer) stepInstruction() {
-	for {
-		v.Cpu.Tick()
-		if v.Cpu.InstructionCompleted() {
-			break
+// The keys go to the buttons by their names, the function keys are the commands when the keyboard
+// takes the text, and the keys that don't type text, like Return, go to the keyboard
+func (v *Visualizer) keyboardEvent(event *sdl.KeyboardEvent, continuing *bool) {
+	name := sdl.GetKeyName(event.Keysym.Sym)
+	pressed := event.State == sdl.PRESSED
+
+	if event.Repeat == 0 {
+		v.setButton(name, pressed)
+	}
+
+	if v.Keyboard == nil || !pressed {
+		return
+	}
+
+	switch event.Keysym.Sym {
+	case sdl.K_F10:
+		*continuing = false
+		v.Debugger.Step()
+	case sdl.K_F5:
+		*continuing = !*continuing
+	case sdl.K_F2:
+		v.Debugger.Reset()
+	case sdl.K_F3:
+		v.Debugger.InterruptRequest()
+	case sdl.K_F4:
+		v.Debugger.NonMaskableInterrupt()
+	default:
+		if code, found := v.Keyboard.Keys[name]; found {
+			v.input(input.DEVICE_KEYBOARD, code)
 		}
 	}
 }
 
+func (v *Visualizer) setButton(name string, pressed bool) {
+	if v.Buttons == nil {
+		return
+	}
+
+	if bits, bound := v.Buttons.With(name, pressed); bound {
+		v.input(input.DEVICE_BUTTONS, bits)
+	}
+}
+
+// Applies the host input to the devices, through the recorder of the debugger when recording
+func (v *Visualizer) input(device byte, value byte) {
+	if v.Debugger.Recorder != nil {
+		v.Debugger.Recorder.Input(device, value)
+		return
+	}
+
+	input.Apply(v.Keyboard, v.Buttons)(device, value)
+}
+
 func (v *Visualizer) setDrawColor(color *sdl.Color) {
 	v.renderer.SetDrawColor(color.R, color.G, color.B, color.A)
 }
@@ -206,20 +326,32 @@ func (v *Visualizer) drawCpu() {
 	v.drawText(fmt.Sprintf("S: $%02X", v.Cpu.S), x, y+80, nil)
 }
 
-func (v *Visualizer) drawInstructions(instructions map[uint16]string, order []uint16) {
+func (v *Visualizer) drawInstructions() {
 	var x, y int32 = 480, 140
 
+	// code not reachable from the vectors, like interrupt handlers set on RAM
+	if !v.program.IsCode(v.Cpu.PC) {
+		v.program.AddEntry(v.Cpu.PC, "")
+		v.code = v.program.Code()
+	}
+
+	order := v.code
+
 	v.drawShadedText(" Instructions ", x, y, v.colors[background], v.colors[font])
 
 	y += 18
 
 	var currentIndex int
 
-	getColor := func(current bool) *sdl.Color {
-		if current {
+	getColor := func(address uint16) *sdl.Color {
+		if address == v.Cpu.PC {
 			return v.colors[green]
 		}
 
+		if len(v.Debugger.Breakpoints.At(address)) > 0 {
+			return v.colors[red]
+		}
+
 		return nil
 	}
 
@@ -242,16 +374,88 @@ func (v *Visualizer) drawInstructions(instructions map[uint16]string, order []ui
 		bottomCut = len(order)
 	}
 
+	if topCut < 0 {
+		topCut = 0
+	}
+
 	for index, value := range order[topCut:bottomCut] {
-		v.drawText(instructions[value], x, y+(int32(index)*16), getColor(value == v.Cpu.PC))
+		v.drawText(v.instructionText(value), x, y+(int32(index)*16), getColor(value))
 	}
 }
 
+// The instruction is shown with its label, the operands named and the source location with debug information
+func (v *Visualizer) instructionText(address uint16) string {
+	name := func(address uint16) (string, bool) {
+		if name, found := v.program.Name(address); found {
+			return name, true
+		}
+
+		return v.Debugger.Name(address)
+	}
+
+	operation, _ := v.program.Operation(address)
+	text := fmt.Sprintf("$%04X:  %s", address, operation.Format(name))
+
+	if label, found := v.program.Label(address); found {
+		text = fmt.Sprintf("%s: %s", label, operation.Format(name))
+	}
+
+	if v.Debugger.DebugInfo != nil {
+		if location, found := v.Debugger.DebugInfo.Location(address); found {
+			text = fmt.Sprintf("%-20s %s", text, location)
+		}
+	}
+
+	return text
+}
+
+// Integer scale of the framebuffer fitting its side on the window
+func (v *Visualizer) screenScale() int32 {
+	side := v.Framebuffer.Width
+	if v.Framebuffer.Height > side {
+		side = v.Framebuffer.Height
+	}
+
+	if side >= screenSide {
+		return 1
+	}
+
+	return int32(screenSide / side)
+}
+
+func (v *Visualizer) drawScreen() {
+	if v.Framebuffer == nil {
+		return
+	}
+
+	var x, y int32 = 820, 30
+	scale := v.screenScale()
+	width, height := int32(v.Framebuffer.Width)*scale, int32(v.Framebuffer.Height)*scale
+
+	v.setDrawColor(v.colors[font])
+	v.drawBox(" Screen ", x-10, y-10, width+20, height+20)
+
+	// the RGBA bytes are the ABGR8888 pixels on little endian
+	picture := v.Framebuffer.Image()
+	v.screen.Update(nil, unsafe.Pointer(&picture.Pix[0]), picture.Stride)
+	v.renderer.Copy(v.screen, nil, &sdl.Rect{X: x, Y: y, W: width, H: height})
+}
+
 func (v *Visualizer) drawCommands() {
 	var x, y int32 = 20, 560
 
+	if v.Keyboard != nil {
+		v.drawText("F10 = Step Instruction", x, y, nil)
+		v.drawText("F2 = Reset", x+232, y, nil)
+		v.drawText("F3 = IRQ", x+344, y, nil)
+		v.drawText("F4 = NMI", x+440, y, nil)
+		v.drawText("F5 = Continue", x+536, y, nil)
+		return
+	}
+
 	v.drawText("SPACE = Step Instruction", x, y, nil)
 	v.drawText("R = Reset", x+232, y, nil)
 	v.drawText("I = IRQ", x+344, y, nil)
 	v.drawText("N = NMI", x+440, y, nil)
+	v.drawText("C = Continue", x+536, y, nil)
 }
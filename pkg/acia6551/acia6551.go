@@ -0,0 +1,254 @@
+// MOS 6551 Asynchronous Communications Interface Adapter
+//
+// Serial port with the data, status, command and control registers. The characters take the time
+// of the programmed baud rate and frame on the system clock, so a program polling the status sees
+// the transmitter busy like on the real chip. The bytes go to and come from an Endpoint: the
+// process stdin/stdout, a PTY, a TCP connection or an in-memory Pipe for the tests.
+//
+//	acia := acia6551.New(acia6551.Stdio())
+//	acia.IRQ = machine.IRQSource()
+//	dataBus.Map(0x5000, 0x5003, machine.Add(acia, 1))
+//
+// The received bytes wait in the endpoint while the receive register is full, so there are no
+// overruns and the programs can read at their own pace.
+package acia6551
+
+import (
+	"github.com/costamauricio/6502-emulator/pkg/clock"
+	"github.com/costamauricio/6502-emulator/pkg/system"
+)
+
+// Registers, mirrored every 4 bytes
+const (
+	REG_DATA    = 0x0 // Reads the received byte, writes the byte to transmit
+	REG_STATUS  = 0x1 // Writing it is a programmed reset
+	REG_COMMAND = 0x2
+	REG_CONTROL = 0x3
+)
+
+// Status bits
+const (
+	STATUS_PARITY  byte = 0x01 // Parity error
+	STATUS_FRAMING byte = 0x02 // Framing error
+	STATUS_OVERRUN byte = 0x04
+	STATUS_RDRF    byte = 0x08 // Receive data register full
+	STATUS_TDRE    byte = 0x10 // Transmit data register empty
+	STATUS_DCD     byte = 0x20 // Data carrier detect, 0 when detected
+	STATUS_DSR     byte = 0x40 // Data set ready, 0 when ready
+	STATUS_IRQ     byte = 0x80 // Interrupt occurred, cleared reading the status
+)
+
+// Command bits
+const (
+	COMMAND_DTR           byte = 0x01 // Data terminal ready, enables the receiver
+	COMMAND_RX_IRQ_OFF    byte = 0x02 // Disables the receive interrupt
+	COMMAND_TX_CONTROL    byte = 0x0C // Transmitter control
+	COMMAND_TX_IRQ        byte = 0x04 // Transmitter control enabling the transmit interrupt
+	COMMAND_ECHO          byte = 0x10 // Echoes the received bytes
+	COMMAND_PARITY_ENABLE byte = 0x20
+)
+
+// Control bits
+const (
+	CONTROL_BAUD        byte = 0x0F // Baud rate select
+	CONTROL_WORD_LENGTH byte = 0x60 // 8, 7, 6 or 5 data bits
+	CONTROL_STOP_BITS   byte = 0x80 // Two stop bits
+)
+
+// Baud rates by the control select, the first one is the 16x external clock of the usual 1.8432 MHz crystal
+var BAUD_RATES = [16]int{115200, 50, 75, 110, 135, 150, 300, 600, 1200, 1800, 2400, 3600, 4800, 7200, 9600, 19200}
+
+type ACIA struct {
+	Endpoint  Endpoint
+	IRQ       func(asserted bool) // Optional, the IRQ output, e.g. System.IRQSource
+	Frequency int                 // CPU clock in Hz timing the characters, defaults to 1 MHz
+
+	status   byte
+	command  byte
+	control  byte
+	received byte
+	transmit byte
+	irq      bool
+
+	shifting bool   // Transmitting the shift register
+	shifted  uint64 // Cycle the shift register transmission ends
+	shifter  byte
+	poll     uint64 // Cycle to check the endpoint for received bytes
+}
+
+func New(endpoint Endpoint) *ACIA {
+	acia := &ACIA{Endpoint: endpoint, Frequency: clock.FREQUENCY_1MHZ}
+	acia.Reset()
+
+	return acia
+}
+
+// Hardware reset
+func (a *ACIA) Reset() {
+	a.status = STATUS_TDRE
+	a.command = COMMAND_RX_IRQ_OFF
+	a.control = 0
+	a.shifting = false
+	a.setIRQ(false)
+}
+
+// Baud rate programmed in the control register
+func (a *ACIA) BaudRate() int {
+	return BAUD_RATES[a.control&CONTROL_BAUD]
+}
+
+// Bits of a character frame: start, data, parity and stop bits
+func (a *ACIA) frameBits() int {
+	bits := 1 + a.dataBits() + 1
+
+	if a.command&COMMAND_PARITY_ENABLE > 0 {
+		bits++
+	}
+
+	// two stop bits, except with 8 data bits and parity that keep one, the 1.5 of 5 data bits count as two
+	if a.control&CONTROL_STOP_BITS > 0 && !(a.dataBits() == 8 && a.command&COMMAND_PARITY_ENABLE > 0) {
+		bits++
+	}
+
+	return bits
+}
+
+func (a *ACIA) dataBits() int {
+	return 8 - int(a.control&CONTROL_WORD_LENGTH>>5)
+}
+
+// CPU cicles a character takes
+func (a *ACIA) CharacterCycles() uint64 {
+	frequency := a.Frequency
+	if frequency <= 0 {
+		frequency = clock.FREQUENCY_1MHZ
+	}
+
+	cycles := uint64(frequency * a.frameBits() / a.BaudRate())
+	if cycles == 0 {
+		return 1
+	}
+
+	return cycles
+}
+
+func (a *ACIA) setIRQ(asserted bool) {
+	if asserted {
+		a.status |= STATUS_IRQ
+	} else {
+		a.status &^= STATUS_IRQ
+	}
+
+	if asserted != a.irq {
+		a.irq = asserted
+		if a.IRQ != nil {
+			a.IRQ(asserted)
+		}
+	}
+}
+
+func (a *ACIA) Read(register uint16) byte {
+	value := a.Peek(register)
+
+	switch register & 0x03 {
+	case REG_DATA:
+		a.status &^= STATUS_RDRF | STATUS_OVERRUN | STATUS_FRAMING | STATUS_PARITY
+	case REG_STATUS:
+		a.setIRQ(false)
+	}
+
+	return value
+}
+
+// Reads the register without clearing the flags
+func (a *ACIA) Peek(register uint16) byte {
+	switch register & 0x03 {
+	case REG_DATA:
+		return a.received
+	case REG_STATUS:
+		return a.status
+	case REG_COMMAND:
+		return a.command
+	}
+
+	return a.control
+}
+
+func (a *ACIA) Write(register uint16, data byte) {
+	switch register & 0x03 {
+	case REG_DATA:
+		a.transmit = data & byte(0xFF>>(8-a.dataBits()))
+		a.status &^= STATUS_TDRE
+	case REG_STATUS:
+		// programmed reset
+		a.command &^= 0x1F
+		a.command |= COMMAND_RX_IRQ_OFF
+		a.status &^= STATUS_OVERRUN
+	case REG_COMMAND:
+		a.command = data
+	case REG_CONTROL:
+		a.control = data
+	}
+}
+
+func (a *ACIA) transmitIRQ() bool {
+	return a.command&COMMAND_TX_CONTROL == COMMAND_TX_IRQ
+}
+
+// Transmits and receives the endpoint bytes at the character rate
+// The transmit register moves to the shift register as soon as it's free, so the next byte can
+// be written while the previous one is sent
+func (a *ACIA) Run(now uint64) uint64 {
+	if a.shifting && now >= a.shifted {
+		a.shifting = false
+		a.send(a.shifter)
+	}
+
+	if !a.shifting && a.status&STATUS_TDRE == 0 {
+		a.shifting = true
+		a.shifted = now + a.CharacterCycles()
+		a.shifter = a.transmit
+		a.status |= STATUS_TDRE
+
+		if a.transmitIRQ() {
+			a.setIRQ(true)
+		}
+	}
+
+	receiving := a.command&COMMAND_DTR > 0
+	if receiving && now >= a.poll {
+		a.poll = now + a.CharacterCycles()
+
+		if a.status&STATUS_RDRF == 0 && a.Endpoint != nil {
+			if value, found := a.Endpoint.Receive(); found {
+				a.received = value
+				a.status |= STATUS_RDRF
+
+				if a.command&COMMAND_ECHO > 0 && a.command&COMMAND_TX_CONTROL == 0 {
+					a.send(value)
+				}
+
+				if a.command&COMMAND_RX_IRQ_OFF == 0 {
+					a.setIRQ(true)
+				}
+			}
+		}
+	}
+
+	next := system.NEVER
+	if a.shifting {
+		next = a.shifted
+	}
+
+	if receiving && a.poll < next {
+		next = a.poll
+	}
+
+	return next
+}
+
+func (a *ACIA) send(value byte) {
+	if a.Endpoint != nil {
+		a.Endpoint.Write([]byte{value})
+	}
+}
@@ -0,0 +1,160 @@
+package acia6551
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/costamauricio/6502-emulator/pkg/bus"
+	"github.com/costamauricio/6502-emulator/pkg/cpu6502"
+	"github.com/costamauricio/6502-emulator/pkg/system"
+)
+
+func TestTransmitTiming(t *testing.T) {
+	pipe := NewPipe()
+	acia := New(pipe)
+	var irq bool
+	acia.IRQ = func(asserted bool) { irq = asserted }
+
+	// 9600 baud, 8 data bits, 1 stop bit: 10 bits of 104 cicles
+	acia.Write(REG_CONTROL, 0x1E)
+	acia.Write(REG_COMMAND, COMMAND_TX_IRQ|COMMAND_RX_IRQ_OFF)
+
+	if acia.CharacterCycles() != 1041 {
+		t.Errorf("expected 1041 cicles per character, got %d", acia.CharacterCycles())
+	}
+
+	acia.Write(REG_DATA, 'A')
+	acia.Run(0)
+
+	// the byte moves to the shift register leaving the transmit register empty
+	if acia.Read(REG_STATUS)&STATUS_TDRE == 0 || irq {
+		t.Errorf("expected the transmit register empty and the interrupt cleared by the status read")
+	}
+
+	acia.Write(REG_DATA, 'B')
+	acia.Run(1040)
+
+	if len(pipe.Output()) != 0 || acia.Peek(REG_STATUS)&STATUS_TDRE > 0 {
+		t.Errorf("expected nothing sent before the character time, got %q", pipe.Output())
+	}
+
+	if next := acia.Run(1041); next != 2082 || string(pipe.Output()) != "A" || !irq {
+		t.Errorf("expected A sent and B shifting until 2082, got %q until %d", pipe.Output(), next)
+	}
+
+	acia.Run(2082)
+	if string(pipe.Output()) != "AB" {
+		t.Errorf("expected AB sent, got %q", pipe.Output())
+	}
+}
+
+func TestEcho(t *testing.T) {
+	dataBus := &bus.Bus{}
+	// LDA #$1F; STA $5003; LDA #$0B; STA $5002
+	// loop: LDA $5001; AND #$08; BEQ loop; LDA $5000; STA $5000; JMP loop
+	dataBus.LoadRamFromString("A9 1F 8D 03 50 A9 0B 8D 02 50 AD 01 50 29 08 F0 F9 AD 00 50 8D 00 50 4C 0A 80", 0x8000)
+	dataBus.LoadRamFromString("00 80", 0xFFFC)
+
+	machine := system.New(cpu6502.New(dataBus), dataBus)
+	pipe := NewPipe()
+	acia := New(pipe)
+	dataBus.Map(0x5000, 0x5003, machine.Add(acia, 1))
+
+	pipe.Send([]byte("HELLO"))
+	// 520 cicles per character at 19200 baud
+	machine.Run(6000)
+
+	if string(pipe.Output()) != "HELLO" {
+		t.Errorf("expected the input echoed, got %q", pipe.Output())
+	}
+}
+
+func TestListener(t *testing.T) {
+	listener, err := Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	conn.Write([]byte("x"))
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if value, found := listener.Receive(); found {
+			if value != 'x' {
+				t.Errorf("expected x received, got %q", value)
+			}
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatal("nothing received")
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	listener.Write([]byte("ok\n"))
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	if line, err := bufio.NewReader(conn).ReadString('\n'); err != nil || line != "ok\n" {
+		t.Errorf("expected ok sent, got %q %v", line, err)
+	}
+}
+
+func TestListenerReplaced(t *testing.T) {
+	listener, err := Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	// the first connection fills the received bytes
+	first, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.Close()
+
+	first.Write(bytes.Repeat([]byte("a"), 5000))
+	time.Sleep(50 * time.Millisecond)
+
+	second, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer second.Close()
+
+	// the first one is closed by the listener
+	first.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := first.Read(make([]byte, 1)); err == nil {
+		t.Error("expected the first connection closed")
+	}
+
+	second.Write([]byte("b"))
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		value, found := listener.Receive()
+		if found && value == 'b' {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatal("nothing received from the second connection")
+		}
+
+		if !found {
+			time.Sleep(time.Millisecond)
+		}
+	}
+}
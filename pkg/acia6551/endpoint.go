@@ -0,0 +1,212 @@
+package acia6551
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"os"
+	"sync"
+)
+
+// Other side of the serial line
+type Endpoint interface {
+	io.Writer              // Receives the transmitted bytes
+	Receive() (byte, bool) // Next byte for the ACIA, without blocking
+}
+
+// Endpoint over a reader and a writer, the reader is consumed in the background
+type Stream struct {
+	writer   io.Writer
+	received chan byte
+}
+
+func NewStream(reader io.Reader, writer io.Writer) *Stream {
+	stream := &Stream{writer: writer, received: make(chan byte, 4096)}
+
+	go func() {
+		buffer := make([]byte, 256)
+
+		for {
+			count, err := reader.Read(buffer)
+			for _, value := range buffer[:count] {
+				stream.received <- value
+			}
+
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	return stream
+}
+
+// Endpoint on the process standard input and output
+func Stdio() *Stream {
+	return NewStream(os.Stdin, os.Stdout)
+}
+
+func (s *Stream) Write(data []byte) (int, error) {
+	return s.writer.Write(data)
+}
+
+func (s *Stream) Receive() (byte, bool) {
+	select {
+	case value := <-s.received:
+		return value, true
+	default:
+		return 0, false
+	}
+}
+
+// In-memory endpoint for the tests, the bytes sent are received by the ACIA
+// and the transmitted ones are kept in the output
+type Pipe struct {
+	lock   sync.Mutex
+	input  []byte
+	output bytes.Buffer
+}
+
+func NewPipe() *Pipe {
+	return &Pipe{}
+}
+
+// Queues the bytes to the ACIA
+func (p *Pipe) Send(data []byte) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.input = append(p.input, data...)
+}
+
+// Bytes transmitted by the ACIA
+func (p *Pipe) Output() []byte {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return append([]byte(nil), p.output.Bytes()...)
+}
+
+func (p *Pipe) Write(data []byte) (int, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return p.output.Write(data)
+}
+
+func (p *Pipe) Receive() (byte, bool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if len(p.input) == 0 {
+		return 0, false
+	}
+
+	value := p.input[0]
+	p.input = p.input[1:]
+
+	return value, true
+}
+
+// Endpoint accepting TCP connections, e.g. on localhost for telnet or nc
+// A new connection replaces the previous one, the bytes transmitted without connection are dropped
+type Listener struct {
+	listener net.Listener
+	received chan byte
+
+	lock   sync.Mutex
+	conn   net.Conn
+	closed chan struct{} // Closed with the current connection, stops its reader
+}
+
+func Listen(address string) (*Listener, error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := &Listener{listener: listener, received: make(chan byte, 4096)}
+	go endpoint.accept()
+
+	return endpoint, nil
+}
+
+func (l *Listener) accept() {
+	for {
+		conn, err := l.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		closed := make(chan struct{})
+
+		l.lock.Lock()
+		l.disconnect()
+		l.conn, l.closed = conn, closed
+		l.lock.Unlock()
+
+		go l.read(conn, closed)
+	}
+}
+
+// Closes the current connection, the lock must be held
+func (l *Listener) disconnect() {
+	if l.conn != nil {
+		l.conn.Close()
+		close(l.closed)
+		l.conn = nil
+	}
+}
+
+// Reads the connection until it's replaced or closed, a full buffer doesn't keep it blocked
+func (l *Listener) read(conn net.Conn, closed chan struct{}) {
+	buffer := make([]byte, 256)
+
+	for {
+		count, err := conn.Read(buffer)
+		for _, value := range buffer[:count] {
+			select {
+			case <-closed:
+				return
+			case l.received <- value:
+			}
+		}
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+// Address listened, useful when listening on port 0
+func (l *Listener) Addr() net.Addr {
+	return l.listener.Addr()
+}
+
+func (l *Listener) Write(data []byte) (int, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.conn == nil {
+		return len(data), nil
+	}
+
+	return l.conn.Write(data)
+}
+
+func (l *Listener) Receive() (byte, bool) {
+	select {
+	case value := <-l.received:
+		return value, true
+	default:
+		return 0, false
+	}
+}
+
+func (l *Listener) Close() error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.disconnect()
+	return l.listener.Close()
+}
@@ -0,0 +1,49 @@
+package acia6551
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// Endpoint on a pseudo terminal, a terminal program (screen, minicom, ...) connects to its Name
+type PTY struct {
+	*Stream
+	Name   string // Path of the terminal side, e.g. /dev/pts/3
+	master *os.File
+}
+
+func OpenPTY() (*PTY, error) {
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var unlock int32
+	if err := ioctl(master, syscall.TIOCSPTLCK, unsafe.Pointer(&unlock)); err != nil {
+		master.Close()
+		return nil, err
+	}
+
+	var number uint32
+	if err := ioctl(master, syscall.TIOCGPTN, unsafe.Pointer(&number)); err != nil {
+		master.Close()
+		return nil, err
+	}
+
+	return &PTY{Stream: NewStream(master, master), Name: fmt.Sprintf("/dev/pts/%d", number), master: master}, nil
+}
+
+func ioctl(file *os.File, request uintptr, argument unsafe.Pointer) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, file.Fd(), request, uintptr(argument))
+	if errno != 0 {
+		return errno
+	}
+
+	return nil
+}
+
+func (p *PTY) Close() error {
+	return p.master.Close()
+}
@@ -0,0 +1,20 @@
+//go:build !linux
+
+package acia6551
+
+import (
+	"errors"
+)
+
+type PTY struct {
+	*Stream
+	Name string
+}
+
+func OpenPTY() (*PTY, error) {
+	return nil, errors.New("the pseudo terminals are only supported on linux")
+}
+
+func (p *PTY) Close() error {
+	return nil
+}
@@ -0,0 +1,142 @@
+// Apple-1 computer
+//
+// RAM, WozMon at $FF00 and the 6821 PIA at $D010-$D013 wired to a text terminal: the keyboard on
+// port A with the strobe on CA1, the display on port B with the data available on CB2. The
+// terminal is an acia6551.Endpoint, so the process stdio, a TCP connection or a Pipe in the tests.
+//
+//	computer := apple1.New(acia6551.Stdio())
+//	clock.New(clock.FREQUENCY_1MHZ, computer.Tick).Run(stop)
+//
+// The display shows the upper case characters and the terminal input is converted the same way,
+// the Enter key is the carriage return and the backspace is the WozMon underscore.
+package apple1
+
+import (
+	"github.com/costamauricio/6502-emulator/pkg/acia6551"
+	"github.com/costamauricio/6502-emulator/pkg/bus"
+	"github.com/costamauricio/6502-emulator/pkg/cpu6502"
+	"github.com/costamauricio/6502-emulator/pkg/pia6821"
+	"github.com/costamauricio/6502-emulator/pkg/system"
+)
+
+// Memory map
+const (
+	KBD          = 0xD010 // Keyboard data, bit 7 always set
+	KBDCR        = 0xD011 // Keyboard control, bit 7 set when a key is pressed
+	DSP          = 0xD012 // Display data, bit 7 set while busy
+	DSPCR        = 0xD013 // Display control
+	WOZMON_START = 0xFF00
+)
+
+// Cicles between the terminal checks for a key
+const KEYBOARD_POLL = 1000
+
+type Apple1 struct {
+	Cpu      *cpu6502.CPU
+	Bus      *bus.Bus
+	System   *system.System
+	Pia      *pia6821.PIA
+	Terminal acia6551.Endpoint
+
+	carriage bool // The last key was a carriage return, so a following line feed is dropped
+}
+
+func New(terminal acia6551.Endpoint) *Apple1 {
+	computer := &Apple1{Bus: &bus.Bus{}, Pia: pia6821.New(), Terminal: terminal}
+
+	computer.Bus.Map(KBD, DSPCR, computer.Pia)
+	computer.Bus.Map(WOZMON_START, 0xFFFF, bus.ROM(WOZMON[:]))
+
+	// the display is always ready, it takes the character when CB2 goes low and acknowledges on CB1
+	computer.Pia.OnCB2 = func(level bool) {
+		if !level {
+			computer.display(computer.Pia.PinsB() & 0x7F)
+			computer.Pia.SetCB1(true)
+			computer.Pia.SetCB1(false)
+		}
+	}
+
+	computer.Cpu = cpu6502.New(computer.Bus)
+	computer.System = system.New(computer.Cpu, computer.Bus)
+	computer.System.Add((*keyboard)(computer), 1)
+
+	return computer
+}
+
+// Presses the reset button
+func (a *Apple1) Reset() {
+	a.Pia.Reset()
+	a.Cpu.Reset()
+}
+
+func (a *Apple1) Tick() {
+	a.System.Tick()
+}
+
+// Ticks the cicles
+func (a *Apple1) Run(cycles uint64) {
+	a.System.Run(cycles)
+}
+
+// Loads a program in the RAM, e.g. the Apple-1 BASIC at $E000
+func (a *Apple1) Load(program []byte, address uint16) {
+	a.Bus.LoadRam(program, address)
+}
+
+func (a *Apple1) display(character byte) {
+	if a.Terminal == nil {
+		return
+	}
+
+	switch {
+	case character == '\r':
+		a.Terminal.Write([]byte{'\n'})
+	case character >= 0x20 && character < 0x7F:
+		a.Terminal.Write([]byte{character})
+	}
+}
+
+// Polls the terminal for keys
+type keyboard Apple1
+
+func (k *keyboard) Run(now uint64) uint64 {
+	pia := k.Pia
+
+	// the previous key wasn't read yet
+	if pia.Peek(pia6821.REG_CONTROL_A)&pia6821.FLAG_C1 > 0 || k.Terminal == nil {
+		return now + KEYBOARD_POLL
+	}
+
+	for {
+		value, found := k.Terminal.Receive()
+		if !found {
+			break
+		}
+
+		if key, ok := k.translate(value); ok {
+			pia.SetInputA(key | 0x80)
+			pia.SetCA1(true)
+			pia.SetCA1(false)
+			break
+		}
+	}
+
+	return now + KEYBOARD_POLL
+}
+
+// Converts the terminal byte to the Apple-1 keyboard
+func (k *keyboard) translate(value byte) (byte, bool) {
+	carriage := k.carriage
+	k.carriage = value == '\r'
+
+	switch {
+	case value == '\n':
+		return '\r', !carriage
+	case value == '\b' || value == 0x7F:
+		return '_', true
+	case value >= 'a' && value <= 'z':
+		return value - 'a' + 'A', true
+	}
+
+	return value, value < 0x60
+}
@@ -0,0 +1,40 @@
+package apple1
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/costamauricio/6502-emulator/pkg/acia6551"
+)
+
+func TestWozMon(t *testing.T) {
+	terminal := acia6551.NewPipe()
+	computer := New(terminal)
+
+	computer.Run(10000)
+	if output := string(terminal.Output()); output != "\\\n" {
+		t.Fatalf("expected the prompt, got %q", output)
+	}
+
+	terminal.Send([]byte("ff00.ff07\n"))
+	computer.Run(200000)
+
+	// WozMon echoes the carriage return and starts the dump with another one
+	expected := "\\\nFF00.FF07\n\nFF00: D8 58 A0 7F 8C 12 D0 A9\n"
+	if output := string(terminal.Output()); output != expected {
+		t.Errorf("expected the memory dump %q, got %q", expected, output)
+	}
+}
+
+func TestWozMonRun(t *testing.T) {
+	terminal := acia6551.NewPipe()
+	computer := New(terminal)
+
+	// prints A with ECHO and goes back to GETLINE
+	terminal.Send([]byte("300: A9 C1 20 EF FF 4C 1F FF\n300R\n"))
+	computer.Run(500000)
+
+	if output := string(terminal.Output()); !strings.HasSuffix(output, "300R\n\n0300: A9A\n") {
+		t.Errorf("expected the program to print A, got %q", output)
+	}
+}
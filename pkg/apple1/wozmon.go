@@ -0,0 +1,133 @@
+package apple1
+
+// WozMon, the Apple-1 monitor by Steve Wozniak, assembled at $FF00
+var WOZMON = [256]byte{
+	0xD8,       // FF00 RESET: CLD
+	0x58,       // FF01 CLI
+	0xA0, 0x7F, // FF02 LDY #$7F
+	0x8C, 0x12, 0xD0, // FF04 STY DSP
+	0xA9, 0xA7, // FF07 LDA #$A7
+	0x8D, 0x11, 0xD0, // FF09 STA KBDCR
+	0x8D, 0x13, 0xD0, // FF0C STA DSPCR
+	0xC9, 0xDF, // FF0F NOTCR: CMP #'_'+$80
+	0xF0, 0x13, // FF11 BEQ BACKSPACE
+	0xC9, 0x9B, // FF13 CMP #$9B
+	0xF0, 0x03, // FF15 BEQ ESCAPE
+	0xC8,       // FF17 INY
+	0x10, 0x0F, // FF18 BPL NEXTCHAR
+	0xA9, 0xDC, // FF1A ESCAPE: LDA #'\'+$80
+	0x20, 0xEF, 0xFF, // FF1C JSR ECHO
+	0xA9, 0x8D, // FF1F GETLINE: LDA #$8D
+	0x20, 0xEF, 0xFF, // FF21 JSR ECHO
+	0xA0, 0x01, // FF24 LDY #$01
+	0x88,       // FF26 BACKSPACE: DEY
+	0x30, 0xF6, // FF27 BMI GETLINE
+	0xAD, 0x11, 0xD0, // FF29 NEXTCHAR: LDA KBDCR
+	0x10, 0xFB, // FF2C BPL NEXTCHAR
+	0xAD, 0x10, 0xD0, // FF2E LDA KBD
+	0x99, 0x00, 0x02, // FF31 STA IN,Y
+	0x20, 0xEF, 0xFF, // FF34 JSR ECHO
+	0xC9, 0x8D, // FF37 CMP #$8D
+	0xD0, 0xD4, // FF39 BNE NOTCR
+	0xA0, 0xFF, // FF3B LDY #$FF
+	0xA9, 0x00, // FF3D LDA #$00
+	0xAA,       // FF3F TAX
+	0x0A,       // FF40 SETSTOR: ASL
+	0x85, 0x2B, // FF41 SETMODE: STA MODE
+	0xC8,             // FF43 BLSKIP: INY
+	0xB9, 0x00, 0x02, // FF44 NEXTITEM: LDA IN,Y
+	0xC9, 0x8D, // FF47 CMP #$8D
+	0xF0, 0xD4, // FF49 BEQ GETLINE
+	0xC9, 0xAE, // FF4B CMP #'.'+$80
+	0x90, 0xF4, // FF4D BCC BLSKIP
+	0xF0, 0xF0, // FF4F BEQ SETMODE
+	0xC9, 0xBA, // FF51 CMP #':'+$80
+	0xF0, 0xEB, // FF53 BEQ SETSTOR
+	0xC9, 0xD2, // FF55 CMP #'R'+$80
+	0xF0, 0x3B, // FF57 BEQ RUN
+	0x86, 0x28, // FF59 STX L
+	0x86, 0x29, // FF5B STX H
+	0x84, 0x2A, // FF5D STY YSAV
+	0xB9, 0x00, 0x02, // FF5F NEXTHEX: LDA IN,Y
+	0x49, 0xB0, // FF62 EOR #$B0
+	0xC9, 0x0A, // FF64 CMP #$0A
+	0x90, 0x06, // FF66 BCC DIG
+	0x69, 0x88, // FF68 ADC #$88
+	0xC9, 0xFA, // FF6A CMP #$FA
+	0x90, 0x11, // FF6C BCC NOTHEX
+	0x0A,       // FF6E DIG: ASL
+	0x0A,       // FF6F ASL
+	0x0A,       // FF70 ASL
+	0x0A,       // FF71 ASL
+	0xA2, 0x04, // FF72 LDX #$04
+	0x0A,       // FF74 HEXSHIFT: ASL
+	0x26, 0x28, // FF75 ROL L
+	0x26, 0x29, // FF77 ROL H
+	0xCA,       // FF79 DEX
+	0xD0, 0xF8, // FF7A BNE HEXSHIFT
+	0xC8,       // FF7C INY
+	0xD0, 0xE0, // FF7D BNE NEXTHEX
+	0xC4, 0x2A, // FF7F NOTHEX: CPY YSAV
+	0xF0, 0x97, // FF81 BEQ ESCAPE
+	0x24, 0x2B, // FF83 BIT MODE
+	0x50, 0x10, // FF85 BVC NOTSTOR
+	0xA5, 0x28, // FF87 LDA L
+	0x81, 0x26, // FF89 STA (STL,X)
+	0xE6, 0x26, // FF8B INC STL
+	0xD0, 0xB5, // FF8D BNE NEXTITEM
+	0xE6, 0x27, // FF8F INC STH
+	0x4C, 0x44, 0xFF, // FF91 TONEXTITEM: JMP NEXTITEM
+	0x6C, 0x24, 0x00, // FF94 RUN: JMP (XAML)
+	0x30, 0x2B, // FF97 NOTSTOR: BMI XAMNEXT
+	0xA2, 0x02, // FF99 LDX #$02
+	0xB5, 0x27, // FF9B SETADR: LDA L-1,X
+	0x95, 0x25, // FF9D STA STL-1,X
+	0x95, 0x23, // FF9F STA XAML-1,X
+	0xCA,       // FFA1 DEX
+	0xD0, 0xF7, // FFA2 BNE SETADR
+	0xD0, 0x14, // FFA4 NXTPRNT: BNE PRDATA
+	0xA9, 0x8D, // FFA6 LDA #$8D
+	0x20, 0xEF, 0xFF, // FFA8 JSR ECHO
+	0xA5, 0x25, // FFAB LDA XAMH
+	0x20, 0xDC, 0xFF, // FFAD JSR PRBYTE
+	0xA5, 0x24, // FFB0 LDA XAML
+	0x20, 0xDC, 0xFF, // FFB2 JSR PRBYTE
+	0xA9, 0xBA, // FFB5 LDA #':'+$80
+	0x20, 0xEF, 0xFF, // FFB7 JSR ECHO
+	0xA9, 0xA0, // FFBA PRDATA: LDA #' '+$80
+	0x20, 0xEF, 0xFF, // FFBC JSR ECHO
+	0xA1, 0x24, // FFBF LDA (XAML,X)
+	0x20, 0xDC, 0xFF, // FFC1 JSR PRBYTE
+	0x86, 0x2B, // FFC4 XAMNEXT: STX MODE
+	0xA5, 0x24, // FFC6 LDA XAML
+	0xC5, 0x28, // FFC8 CMP L
+	0xA5, 0x25, // FFCA LDA XAMH
+	0xE5, 0x29, // FFCC SBC H
+	0xB0, 0xC1, // FFCE BCS TONEXTITEM
+	0xE6, 0x24, // FFD0 INC XAML
+	0xD0, 0x02, // FFD2 BNE MOD8CHK
+	0xE6, 0x25, // FFD4 INC XAMH
+	0xA5, 0x24, // FFD6 MOD8CHK: LDA XAML
+	0x29, 0x07, // FFD8 AND #$07
+	0x10, 0xC8, // FFDA BPL NXTPRNT
+	0x48,             // FFDC PRBYTE: PHA
+	0x4A,             // FFDD LSR
+	0x4A,             // FFDE LSR
+	0x4A,             // FFDF LSR
+	0x4A,             // FFE0 LSR
+	0x20, 0xE5, 0xFF, // FFE1 JSR PRHEX
+	0x68,       // FFE4 PLA
+	0x29, 0x0F, // FFE5 PRHEX: AND #$0F
+	0x09, 0xB0, // FFE7 ORA #'0'+$80
+	0xC9, 0xBA, // FFE9 CMP #$BA
+	0x90, 0x02, // FFEB BCC ECHO
+	0x69, 0x06, // FFED ADC #$06
+	0x2C, 0x12, 0xD0, // FFEF ECHO: BIT DSP
+	0x30, 0xFB, // FFF2 BMI ECHO
+	0x8D, 0x12, 0xD0, // FFF4 STA DSP
+	0x60,       // FFF7 RTS
+	0x00, 0x00, // FFF8
+	0x00, 0x0F, // FFFA NMI vector
+	0x00, 0xFF, // FFFC reset vector
+	0x00, 0x00, // FFFE IRQ vector
+}
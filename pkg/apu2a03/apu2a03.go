@@ -0,0 +1,704 @@
+// Ricoh 2A03 Audio Processing Unit of the NES
+//
+// The two pulse channels with their sweeps, the triangle, the noise and the delta modulation
+// channel, the envelopes and length counters clocked by the frame counter, and the non-linear
+// mixer. It's clocked by the system on the CPU cicles and mapped on $4000-$4017:
+//
+//	apu := apu2a03.New(apu2a03.FREQUENCY_NTSC, 44100)
+//	apu.Memory = dataBus.Read
+//	dataBus.Map(0x4000, 0x4017, machine.Add(apu, 1))
+//
+// The channels advance when the APU runs, the mixed output is averaged over each sample period
+// and kept until it's taken with Samples.
+package apu2a03
+
+import (
+	"math"
+)
+
+// CPU clocks
+const (
+	FREQUENCY_NTSC = 1789773
+	FREQUENCY_PAL  = 1662607
+)
+
+// Registers from $4000
+const (
+	REG_PULSE1   = 0x00 // Duty, halt, envelope, sweep, timer low, length and timer high
+	REG_PULSE2   = 0x04
+	REG_TRIANGLE = 0x08 // Control and linear counter, unused, timer low, length and timer high
+	REG_NOISE    = 0x0C // Halt and envelope, unused, mode and period, length
+	REG_DMC      = 0x10 // IRQ, loop and rate, direct load, sample address, sample length
+	REG_STATUS   = 0x15 // Enables the channels, reads their state and the interrupts
+	REG_FRAME    = 0x17 // Frame counter mode and IRQ inhibit
+)
+
+// Status bits
+const (
+	STATUS_PULSE1    byte = 0x01
+	STATUS_PULSE2    byte = 0x02
+	STATUS_TRIANGLE  byte = 0x04
+	STATUS_NOISE     byte = 0x08
+	STATUS_DMC       byte = 0x10
+	STATUS_FRAME_IRQ byte = 0x40
+	STATUS_DMC_IRQ   byte = 0x80
+)
+
+// Frame counter, in CPU cicles
+var (
+	FOUR_STEP = [4]uint64{7457, 14913, 22371, 29829}
+	FIVE_STEP = [5]uint64{7457, 14913, 22371, 29829, 37281}
+)
+
+// Cicles the APU runs at most between the system runs, it bounds the pending output
+const BATCH = 4096
+
+var lengths = [32]byte{
+	10, 254, 20, 2, 40, 4, 80, 6, 160, 8, 60, 10, 14, 12, 26, 14,
+	12, 16, 24, 18, 48, 20, 96, 22, 192, 24, 72, 26, 16, 28, 32, 30,
+}
+
+var duties = [4][8]byte{
+	{0, 1, 0, 0, 0, 0, 0, 0},
+	{0, 1, 1, 0, 0, 0, 0, 0},
+	{0, 1, 1, 1, 1, 0, 0, 0},
+	{1, 0, 0, 1, 1, 1, 1, 1},
+}
+
+var triangleSteps = [32]byte{
+	15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1, 0,
+	0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15,
+}
+
+// Periods in CPU cicles
+var (
+	NOISE_PERIODS = [16]uint16{4, 8, 16, 32, 64, 96, 128, 160, 202, 254, 380, 508, 762, 1016, 2034, 4068}
+	DMC_RATES     = [16]uint16{428, 380, 340, 320, 286, 254, 226, 214, 190, 160, 142, 128, 106, 84, 72, 54}
+)
+
+type envelope struct {
+	start    bool
+	loop     bool // Also the length counter halt
+	constant bool
+	volume   byte // Constant volume or the divider period
+	divider  byte
+	decay    byte
+}
+
+func (e *envelope) write(value byte) {
+	e.loop = value&0x20 > 0
+	e.constant = value&0x10 > 0
+	e.volume = value & 0x0F
+}
+
+func (e *envelope) clock() {
+	if e.start {
+		e.start, e.decay, e.divider = false, 15, e.volume
+		return
+	}
+
+	if e.divider > 0 {
+		e.divider--
+		return
+	}
+
+	e.divider = e.volume
+	if e.decay > 0 {
+		e.decay--
+	} else if e.loop {
+		e.decay = 15
+	}
+}
+
+func (e *envelope) output() byte {
+	if e.constant {
+		return e.volume
+	}
+
+	return e.decay
+}
+
+type pulse struct {
+	envelope
+	enabled bool
+	second  bool // The second pulse negates the sweep in two's complement
+
+	duty   byte
+	step   byte
+	period uint16
+	timer  uint16
+	length byte
+
+	sweepEnabled bool
+	sweepPeriod  byte
+	sweepNegate  bool
+	sweepShift   byte
+	sweepDivider byte
+	sweepReload  bool
+}
+
+func (p *pulse) write(register uint16, value byte) {
+	switch register & 0x03 {
+	case 0:
+		p.duty = value >> 6
+		p.envelope.write(value)
+	case 1:
+		p.sweepEnabled = value&0x80 > 0
+		p.sweepPeriod = value >> 4 & 0x07
+		p.sweepNegate = value&0x08 > 0
+		p.sweepShift = value & 0x07
+		p.sweepReload = true
+	case 2:
+		p.period = p.period&0x0700 | uint16(value)
+	case 3:
+		p.period = p.period&0x00FF | uint16(value&0x07)<<8
+		if p.enabled {
+			p.length = lengths[value>>3]
+		}
+
+		p.step, p.start = 0, true
+	}
+}
+
+// Clocked every other CPU cycle
+func (p *pulse) clock() {
+	if p.timer > 0 {
+		p.timer--
+		return
+	}
+
+	p.timer = p.period
+	p.step = (p.step + 1) & 0x07
+}
+
+func (p *pulse) target() int {
+	change := int(p.period >> p.sweepShift)
+
+	if !p.sweepNegate {
+		return int(p.period) + change
+	}
+
+	if p.second {
+		return int(p.period) - change
+	}
+
+	return int(p.period) - change - 1
+}
+
+func (p *pulse) muted() bool {
+	return p.period < 8 || p.target() > 0x7FF
+}
+
+func (p *pulse) clockSweep() {
+	if p.sweepDivider == 0 && p.sweepEnabled && p.sweepShift > 0 && !p.muted() {
+		if target := p.target(); target >= 0 {
+			p.period = uint16(target)
+		}
+	}
+
+	if p.sweepDivider == 0 || p.sweepReload {
+		p.sweepDivider, p.sweepReload = p.sweepPeriod, false
+		return
+	}
+
+	p.sweepDivider--
+}
+
+func (p *pulse) clockLength() {
+	if p.length > 0 && !p.loop {
+		p.length--
+	}
+}
+
+func (p *pulse) output() byte {
+	if p.length == 0 || p.muted() || duties[p.duty][p.step] == 0 {
+		return 0
+	}
+
+	return p.envelope.output()
+}
+
+type triangle struct {
+	enabled bool
+	control bool // Halts the length counter and reloads the linear counter
+
+	period uint16
+	timer  uint16
+	step   byte
+	length byte
+
+	linear       byte
+	linearReload byte
+	reload       bool
+}
+
+func (t *triangle) write(register uint16, value byte) {
+	switch register & 0x03 {
+	case 0:
+		t.control = value&0x80 > 0
+		t.linearReload = value & 0x7F
+	case 2:
+		t.period = t.period&0x0700 | uint16(value)
+	case 3:
+		t.period = t.period&0x00FF | uint16(value&0x07)<<8
+		if t.enabled {
+			t.length = lengths[value>>3]
+		}
+
+		t.reload = true
+	}
+}
+
+// Clocked every CPU cycle
+func (t *triangle) clock() {
+	if t.timer > 0 {
+		t.timer--
+		return
+	}
+
+	t.timer = t.period
+	if t.length > 0 && t.linear > 0 && t.period >= 2 {
+		t.step = (t.step + 1) & 0x1F
+	}
+}
+
+func (t *triangle) clockLinear() {
+	if t.reload {
+		t.linear = t.linearReload
+	} else if t.linear > 0 {
+		t.linear--
+	}
+
+	if !t.control {
+		t.reload = false
+	}
+}
+
+func (t *triangle) clockLength() {
+	if t.length > 0 && !t.control {
+		t.length--
+	}
+}
+
+func (t *triangle) output() byte {
+	return triangleSteps[t.step]
+}
+
+type noise struct {
+	envelope
+	enabled bool
+
+	mode   bool // Short sequence from the bit 6
+	period uint16
+	timer  uint16
+	shift  uint16
+	length byte
+}
+
+func (n *noise) write(register uint16, value byte) {
+	switch register & 0x03 {
+	case 0:
+		n.envelope.write(value)
+	case 2:
+		n.mode = value&0x80 > 0
+		n.period = NOISE_PERIODS[value&0x0F]
+	case 3:
+		if n.enabled {
+			n.length = lengths[value>>3]
+		}
+
+		n.start = true
+	}
+}
+
+// Clocked every CPU cycle with the periods in CPU cicles
+func (n *noise) clock() {
+	if n.timer > 0 {
+		n.timer--
+		return
+	}
+
+	n.timer = n.period - 1
+
+	tap := n.shift >> 1
+	if n.mode {
+		tap = n.shift >> 6
+	}
+
+	feedback := (n.shift ^ tap) & 0x01
+	n.shift = n.shift>>1 | feedback<<14
+}
+
+func (n *noise) clockLength() {
+	if n.length > 0 && !n.loop {
+		n.length--
+	}
+}
+
+func (n *noise) output() byte {
+	if n.length == 0 || n.shift&0x01 > 0 {
+		return 0
+	}
+
+	return n.envelope.output()
+}
+
+type dmc struct {
+	irqEnabled bool
+	loop       bool
+	irq        bool
+
+	period uint16
+	timer  uint16
+	level  byte
+
+	start     uint16
+	size      uint16
+	address   uint16
+	remaining uint16
+
+	buffer   byte
+	buffered bool
+	shift    byte
+	bits     byte
+	silence  bool
+}
+
+func (d *dmc) write(register uint16, value byte) {
+	switch register & 0x03 {
+	case 0:
+		d.irqEnabled = value&0x80 > 0
+		d.loop = value&0x40 > 0
+		d.period = DMC_RATES[value&0x0F]
+		if !d.irqEnabled {
+			d.irq = false
+		}
+	case 1:
+		d.level = value & 0x7F
+	case 2:
+		d.start = 0xC000 | uint16(value)<<6
+	case 3:
+		d.size = uint16(value)<<4 | 1
+	}
+}
+
+func (d *dmc) restart() {
+	d.address, d.remaining = d.start, d.size
+}
+
+// Clocked every CPU cycle, the bytes are fetched from the memory
+func (d *dmc) clock(memory func(address uint16) byte) {
+	if !d.buffered && d.remaining > 0 && memory != nil {
+		d.buffer, d.buffered = memory(d.address), true
+
+		d.address++
+		if d.address == 0 {
+			d.address = 0x8000
+		}
+
+		if d.remaining--; d.remaining == 0 {
+			if d.loop {
+				d.restart()
+			} else if d.irqEnabled {
+				d.irq = true
+			}
+		}
+	}
+
+	if d.timer > 0 {
+		d.timer--
+		return
+	}
+
+	d.timer = d.period - 1
+
+	if !d.silence {
+		if d.shift&0x01 > 0 && d.level <= 125 {
+			d.level += 2
+		} else if d.shift&0x01 == 0 && d.level >= 2 {
+			d.level -= 2
+		}
+	}
+
+	d.shift >>= 1
+
+	if d.bits > 0 {
+		d.bits--
+	}
+
+	if d.bits == 0 {
+		d.bits = 8
+		d.silence = !d.buffered
+		d.shift, d.buffered = d.buffer, false
+	}
+}
+
+type APU struct {
+	IRQ    func(asserted bool)       // Optional, the frame counter and DMC interrupts
+	Memory func(address uint16) byte // Optional, read by the DMC, the CPU bus
+
+	Frequency  int
+	SampleRate int
+
+	pulse1, pulse2 pulse
+	triangle       triangle
+	noise          noise
+	dmc            dmc
+
+	fiveStep   bool
+	irqInhibit bool
+	frameIRQ   bool
+	frame      uint64 // CPU cicles into the frame counter sequence
+	irq        bool
+
+	now     uint64  // Cycle the APU ran up to
+	sum     float64 // Output summed over the current sample
+	summed  int
+	phase   float64 // Cicles into the current sample
+	samples []float32
+
+	filtered, previous float64 // DC blocking filter
+}
+
+func New(frequency int, sampleRate int) *APU {
+	apu := &APU{Frequency: frequency, SampleRate: sampleRate}
+	apu.Reset()
+
+	return apu
+}
+
+// Silences the channels as on power up
+func (a *APU) Reset() {
+	a.pulse1, a.pulse2 = pulse{}, pulse{second: true}
+	a.triangle, a.noise = triangle{}, noise{shift: 1, period: NOISE_PERIODS[0]}
+	a.dmc = dmc{period: DMC_RATES[0], bits: 8, silence: true}
+	a.fiveStep, a.irqInhibit, a.frameIRQ, a.frame = false, false, false, 0
+	a.updateIRQ()
+}
+
+func (a *APU) Read(register uint16) byte {
+	value := a.Peek(register)
+
+	if register == REG_STATUS {
+		a.frameIRQ = false
+		a.updateIRQ()
+	}
+
+	return value
+}
+
+// Reads the status without clearing the frame interrupt
+func (a *APU) Peek(register uint16) byte {
+	if register != REG_STATUS {
+		return 0
+	}
+
+	var status byte
+	for bit, active := range []bool{
+		a.pulse1.length > 0, a.pulse2.length > 0, a.triangle.length > 0, a.noise.length > 0,
+		a.dmc.remaining > 0, false, a.frameIRQ, a.dmc.irq,
+	} {
+		if active {
+			status |= 1 << bit
+		}
+	}
+
+	return status
+}
+
+func (a *APU) Write(register uint16, value byte) {
+	switch {
+	case register < REG_PULSE2:
+		a.pulse1.write(register, value)
+	case register < REG_TRIANGLE:
+		a.pulse2.write(register, value)
+	case register < REG_NOISE:
+		a.triangle.write(register, value)
+	case register < REG_DMC:
+		a.noise.write(register, value)
+	case register < 0x14:
+		a.dmc.write(register, value)
+	case register == REG_STATUS:
+		a.pulse1.enabled, a.pulse2.enabled = value&STATUS_PULSE1 > 0, value&STATUS_PULSE2 > 0
+		a.triangle.enabled, a.noise.enabled = value&STATUS_TRIANGLE > 0, value&STATUS_NOISE > 0
+
+		for _, channel := range []struct {
+			enabled bool
+			length  *byte
+		}{
+			{a.pulse1.enabled, &a.pulse1.length}, {a.pulse2.enabled, &a.pulse2.length},
+			{a.triangle.enabled, &a.triangle.length}, {a.noise.enabled, &a.noise.length},
+		} {
+			if !channel.enabled {
+				*channel.length = 0
+			}
+		}
+
+		a.dmc.irq = false
+		if value&STATUS_DMC == 0 {
+			a.dmc.remaining = 0
+		} else if a.dmc.remaining == 0 {
+			a.dmc.restart()
+		}
+	case register == REG_FRAME:
+		a.fiveStep = value&0x80 > 0
+		a.irqInhibit = value&0x40 > 0
+		a.frame = 0
+
+		if a.irqInhibit {
+			a.frameIRQ = false
+		}
+
+		// the 5 step mode clocks the units at once
+		if a.fiveStep {
+			a.quarterFrame()
+			a.halfFrame()
+		}
+	}
+
+	a.updateIRQ()
+}
+
+func (a *APU) updateIRQ() {
+	asserted := a.frameIRQ || a.dmc.irq
+	if asserted != a.irq {
+		a.irq = asserted
+		if a.IRQ != nil {
+			a.IRQ(asserted)
+		}
+	}
+}
+
+// Envelopes and the triangle linear counter
+func (a *APU) quarterFrame() {
+	a.pulse1.envelope.clock()
+	a.pulse2.envelope.clock()
+	a.noise.envelope.clock()
+	a.triangle.clockLinear()
+}
+
+// Length counters and sweeps
+func (a *APU) halfFrame() {
+	a.pulse1.clockLength()
+	a.pulse2.clockLength()
+	a.triangle.clockLength()
+	a.noise.clockLength()
+	a.pulse1.clockSweep()
+	a.pulse2.clockSweep()
+}
+
+func (a *APU) clockFrame() {
+	a.frame++
+
+	if a.fiveStep {
+		switch a.frame {
+		case FIVE_STEP[0], FIVE_STEP[2]:
+			a.quarterFrame()
+		case FIVE_STEP[1]:
+			a.quarterFrame()
+			a.halfFrame()
+		case FIVE_STEP[4]:
+			a.quarterFrame()
+			a.halfFrame()
+			a.frame = 0
+		}
+
+		return
+	}
+
+	switch a.frame {
+	case FOUR_STEP[0], FOUR_STEP[2]:
+		a.quarterFrame()
+	case FOUR_STEP[1]:
+		a.quarterFrame()
+		a.halfFrame()
+	case FOUR_STEP[3]:
+		a.quarterFrame()
+		a.halfFrame()
+		a.frame = 0
+
+		if !a.irqInhibit {
+			a.frameIRQ = true
+			a.updateIRQ()
+		}
+	}
+}
+
+// Mixed output of the channels, from 0 to about 1
+func (a *APU) Output() float64 {
+	var pulses, tnd float64
+
+	if sum := float64(a.pulse1.output()) + float64(a.pulse2.output()); sum > 0 {
+		pulses = 95.88 / (8128/sum + 100)
+	}
+
+	triangle, noise, dmc := float64(a.triangle.output()), float64(a.noise.output()), float64(a.dmc.level)
+	if sum := triangle/8227 + noise/12241 + dmc/22638; sum > 0 {
+		tnd = 159.79 / (1/sum + 100)
+	}
+
+	return pulses + tnd
+}
+
+// Advances a CPU cycle
+func (a *APU) tick() {
+	if a.now&0x01 == 0 {
+		a.pulse1.clock()
+		a.pulse2.clock()
+	}
+
+	a.triangle.clock()
+	a.noise.clock()
+	a.dmc.clock(a.Memory)
+	a.clockFrame()
+
+	if a.dmc.irq && !a.irq {
+		a.updateIRQ()
+	}
+
+	a.now++
+	a.sample()
+}
+
+// Averages the output over the sample period
+func (a *APU) sample() {
+	if a.SampleRate <= 0 {
+		return
+	}
+
+	a.sum += a.Output()
+	a.summed++
+	a.phase++
+
+	period := float64(a.Frequency) / float64(a.SampleRate)
+	if a.phase < period {
+		return
+	}
+
+	a.phase -= period
+	value := a.sum / float64(a.summed)
+	a.sum, a.summed = 0, 0
+
+	// removes the DC offset of the mixer, about 40 Hz
+	a.filtered = 0.996*a.filtered + value - a.previous
+	a.previous = value
+
+	a.samples = append(a.samples, float32(math.Max(-1, math.Min(1, a.filtered*2))))
+}
+
+// Runs the channels up to now
+func (a *APU) Run(now uint64) uint64 {
+	for a.now < now {
+		a.tick()
+	}
+
+	return now + BATCH
+}
+
+// Takes the samples produced since the last call, from -1 to 1
+func (a *APU) Samples() []float32 {
+	samples := a.samples
+	a.samples = nil
+
+	return samples
+}
@@ -0,0 +1,93 @@
+package apu2a03
+
+import (
+	"testing"
+)
+
+func TestLengthCounters(t *testing.T) {
+	apu := New(FREQUENCY_NTSC, 0)
+
+	// loaded only while the channel is enabled
+	apu.Write(REG_PULSE1+3, 0x08)
+	if status := apu.Read(REG_STATUS); status != 0 {
+		t.Errorf("expected silent channels, got status $%02X", status)
+	}
+
+	apu.Write(REG_STATUS, STATUS_PULSE1|STATUS_NOISE)
+	apu.Write(REG_PULSE1, 0x00)
+	apu.Write(REG_PULSE1+3, 0x08) // length 254
+	apu.Write(REG_NOISE+3, 0x18)  // length 2
+
+	if status := apu.Read(REG_STATUS); status != STATUS_PULSE1|STATUS_NOISE {
+		t.Errorf("expected pulse 1 and noise playing, got status $%02X", status)
+	}
+
+	// two half frames silence the noise
+	apu.Run(FOUR_STEP[3] + 1)
+	if status := apu.Peek(REG_STATUS); status&STATUS_NOISE != 0 || status&STATUS_PULSE1 == 0 {
+		t.Errorf("expected the noise stopped, got status $%02X", status)
+	}
+
+	apu.Write(REG_STATUS, 0)
+	if status := apu.Peek(REG_STATUS); status&STATUS_PULSE1 != 0 {
+		t.Errorf("expected pulse 1 stopped when disabled, got status $%02X", status)
+	}
+}
+
+func TestFrameIRQ(t *testing.T) {
+	apu := New(FREQUENCY_NTSC, 0)
+
+	irq := false
+	apu.IRQ = func(asserted bool) { irq = asserted }
+
+	apu.Run(FOUR_STEP[3] - 1)
+	if irq {
+		t.Fatal("interrupt before the end of the sequence")
+	}
+
+	apu.Run(FOUR_STEP[3])
+	if !irq || apu.Peek(REG_STATUS)&STATUS_FRAME_IRQ == 0 {
+		t.Fatal("expected the frame interrupt")
+	}
+
+	apu.Read(REG_STATUS)
+	if irq {
+		t.Error("expected the interrupt cleared by reading the status")
+	}
+
+	apu.Write(REG_FRAME, 0x40)
+	apu.Run(3 * FOUR_STEP[3])
+	if irq {
+		t.Error("expected the interrupt inhibited")
+	}
+}
+
+func TestDMC(t *testing.T) {
+	apu := New(FREQUENCY_NTSC, 0)
+
+	fetched := []uint16{}
+	apu.Memory = func(address uint16) byte {
+		fetched = append(fetched, address)
+		return 0xFF
+	}
+
+	apu.Write(REG_DMC, 0x8F)   // IRQ, fastest rate
+	apu.Write(REG_DMC+1, 0x40) // level 64
+	apu.Write(REG_DMC+2, 0x01) // $C040
+	apu.Write(REG_DMC+3, 0x00) // 1 byte
+	apu.Write(REG_STATUS, STATUS_DMC)
+
+	apu.Run(20 * uint64(DMC_RATES[15]))
+
+	if len(fetched) != 1 || fetched[0] != 0xC040 {
+		t.Errorf("expected a fetch of $C040, got %v", fetched)
+	}
+
+	if apu.dmc.level != 64+16 {
+		t.Errorf("expected the level raised by 8 ones, got %d", apu.dmc.level)
+	}
+
+	if apu.Peek(REG_STATUS) != STATUS_DMC_IRQ {
+		t.Errorf("expected only the DMC interrupt, got status $%02X", apu.Peek(REG_STATUS))
+	}
+}
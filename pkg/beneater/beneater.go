@@ -0,0 +1,112 @@
+// Ben Eater's breadboard 6502 computer
+//
+// 16K of RAM at $0000, the 6551 ACIA at $5000, the 6522 VIA at $6000 and the 32K ROM at $8000,
+// with the 16x2 HD44780 LCD on the VIA ports wired like in the videos, so the course programs
+// run unmodified:
+//
+//	computer := beneater.New(rom, nil, beneater.WIRING_8BIT)
+//	clock.New(clock.FREQUENCY_1MHZ, computer.Tick).Run(stop)
+//	fmt.Print(computer.Lcd)
+//
+// The ACIA and the VIA take the whole address decoding ranges, so their registers are mirrored.
+package beneater
+
+import (
+	"github.com/costamauricio/6502-emulator/pkg/acia6551"
+	"github.com/costamauricio/6502-emulator/pkg/bus"
+	"github.com/costamauricio/6502-emulator/pkg/cpu6502"
+	"github.com/costamauricio/6502-emulator/pkg/hd44780"
+	"github.com/costamauricio/6502-emulator/pkg/system"
+	"github.com/costamauricio/6502-emulator/pkg/via6522"
+)
+
+// Memory map
+const (
+	RAM_START  = 0x0000
+	RAM_END    = 0x3FFF
+	ACIA_START = 0x5000
+	ACIA_END   = 0x5FFF
+	VIA_START  = 0x6000
+	VIA_END    = 0x7FFF
+	ROM_START  = 0x8000
+	ROM_SIZE   = 0x8000
+)
+
+// LCD wirings of the videos
+const (
+	WIRING_8BIT = iota // Data on port B, E, RW and RS on PA7, PA6 and PA5
+	WIRING_4BIT        // Data on PB0-PB3, RS, RW and E on PB4, PB5 and PB6, port A free for the keyboard
+)
+
+type BenEater struct {
+	Cpu    *cpu6502.CPU
+	Bus    *bus.Bus
+	System *system.System
+	Via    *via6522.VIA
+	Acia   *acia6551.ACIA
+	Lcd    *hd44780.LCD
+	Wiring int
+}
+
+// The ROM image is mapped at $8000, the serial endpoint can be nil without the ACIA connected
+func New(rom []byte, serial acia6551.Endpoint, wiring int) *BenEater {
+	computer := &BenEater{
+		Bus:    &bus.Bus{},
+		Via:    via6522.New(),
+		Acia:   acia6551.New(serial),
+		Lcd:    hd44780.New(16, 2),
+		Wiring: wiring,
+	}
+
+	image := make(bus.ROM, ROM_SIZE)
+	copy(image, rom)
+	computer.Bus.Map(ROM_START, ROM_START+ROM_SIZE-1, image)
+
+	computer.Cpu = cpu6502.New(computer.Bus)
+	computer.System = system.New(computer.Cpu, computer.Bus)
+
+	computer.Via.IRQ = computer.System.IRQSource()
+	computer.Acia.IRQ = computer.System.IRQSource()
+	computer.Bus.Map(VIA_START, VIA_END, computer.System.Add(computer.Via, 1))
+	computer.Bus.Map(ACIA_START, ACIA_END, computer.System.Add(computer.Acia, 1))
+
+	computer.Via.OnPortA = func(byte) { computer.updateLCD() }
+	computer.Via.OnPortB = func(byte) { computer.updateLCD() }
+
+	return computer
+}
+
+// Presses the reset button
+func (b *BenEater) Reset() {
+	b.Via.Reset()
+	b.Acia.Reset()
+	b.Cpu.Reset()
+}
+
+func (b *BenEater) Tick() {
+	b.System.Tick()
+}
+
+// Ticks the cicles
+func (b *BenEater) Run(cycles uint64) {
+	b.System.Run(cycles)
+}
+
+// Drives the LCD pins from the VIA ports and puts its data on port B while it's read
+func (b *BenEater) updateLCD() {
+	pinsA, pinsB := b.Via.PinsA(), b.Via.PinsB()
+
+	if b.Wiring == WIRING_4BIT {
+		data, driven := b.Lcd.Interface(pinsB&0x10 > 0, pinsB&0x20 > 0, pinsB&0x40 > 0, pinsB<<4)
+		if driven {
+			b.Via.SetInputB(data >> 4)
+		}
+
+		return
+	}
+
+	data, driven := b.Lcd.Interface(pinsA&0x20 > 0, pinsA&0x40 > 0, pinsA&0x80 > 0, pinsB)
+	if driven {
+		b.Via.SetInputB(data)
+	}
+}
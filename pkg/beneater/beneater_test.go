@@ -0,0 +1,78 @@
+package beneater
+
+import (
+	"testing"
+)
+
+// Hello world of the LCD video, with the busy flag check
+var hello = []byte{
+	0xA2, 0xFF, // 8000 ldx #$ff
+	0x9A,       // 8002 txs
+	0xA9, 0xFF, // 8003 lda #%11111111
+	0x8D, 0x02, 0x60, // 8005 sta DDRB
+	0xA9, 0xE0, // 8008 lda #%11100000
+	0x8D, 0x03, 0x60, // 800A sta DDRA
+	0xA9, 0x38, // 800D lda #%00111000, 8 bit, 2 lines
+	0x20, 0x62, 0x80, // 800F jsr lcd_instruction
+	0xA9, 0x0E, // 8012 lda #%00001110, display and cursor on
+	0x20, 0x62, 0x80, // 8014 jsr lcd_instruction
+	0xA9, 0x06, // 8017 lda #%00000110, increment
+	0x20, 0x62, 0x80, // 8019 jsr lcd_instruction
+	0xA9, 0x01, // 801C lda #%00000001, clear
+	0x20, 0x62, 0x80, // 801E jsr lcd_instruction
+	0xA2, 0x00, // 8021 ldx #0
+	0xBD, 0x31, 0x80, // 8023 print: lda message,x
+	0xF0, 0x06, // 8026 beq loop
+	0x20, 0x78, 0x80, // 8028 jsr print_char
+	0xE8,       // 802B inx
+	0xD0, 0xF5, // 802C bne print
+	0x4C, 0x2E, 0x80, // 802E loop: jmp loop
+	'H', 'e', 'l', 'l', 'o', ',', ' ', 'w', 'o', 'r', 'l', 'd', '!', 0x00, // 8031 message
+	0x48,       // 803F lcd_wait: pha
+	0xA9, 0x00, // 8040 lda #%00000000
+	0x8D, 0x02, 0x60, // 8042 sta DDRB
+	0xA9, 0x40, // 8045 lcdbusy: lda #RW
+	0x8D, 0x01, 0x60, // 8047 sta PORTA
+	0xA9, 0xC0, // 804A lda #(RW | E)
+	0x8D, 0x01, 0x60, // 804C sta PORTA
+	0xAD, 0x00, 0x60, // 804F lda PORTB
+	0x29, 0x80, // 8052 and #%10000000
+	0xD0, 0xEF, // 8054 bne lcdbusy
+	0xA9, 0x40, // 8056 lda #RW
+	0x8D, 0x01, 0x60, // 8058 sta PORTA
+	0xA9, 0xFF, // 805B lda #%11111111
+	0x8D, 0x02, 0x60, // 805D sta DDRB
+	0x68,             // 8060 pla
+	0x60,             // 8061 rts
+	0x20, 0x3F, 0x80, // 8062 lcd_instruction: jsr lcd_wait
+	0x8D, 0x00, 0x60, // 8065 sta PORTB
+	0xA9, 0x00, // 8068 lda #0
+	0x8D, 0x01, 0x60, // 806A sta PORTA
+	0xA9, 0x80, // 806D lda #E
+	0x8D, 0x01, 0x60, // 806F sta PORTA
+	0xA9, 0x00, // 8072 lda #0
+	0x8D, 0x01, 0x60, // 8074 sta PORTA
+	0x60,             // 8077 rts
+	0x20, 0x3F, 0x80, // 8078 print_char: jsr lcd_wait
+	0x8D, 0x00, 0x60, // 807B sta PORTB
+	0xA9, 0x20, // 807E lda #RS
+	0x8D, 0x01, 0x60, // 8080 sta PORTA
+	0xA9, 0xA0, // 8083 lda #(RS | E)
+	0x8D, 0x01, 0x60, // 8085 sta PORTA
+	0xA9, 0x20, // 8088 lda #RS
+	0x8D, 0x01, 0x60, // 808A sta PORTA
+	0x60, // 808D rts
+}
+
+func TestHelloWorld(t *testing.T) {
+	rom := make([]byte, ROM_SIZE)
+	copy(rom, hello)
+	rom[0x7FFC], rom[0x7FFD] = 0x00, 0x80
+
+	computer := New(rom, nil, WIRING_8BIT)
+	computer.Run(20000)
+
+	if lines := computer.Lcd.Text(); lines[0] != "Hello, world!   " || lines[1] != "                " {
+		t.Errorf("expected hello world on the LCD, got %q", lines)
+	}
+}
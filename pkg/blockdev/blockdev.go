@@ -0,0 +1,298 @@
+// Block device on an image of 512-byte sectors
+//
+// A disk controller for the programs that load and save data: the sector number is set on its
+// registers and a command reads or writes it through the data register a byte at a time, or by
+// DMA straight to the memory. The image is a host file or kept in memory:
+//
+//	image, _ := blockdev.Open("disk.img")
+//	disk := blockdev.New(image)
+//	disk.Memory = dataBus
+//	dataBus.Map(0xF010, 0xF017, disk)
+//
+// The commands complete at once, the status only tells whether they failed.
+package blockdev
+
+import (
+	"errors"
+	"io"
+	"os"
+
+	"github.com/costamauricio/6502-emulator/pkg/cpu6502"
+)
+
+const SECTOR_SIZE = 512
+
+// Registers
+const (
+	REG_COMMAND = 0x0 // Writes run a command
+	REG_STATUS  = 0x0 // Reads the status
+	REG_DATA    = 0x1 // Reads or writes the next byte of the sector buffer
+	REG_SECTOR  = 0x2 // Sector number, 4 bytes from the low one
+	REG_DMA     = 0x6 // Memory address of the DMA commands, 2 bytes from the low one
+	REGISTERS   = 0x8
+)
+
+// Commands
+const (
+	CMD_READ      = 0x01 // Reads the sector into the buffer, then read from the data register
+	CMD_WRITE     = 0x02 // Writes the buffer, filled before on the data register, to the sector
+	CMD_READ_DMA  = 0x03 // Reads the sector into the memory at the DMA address
+	CMD_WRITE_DMA = 0x04 // Writes the memory at the DMA address to the sector
+	CMD_RESET     = 0x05 // Clears the error and restarts the buffer
+)
+
+// Status bits
+const (
+	STATUS_ERROR byte = 0x01 // The last command failed
+	STATUS_DATA  byte = 0x08 // The buffer has bytes left to read or write
+	STATUS_READY byte = 0x40 // An image is inserted
+)
+
+var (
+	ErrSectorRange = errors.New("sector out of the image")
+	ErrNoImage     = errors.New("no image inserted")
+	ErrNoMemory    = errors.New("no memory for the DMA")
+	ErrCommand     = errors.New("unknown command")
+	ErrIncomplete  = errors.New("buffer not filled")
+)
+
+// Image of the sectors
+type Image interface {
+	io.ReaderAt
+	io.WriterAt
+	Sectors() uint32
+}
+
+// Image on a host file, the sectors past the end aren't available
+type File struct {
+	*os.File
+	sectors uint32
+}
+
+// Opens the image file for reading and writing
+func Open(path string) (*File, error) {
+	file, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return &File{File: file, sectors: uint32(info.Size() / SECTOR_SIZE)}, nil
+}
+
+func (f *File) Sectors() uint32 {
+	return f.sectors
+}
+
+// Image kept in memory, for the tests
+type Memory []byte
+
+func NewMemory(sectors uint32) Memory {
+	return make(Memory, int(sectors)*SECTOR_SIZE)
+}
+
+func (m Memory) ReadAt(data []byte, offset int64) (int, error) {
+	if offset >= int64(len(m)) {
+		return 0, io.EOF
+	}
+
+	count := copy(data, m[offset:])
+	if count < len(data) {
+		return count, io.EOF
+	}
+
+	return count, nil
+}
+
+func (m Memory) WriteAt(data []byte, offset int64) (int, error) {
+	if offset+int64(len(data)) > int64(len(m)) {
+		return 0, io.ErrShortWrite
+	}
+
+	return copy(m[offset:], data), nil
+}
+
+func (m Memory) Sectors() uint32 {
+	return uint32(len(m) / SECTOR_SIZE)
+}
+
+// Reads the sector of the image
+func ReadSector(image Image, sector uint32, data []byte) error {
+	if sector >= image.Sectors() {
+		return ErrSectorRange
+	}
+
+	_, err := image.ReadAt(data[:SECTOR_SIZE], int64(sector)*SECTOR_SIZE)
+	return err
+}
+
+// Writes the sector of the image
+func WriteSector(image Image, sector uint32, data []byte) error {
+	if sector >= image.Sectors() {
+		return ErrSectorRange
+	}
+
+	_, err := image.WriteAt(data[:SECTOR_SIZE], int64(sector)*SECTOR_SIZE)
+	return err
+}
+
+type Disk struct {
+	Image  Image       // Nil without a disk inserted
+	Memory cpu6502.Bus // Optional, accessed by the DMA commands
+	Err    error       // Error of the last failed command
+
+	sector  uint32
+	dma     uint16
+	buffer  [SECTOR_SIZE]byte
+	index   int // Next byte of the buffer on the data register
+	reading bool
+}
+
+func New(image Image) *Disk {
+	disk := &Disk{Image: image}
+	disk.Reset()
+
+	return disk
+}
+
+func (d *Disk) Reset() {
+	d.sector, d.dma, d.index, d.reading, d.Err = 0, 0, SECTOR_SIZE, false, nil
+}
+
+func (d *Disk) Read(register uint16) byte {
+	value := d.Peek(register)
+
+	if register%REGISTERS == REG_DATA && d.reading && d.index < SECTOR_SIZE {
+		d.index++
+	}
+
+	return value
+}
+
+func (d *Disk) Peek(register uint16) byte {
+	switch register := register % REGISTERS; {
+	case register == REG_STATUS:
+		var status byte
+
+		if d.Image != nil {
+			status |= STATUS_READY
+		}
+
+		if d.index < SECTOR_SIZE {
+			status |= STATUS_DATA
+		}
+
+		if d.Err != nil {
+			status |= STATUS_ERROR
+		}
+
+		return status
+	case register == REG_DATA:
+		// writing after a read or a full buffer starts filling it again
+		if d.reading && d.index < SECTOR_SIZE {
+			return d.buffer[d.index]
+		}
+	case register >= REG_SECTOR && register < REG_DMA:
+		return byte(d.sector >> (8 * (register - REG_SECTOR)))
+	case register >= REG_DMA:
+		return byte(d.dma >> (8 * (register - REG_DMA)))
+	}
+
+	return 0
+}
+
+func (d *Disk) Write(register uint16, data byte) {
+	switch register := register % REGISTERS; {
+	case register == REG_COMMAND:
+		d.command(data)
+	case register == REG_DATA:
+		// writing after a read or a full buffer starts filling it again
+		if d.reading || d.index >= SECTOR_SIZE {
+			d.index, d.reading = 0, false
+		}
+
+		d.buffer[d.index] = data
+		d.index++
+	case register >= REG_SECTOR && register < REG_DMA:
+		shift := 8 * (register - REG_SECTOR)
+		d.sector = d.sector&^(0xFF<<shift) | uint32(data)<<shift
+	case register >= REG_DMA:
+		shift := 8 * (register - REG_DMA)
+		d.dma = d.dma&^(0xFF<<shift) | uint16(data)<<shift
+	}
+}
+
+func (d *Disk) command(command byte) {
+	d.Err = nil
+
+	switch command {
+	case CMD_READ:
+		d.index, d.reading = 0, true
+		if d.Err = d.read(); d.Err != nil {
+			d.index = SECTOR_SIZE
+		}
+	case CMD_WRITE:
+		if !d.reading && d.index < SECTOR_SIZE {
+			d.Err = ErrIncomplete
+			return
+		}
+
+		d.Err = d.write()
+		// the next bytes written fill the buffer again
+		d.index, d.reading = 0, false
+	case CMD_READ_DMA:
+		d.index = SECTOR_SIZE
+		if d.Err = d.read(); d.Err == nil {
+			d.Err = d.transfer(true)
+		}
+	case CMD_WRITE_DMA:
+		d.index = SECTOR_SIZE
+		if d.Err = d.transfer(false); d.Err == nil {
+			d.Err = d.write()
+		}
+	case CMD_RESET:
+		d.index, d.reading = 0, false
+	default:
+		d.Err = ErrCommand
+	}
+}
+
+func (d *Disk) read() error {
+	if d.Image == nil {
+		return ErrNoImage
+	}
+
+	return ReadSector(d.Image, d.sector, d.buffer[:])
+}
+
+func (d *Disk) write() error {
+	if d.Image == nil {
+		return ErrNoImage
+	}
+
+	return WriteSector(d.Image, d.sector, d.buffer[:])
+}
+
+// Copies the buffer to the memory, or back
+func (d *Disk) transfer(toMemory bool) error {
+	if d.Memory == nil {
+		return ErrNoMemory
+	}
+
+	for index := range d.buffer {
+		address := d.dma + uint16(index)
+
+		if toMemory {
+			d.Memory.Write(address, d.buffer[index])
+		} else {
+			d.buffer[index] = d.Memory.Read(address)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,127 @@
+package blockdev
+
+import (
+	"testing"
+
+	"github.com/costamauricio/6502-emulator/pkg/bus"
+)
+
+func setSector(disk *Disk, sector uint32) {
+	for index := uint16(0); index < 4; index++ {
+		disk.Write(REG_SECTOR+index, byte(sector>>(8*index)))
+	}
+}
+
+func TestDataRegister(t *testing.T) {
+	image := NewMemory(4)
+	disk := New(image)
+
+	if status := disk.Read(REG_STATUS); status != STATUS_READY {
+		t.Errorf("expected an idle disk, got status $%02X", status)
+	}
+
+	// fills the buffer and writes it to sector 2
+	disk.Write(REG_COMMAND, CMD_RESET)
+	for index := 0; index < SECTOR_SIZE; index++ {
+		disk.Write(REG_DATA, byte(index))
+	}
+
+	if status := disk.Read(REG_STATUS); status&STATUS_DATA != 0 {
+		t.Errorf("expected the buffer full, got status $%02X", status)
+	}
+
+	setSector(disk, 2)
+	disk.Write(REG_COMMAND, CMD_WRITE)
+
+	if image[2*SECTOR_SIZE+3] != 3 || image[3*SECTOR_SIZE-1] != 0xFF || image[SECTOR_SIZE] != 0 {
+		t.Fatal("expected the buffer in sector 2")
+	}
+
+	disk.Write(REG_COMMAND, CMD_READ)
+	for index := 0; index < SECTOR_SIZE; index++ {
+		if value := disk.Read(REG_DATA); value != byte(index) {
+			t.Fatalf("expected $%02X at %d, got $%02X", byte(index), index, value)
+		}
+	}
+
+	if status := disk.Read(REG_STATUS); status != STATUS_READY {
+		t.Errorf("expected the buffer read, got status $%02X", status)
+	}
+
+	setSector(disk, 4)
+	disk.Write(REG_COMMAND, CMD_READ)
+	if status := disk.Read(REG_STATUS); status != STATUS_READY|STATUS_ERROR || disk.Err != ErrSectorRange {
+		t.Errorf("expected the sector out of range, got status $%02X %v", status, disk.Err)
+	}
+}
+
+func TestDMA(t *testing.T) {
+	image := NewMemory(2)
+	image[SECTOR_SIZE] = 0xAA
+	image[2*SECTOR_SIZE-1] = 0x55
+
+	dataBus := &bus.Bus{}
+	disk := New(image)
+	dataBus.Map(0xF010, 0xF017, disk)
+
+	dataBus.Write(0xF010+REG_COMMAND, CMD_READ_DMA)
+	if disk.Err != ErrNoMemory {
+		t.Errorf("expected the DMA to fail without memory, got %v", disk.Err)
+	}
+
+	disk.Memory = dataBus
+
+	// sector 1 to $0400
+	dataBus.Write(0xF010+REG_SECTOR, 1)
+	dataBus.Write(0xF010+REG_DMA+1, 0x04)
+	dataBus.Write(0xF010+REG_COMMAND, CMD_READ_DMA)
+
+	if disk.Err != nil || dataBus.Read(0x0400) != 0xAA || dataBus.Read(0x05FF) != 0x55 {
+		t.Fatalf("expected sector 1 at $0400, %v", disk.Err)
+	}
+
+	// back to sector 0
+	dataBus.Write(0x0401, 0x77)
+	dataBus.Write(0xF010+REG_SECTOR, 0)
+	dataBus.Write(0xF010+REG_COMMAND, CMD_WRITE_DMA)
+
+	if disk.Err != nil || image[0] != 0xAA || image[1] != 0x77 || image[SECTOR_SIZE-1] != 0x55 {
+		t.Fatalf("expected $0400 in sector 0, %v", disk.Err)
+	}
+}
+
+func TestWriteModes(t *testing.T) {
+	image := NewMemory(2)
+	disk := New(image)
+
+	// a fresh disk takes the bytes without a reset
+	for index := 0; index < SECTOR_SIZE; index++ {
+		disk.Write(REG_DATA, 0x11)
+	}
+
+	disk.Write(REG_COMMAND, CMD_WRITE)
+	if image[0] != 0x11 || image[SECTOR_SIZE-1] != 0x11 || disk.Err != nil {
+		t.Fatalf("expected the bytes written on a fresh disk, got $%02X %v", image[0], disk.Err)
+	}
+
+	// writing after a read fills the buffer again
+	disk.Write(REG_COMMAND, CMD_READ)
+	disk.Read(REG_DATA)
+
+	for index := 0; index < SECTOR_SIZE; index++ {
+		disk.Write(REG_DATA, 0x22)
+	}
+
+	setSector(disk, 1)
+	disk.Write(REG_COMMAND, CMD_WRITE)
+	if image[SECTOR_SIZE] != 0x22 || image[2*SECTOR_SIZE-1] != 0x22 || disk.Err != nil {
+		t.Fatalf("expected the bytes written after a read, got $%02X %v", image[SECTOR_SIZE], disk.Err)
+	}
+
+	// a partial buffer isn't written
+	disk.Write(REG_DATA, 0x33)
+	disk.Write(REG_COMMAND, CMD_WRITE)
+	if image[SECTOR_SIZE] != 0x22 || disk.Err != ErrIncomplete {
+		t.Errorf("expected the partial buffer refused, got %v", disk.Err)
+	}
+}
@@ -6,7 +6,8 @@ import (
 )
 
 type Bus struct {
-	ram [64 * 1024]byte
+	ram   [64 * 1024]byte
+	pages [256][]*mapping // Devices mapped on each page
 }
 
 func (bus *Bus) String() string {
@@ -14,12 +15,21 @@ func (bus *Bus) String() string {
 }
 
 func (bus *Bus) Write(address uint16, data byte) {
+	if device, register, found := bus.device(address); found {
+		device.Write(register, data)
+		return
+	}
+
 	if address <= 0xFFFF {
 		bus.ram[address] = data
 	}
 }
 
 func (bus *Bus) Read(address uint16) byte {
+	if device, register, found := bus.device(address); found {
+		return device.Read(register)
+	}
+
 	if address <= 0xFFFF {
 		return bus.ram[address]
 	}
@@ -35,9 +45,14 @@ func (bus *Bus) LoadRamFromString(memory string, offset uint16) error {
 		return err
 	}
 
-	for index, content := range decoded {
-		bus.ram[offset+uint16(index)] = content
-	}
+	bus.LoadRam(decoded, offset)
 
 	return nil
 }
+
+// Copies the data to the RAM starting at offset, wrapping around the end of the address space
+func (bus *Bus) LoadRam(data []byte, offset uint16) {
+	for index, content := range data {
+		bus.ram[offset+uint16(index)] = content
+	}
+}
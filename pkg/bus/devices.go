@@ -0,0 +1,67 @@
+package bus
+
+// Device mapped on the bus, the register is the offset from the mapped start
+type Device interface {
+	Read(register uint16) byte
+	Write(register uint16, data byte)
+}
+
+// Devices whose registers can be read without side effects, like clearing interrupt flags
+// The debuggers use it to show the memory without changing the device state
+type Peeker interface {
+	Peek(register uint16) byte
+}
+
+type mapping struct {
+	start  uint16
+	end    uint16
+	device Device
+}
+
+// Maps the device on the range, replacing the RAM there
+// The ranges mapped later take precedence over the previous ones
+func (bus *Bus) Map(start uint16, end uint16, device Device) {
+	mapped := &mapping{start: start, end: end, device: device}
+
+	for page := int(start >> 8); page <= int(end>>8); page++ {
+		bus.pages[page] = append([]*mapping{mapped}, bus.pages[page]...)
+	}
+}
+
+// Device mapped at the address and its register
+func (bus *Bus) device(address uint16) (Device, uint16, bool) {
+	for _, mapped := range bus.pages[address>>8] {
+		if address >= mapped.start && address <= mapped.end {
+			return mapped.device, address - mapped.start, true
+		}
+	}
+
+	return nil, 0, false
+}
+
+// Reads the address without side effects on the mapped devices
+// Devices that can't be peeked are read
+func (bus *Bus) Peek(address uint16) byte {
+	if device, register, found := bus.device(address); found {
+		if peeker, ok := device.(Peeker); ok {
+			return peeker.Peek(register)
+		}
+
+		return device.Read(register)
+	}
+
+	return bus.ram[address]
+}
+
+// Read only memory, the writes are ignored
+type ROM []byte
+
+func (rom ROM) Read(register uint16) byte {
+	if int(register) < len(rom) {
+		return rom[register]
+	}
+
+	return 0x00
+}
+
+func (rom ROM) Write(register uint16, data byte) {}
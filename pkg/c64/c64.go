@@ -0,0 +1,337 @@
+// Runner of Commodore 64 machine language programs without the C64
+//
+// Loads a .prg at its embedded address and calls it with the common KERNAL entry points served
+// in Go: the screen output and the keyboard input on the host streams, and the LOAD of the files
+// from a host directory. The rest of the memory is plain RAM, so it suits the routines that
+// compute and print, not the ones driving the VIC or the SID:
+//
+//	runner := c64.New(os.Stdout, os.Stdin)
+//	address, _ := runner.LoadPRG(program)
+//	reason, err := runner.Run(address)
+//
+// The run ends when the program returns from the entry point or executes BRK. The calls to the
+// KERNAL routines that aren't served stop it with an error.
+package c64
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/costamauricio/6502-emulator/pkg/bus"
+	"github.com/costamauricio/6502-emulator/pkg/cpu6502"
+	"github.com/costamauricio/6502-emulator/pkg/trap"
+)
+
+// KERNAL entry points
+const (
+	SETLFS = 0xFFBA // Sets the logical file, the device and the secondary address from A, X and Y
+	SETNAM = 0xFFBD // Sets the file name, the length in A and the address in X and Y
+	CHRIN  = 0xFFCF // Reads a character of the input line into A
+	CHROUT = 0xFFD2 // Prints the character in A
+	LOAD   = 0xFFD5 // Loads the file, at X and Y with the secondary address 0, returns the end in X and Y
+	GETIN  = 0xFFE4 // Reads a key into A, 0 without one
+)
+
+const (
+	BASIC_START  = 0x0801
+	KERNAL_START = 0xE000
+	EXIT         = 0xFFF6 // Return address of the entry point, trapped to end the run
+	TOKEN_SYS    = 0x9E
+)
+
+// How the run ended
+const (
+	EXIT_RETURN = iota // The program returned from the entry point
+	EXIT_BREAK         // The program executed BRK
+)
+
+// KERNAL I/O errors, returned in A with the carry set
+const (
+	ERROR_FILE_NOT_FOUND = 4
+	ERROR_MISSING_NAME   = 8
+)
+
+var (
+	ErrInvalidPRG  = errors.New("invalid .prg, it needs the load address and some data")
+	ErrCycleLimit  = errors.New("cycle limit reached")
+	ErrUnsupported = errors.New("unsupported KERNAL call")
+)
+
+type Runner struct {
+	Cpu   *cpu6502.CPU
+	Bus   *bus.Bus
+	Traps *trap.Traps
+
+	Output    io.Writer
+	Input     *bufio.Reader
+	Directory string // Host directory of the loaded files, the current one by default
+	Limit     uint64 // Cicles the program can run, 0 is unlimited
+
+	logical, device, secondary byte
+	name                       string
+}
+
+// Nil streams print nothing and read the end of the input
+func New(output io.Writer, input io.Reader) *Runner {
+	if output == nil {
+		output = io.Discard
+	}
+
+	if input == nil {
+		input = strings.NewReader("")
+	}
+
+	runner := &Runner{Bus: &bus.Bus{}, Output: output, Input: bufio.NewReader(input)}
+	runner.Cpu = cpu6502.New(runner.Bus)
+	runner.Traps = trap.New(runner.Cpu, runner.Bus)
+
+	runner.Traps.Set(SETLFS, runner.setlfs)
+	runner.Traps.Set(SETNAM, runner.setnam)
+	runner.Traps.Set(CHRIN, runner.chrin)
+	runner.Traps.Set(CHROUT, runner.chrout)
+	runner.Traps.Set(LOAD, runner.load)
+	runner.Traps.Set(GETIN, runner.getin)
+
+	return runner
+}
+
+// Loads the program at its embedded address, returning where the execution starts:
+// the SYS address of a BASIC stub at $0801, otherwise the load address
+func (r *Runner) LoadPRG(prg []byte) (uint16, error) {
+	if len(prg) < 3 {
+		return 0, ErrInvalidPRG
+	}
+
+	address := uint16(prg[0]) | uint16(prg[1])<<8
+	r.Bus.LoadRam(prg[2:], address)
+
+	if address == BASIC_START {
+		if entry, found := SysAddress(prg[2:]); found {
+			return entry, nil
+		}
+	}
+
+	return address, nil
+}
+
+// Address of the SYS on the first line of the BASIC program
+func SysAddress(basic []byte) (uint16, bool) {
+	if len(basic) < 5 {
+		return 0, false
+	}
+
+	// skips the next line pointer and the line number
+	line := basic[4:]
+
+	for index, token := range line {
+		if token == 0x00 {
+			break
+		}
+
+		if token != TOKEN_SYS {
+			continue
+		}
+
+		address, found := 0, false
+		for _, digit := range line[index+1:] {
+			if digit == ' ' || digit == '(' {
+				continue
+			}
+
+			if digit < '0' || digit > '9' || address > 0xFFFF {
+				break
+			}
+
+			address, found = address*10+int(digit-'0'), true
+		}
+
+		return uint16(address), found && address <= 0xFFFF
+	}
+
+	return 0, false
+}
+
+// Calls the program at the entry until it returns or executes BRK
+func (r *Runner) Run(entry uint16) (int, error) {
+	// the entry returns to the trapped exit address like from SYS
+	state := r.Cpu.State()
+	state.PC, state.S, state.Cicles = entry, 0xFF, 0
+	state.Status = cpu6502.FLAG_U
+	r.Cpu.Restore(state)
+
+	r.push(byte((EXIT - 1) >> 8))
+	r.push(byte((EXIT - 1) & 0x00FF))
+
+	start := r.Cpu.Cycles()
+
+	for {
+		if r.Cpu.InstructionCompleted() && !r.Traps.Check() {
+			switch pc := r.Cpu.PC; {
+			case pc == EXIT:
+				return EXIT_RETURN, nil
+			case pc >= KERNAL_START && r.Bus.Read(pc) == 0x00:
+				return 0, fmt.Errorf("%w $%04X", ErrUnsupported, pc)
+			case r.Bus.Read(pc) == 0x00:
+				return EXIT_BREAK, nil
+			}
+		}
+
+		r.Cpu.Tick()
+
+		if r.Limit > 0 && r.Cpu.Cycles()-start >= r.Limit {
+			return 0, ErrCycleLimit
+		}
+	}
+}
+
+func (r *Runner) push(value byte) {
+	r.Bus.Write(0x0100|uint16(r.Cpu.S), value)
+	r.Cpu.S--
+}
+
+func (r *Runner) setlfs(cpu *cpu6502.CPU) bool {
+	r.logical, r.device, r.secondary = cpu.A, cpu.X, cpu.Y
+	return true
+}
+
+func (r *Runner) setnam(cpu *cpu6502.CPU) bool {
+	address := uint16(cpu.X) | uint16(cpu.Y)<<8
+
+	name := make([]byte, cpu.A)
+	for index := range name {
+		name[index] = r.Bus.Read(address + uint16(index))
+	}
+
+	r.name = ToASCII(name)
+	return true
+}
+
+func (r *Runner) chrout(cpu *cpu6502.CPU) bool {
+	if text := ToASCII([]byte{cpu.A}); text != "" {
+		io.WriteString(r.Output, text)
+	}
+
+	cpu.SetFlag(cpu6502.FLAG_C, false)
+	return true
+}
+
+// Reads the next character of the line, the end of the input is an empty line
+func (r *Runner) chrin(cpu *cpu6502.CPU) bool {
+	value, err := r.Input.ReadByte()
+	if err != nil {
+		value = '\n'
+	}
+
+	cpu.A = FromASCII(value)
+	cpu.SetFlag(cpu6502.FLAG_C, false)
+
+	return true
+}
+
+// Reads the next key, 0 at the end of the input
+func (r *Runner) getin(cpu *cpu6502.CPU) bool {
+	value, err := r.Input.ReadByte()
+
+	cpu.A = 0
+	if err == nil {
+		cpu.A = FromASCII(value)
+	}
+
+	cpu.SetFlag(cpu6502.FLAG_Z, cpu.A == 0)
+	cpu.SetFlag(cpu6502.FLAG_C, false)
+
+	return true
+}
+
+// Loads the named host file, at its own address with a secondary address other than 0
+func (r *Runner) load(cpu *cpu6502.CPU) bool {
+	fail := func(code byte) bool {
+		cpu.A = code
+		cpu.SetFlag(cpu6502.FLAG_C, true)
+		return true
+	}
+
+	if r.name == "" {
+		return fail(ERROR_MISSING_NAME)
+	}
+
+	data, err := r.open(r.name)
+	if err != nil || len(data) < 2 {
+		return fail(ERROR_FILE_NOT_FOUND)
+	}
+
+	address := uint16(data[0]) | uint16(data[1])<<8
+	if r.secondary == 0 {
+		address = uint16(cpu.X) | uint16(cpu.Y)<<8
+	}
+
+	// verifies instead with A other than 0, it only checks the file is there
+	if cpu.A == 0 {
+		r.Bus.LoadRam(data[2:], address)
+	}
+
+	end := address + uint16(len(data)-2)
+	cpu.X, cpu.Y = byte(end&0x00FF), byte(end>>8)
+	cpu.SetFlag(cpu6502.FLAG_C, false)
+
+	return true
+}
+
+// Reads the file by its name as is, in lower case and with the .prg extension
+func (r *Runner) open(name string) ([]byte, error) {
+	directory := r.Directory
+	if directory == "" {
+		directory = "."
+	}
+
+	var err error
+	for _, candidate := range []string{name, strings.ToLower(name), name + ".prg", strings.ToLower(name) + ".prg"} {
+		var data []byte
+		if data, err = os.ReadFile(filepath.Join(directory, filepath.Base(candidate))); err == nil {
+			return data, nil
+		}
+	}
+
+	return nil, err
+}
+
+// Text of the PETSCII characters, the control codes out of the carriage return are dropped
+func ToASCII(petscii []byte) string {
+	var text strings.Builder
+
+	for _, value := range petscii {
+		switch {
+		case value == 0x0D:
+			text.WriteByte('\n')
+		case value == 0x5C:
+			text.WriteString("£")
+		case value == 0x5E:
+			text.WriteString("↑")
+		case value == 0x5F:
+			text.WriteString("←")
+		case value >= 0x20 && value < 0x7B:
+			text.WriteByte(value)
+		case value >= 0xC1 && value <= 0xDA:
+			text.WriteByte(value - 0xC1 + 'A')
+		}
+	}
+
+	return text.String()
+}
+
+// PETSCII of the typed ASCII character, the letters are the unshifted ones
+func FromASCII(value byte) byte {
+	switch {
+	case value == '\n':
+		return 0x0D
+	case value >= 'a' && value <= 'z':
+		return value - 'a' + 'A'
+	}
+
+	return value
+}
@@ -0,0 +1,89 @@
+package c64
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHello(t *testing.T) {
+	prg := []byte{
+		0x00, 0xC0, // load address
+		0xA2, 0x00, // C000 LDX #0
+		0xBD, 0x0E, 0xC0, // C002 LDA message,X
+		0xF0, 0x06, // C005 BEQ done
+		0x20, 0xD2, 0xFF, // C007 JSR CHROUT
+		0xE8,       // C00A INX
+		0xD0, 0xF5, // C00B BNE C002
+		0x60,                                // C00D done: RTS
+		'H', 'E', 'L', 'L', 'O', 0x0D, 0x00, // C00E message
+	}
+
+	var output bytes.Buffer
+	runner := New(&output, nil)
+
+	entry, err := runner.LoadPRG(prg)
+	if err != nil || entry != 0xC000 {
+		t.Fatalf("expected the entry at $C000, got $%04X %v", entry, err)
+	}
+
+	if reason, err := runner.Run(entry); reason != EXIT_RETURN || err != nil {
+		t.Errorf("expected the program to return, got %d %v", reason, err)
+	}
+
+	if output.String() != "HELLO\n" {
+		t.Errorf("expected HELLO, got %q", output.String())
+	}
+}
+
+func TestLoad(t *testing.T) {
+	directory := t.TempDir()
+	if err := os.WriteFile(filepath.Join(directory, "data.prg"), []byte{0x00, 0x30, 1, 2, 3}, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	prg := []byte{
+		0x00, 0xC0,
+		0xA9, 0x01, // C000 LDA #1
+		0xA2, 0x08, // C002 LDX #8
+		0xA0, 0x01, // C004 LDY #1
+		0x20, 0xBA, 0xFF, // C006 JSR SETLFS
+		0xA9, 0x04, // C009 LDA #4
+		0xA2, 0x18, // C00B LDX #<name
+		0xA0, 0xC0, // C00D LDY #>name
+		0x20, 0xBD, 0xFF, // C00F JSR SETNAM
+		0xA9, 0x00, // C012 LDA #0
+		0x20, 0xD5, 0xFF, // C014 JSR LOAD
+		0x00,               // C017 BRK
+		'D', 'A', 'T', 'A', // C018 name
+	}
+
+	runner := New(nil, nil)
+	runner.Directory = directory
+	entry, _ := runner.LoadPRG(prg)
+
+	if reason, err := runner.Run(entry); reason != EXIT_BREAK || err != nil {
+		t.Errorf("expected the program to stop on BRK, got %d %v", reason, err)
+	}
+
+	if runner.Bus.Read(0x3002) != 3 || runner.Cpu.X != 0x03 || runner.Cpu.Y != 0x30 {
+		t.Errorf("expected the file loaded at $3000 up to $3003, got $%02X%02X", runner.Cpu.Y, runner.Cpu.X)
+	}
+}
+
+func TestUnsupported(t *testing.T) {
+	// BASIC stub 10 SYS 2061, then JSR CLRCHN
+	prg := []byte{0x01, 0x08, 0x0B, 0x08, 0x0A, 0x00, 0x9E, '2', '0', '6', '1', 0x00, 0x00, 0x00, 0x20, 0xCC, 0xFF, 0x60}
+
+	runner := New(nil, nil)
+	entry, _ := runner.LoadPRG(prg)
+	if entry != 2061 {
+		t.Fatalf("expected the SYS address 2061, got %d", entry)
+	}
+
+	if _, err := runner.Run(entry); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("expected the unsupported CLRCHN call, got %v", err)
+	}
+}
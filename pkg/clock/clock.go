@@ -0,0 +1,137 @@
+// Emulated clock pacing the ticks to real time
+//
+// The ticks run in short bursts and the clock sleeps between them until the wall clock catches up
+// with the emulated time, so the average speed matches the configured frequency. With frequency 0
+// it runs unthrottled, as fast as the host can tick.
+package clock
+
+import (
+	"time"
+)
+
+// Common 6502 clock frequencies in Hz
+const (
+	FREQUENCY_1MHZ      = 1000000 // Apple I, KIM-1, Commodore PET
+	FREQUENCY_C64_PAL   = 985248
+	FREQUENCY_C64_NTSC  = 1022727
+	FREQUENCY_NES_NTSC  = 1789773
+	FREQUENCY_ATARI     = 1789790 // Atari 8-bit NTSC
+	FREQUENCY_2MHZ      = 2000000
+	FREQUENCY_UNLIMITED = 0
+)
+
+// How long a burst of ticks runs before syncing with the wall clock
+const DEFAULT_SLICE = 10 * time.Millisecond
+
+// Cicles of the bursts running unthrottled
+const UNLIMITED_BURST = 10000
+
+// Maximum delay recovered running faster, beyond it the clock gives up catching up
+const MAX_LAG = 100 * time.Millisecond
+
+type Clock struct {
+	Frequency int           // Cicles per second, FREQUENCY_UNLIMITED doesn't pace the ticks
+	Slice     time.Duration // Defaults to DEFAULT_SLICE
+	Tick      func()
+
+	cycles uint64    // Cicles ticked since created
+	synced uint64    // Cicles ticked when the wall clock was synced
+	start  time.Time // Wall clock when synced
+
+	now   func() time.Time
+	sleep func(time.Duration)
+}
+
+func New(frequency int, tick func()) *Clock {
+	return &Clock{Frequency: frequency, Tick: tick, now: time.Now, sleep: time.Sleep}
+}
+
+// Cicles ticked by the clock
+func (c *Clock) Cycles() uint64 {
+	return c.cycles
+}
+
+// Emulated time of the ticked cicles
+func (c *Clock) Elapsed() time.Duration {
+	if c.Frequency <= 0 {
+		return 0
+	}
+
+	return c.duration(c.cycles)
+}
+
+func (c *Clock) duration(cycles uint64) time.Duration {
+	return time.Duration(float64(cycles) / float64(c.Frequency) * float64(time.Second))
+}
+
+// Syncs with the wall clock, used after pausing so the clock doesn't rush to catch up
+func (c *Clock) Sync() {
+	c.synced = c.cycles
+	c.start = c.now()
+}
+
+// Ticks the cicles, sleeping as needed to keep the frequency
+func (c *Clock) Advance(cycles uint64) {
+	if c.start.IsZero() {
+		c.Sync()
+	}
+
+	for end := c.cycles + cycles; c.cycles < end; {
+		burst := end - c.cycles
+		if c.Frequency > 0 {
+			if slice := c.sliceCycles(); burst > slice {
+				burst = slice
+			}
+		}
+
+		for index := uint64(0); index < burst; index++ {
+			c.Tick()
+		}
+
+		c.cycles += burst
+		c.pace()
+	}
+}
+
+// Ticks until stop returns true, it's checked between the bursts
+func (c *Clock) Run(stop func() bool) {
+	for !stop() {
+		c.Advance(c.sliceCycles())
+	}
+}
+
+// Cicles of a burst, the unthrottled bursts are fixed
+func (c *Clock) sliceCycles() uint64 {
+	if c.Frequency <= 0 {
+		return UNLIMITED_BURST
+	}
+
+	slice := c.Slice
+	if slice <= 0 {
+		slice = DEFAULT_SLICE
+	}
+
+	cycles := uint64(slice.Seconds() * float64(c.Frequency))
+	if cycles == 0 {
+		return 1
+	}
+
+	return cycles
+}
+
+// Sleeps until the wall clock reaches the emulated time
+func (c *Clock) pace() {
+	if c.Frequency <= 0 {
+		return
+	}
+
+	ahead := c.duration(c.cycles-c.synced) - c.now().Sub(c.start)
+
+	switch {
+	case ahead > 0:
+		c.sleep(ahead)
+	case ahead < -MAX_LAG:
+		// the host is too slow or was paused, runs from now instead of rushing
+		c.Sync()
+	}
+}
@@ -0,0 +1,56 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+// Clock driven by a fake wall clock that only moves when sleeping
+func newClock(frequency int, now *time.Time, slept *time.Duration) (*Clock, *int) {
+	ticks := 0
+	clock := New(frequency, func() { ticks++ })
+	clock.now = func() time.Time { return *now }
+	clock.sleep = func(duration time.Duration) {
+		*slept += duration
+		*now = now.Add(duration)
+	}
+
+	return clock, &ticks
+}
+
+func TestPacing(t *testing.T) {
+	now := time.Unix(0, 0)
+	var slept time.Duration
+
+	clock, ticks := newClock(FREQUENCY_1MHZ, &now, &slept)
+	clock.Advance(FREQUENCY_1MHZ / 2)
+
+	if *ticks != FREQUENCY_1MHZ/2 || clock.Cycles() != FREQUENCY_1MHZ/2 {
+		t.Errorf("expected %d ticks, got %d", FREQUENCY_1MHZ/2, *ticks)
+	}
+
+	if slept != 500*time.Millisecond || clock.Elapsed() != 500*time.Millisecond {
+		t.Errorf("expected to run for 500ms, slept %s", slept)
+	}
+
+	// a long pause isn't recovered running faster
+	now = now.Add(time.Second)
+	slept = 0
+	clock.Advance(FREQUENCY_1MHZ / 10)
+
+	if slept < 90*time.Millisecond {
+		t.Errorf("expected to resync after the pause, slept %s", slept)
+	}
+}
+
+func TestUnlimited(t *testing.T) {
+	now := time.Unix(0, 0)
+	var slept time.Duration
+
+	clock, ticks := newClock(FREQUENCY_UNLIMITED, &now, &slept)
+	clock.Run(func() bool { return *ticks >= 3*UNLIMITED_BURST })
+
+	if *ticks != 3*UNLIMITED_BURST || slept != 0 {
+		t.Errorf("expected 3 unthrottled bursts, got %d ticks sleeping %s", *ticks, slept)
+	}
+}
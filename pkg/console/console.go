@@ -0,0 +1,126 @@
+// Memory-mapped character console
+//
+// The trivial getchar and putchar of the 6502 simulators, like the py65 one, so the programs can
+// print and read without a UART. It takes two addresses anywhere on the bus:
+//
+//	dataBus.Map(0xF000, 0xF001, console.New(os.Stdout, os.Stdin))
+//
+// Writing the data register prints the byte, reading it takes the next input byte. The status
+// register tells whether a byte is waiting and when the input ended.
+//
+// A plain reader is read when the program looks at the input, waiting for it like getchar. A
+// polled input, like the acia6551 endpoints, never stops the program: the status tells when a
+// byte arrived and the data reads 0 until then.
+package console
+
+import (
+	"bufio"
+	"io"
+)
+
+// Registers
+const (
+	REG_DATA   = 0x0 // Writes print the byte, reads take the next input byte, 0 without one
+	REG_STATUS = 0x1
+)
+
+// Status bits
+const (
+	STATUS_RECEIVED byte = 0x01 // A byte is waiting on the data register
+	STATUS_READY    byte = 0x02 // The output takes bytes, always set
+	STATUS_EOF      byte = 0x80 // The input ended
+)
+
+// Input polled without blocking, like the acia6551 endpoints
+type Receiver interface {
+	Receive() (byte, bool)
+}
+
+type Console struct {
+	Output io.Writer // Optional, nil drops the bytes
+
+	input    *bufio.Reader
+	receiver Receiver
+
+	pending  byte
+	received bool
+	eof      bool
+}
+
+// Nil streams print nothing and end the input at once
+func New(output io.Writer, input io.Reader) *Console {
+	if input == nil {
+		return &Console{Output: output, eof: true}
+	}
+
+	return &Console{Output: output, input: bufio.NewReader(input)}
+}
+
+// Console polling the input, e.g. on an acia6551 endpoint
+func Polled(output io.Writer, input Receiver) *Console {
+	return &Console{Output: output, receiver: input}
+}
+
+// Takes the next input byte into the data register, waiting for a plain reader
+func (c *Console) fetch() {
+	if c.received || c.eof {
+		return
+	}
+
+	if c.receiver != nil {
+		c.pending, c.received = c.receiver.Receive()
+		return
+	}
+
+	value, err := c.input.ReadByte()
+	if err != nil {
+		c.eof = true
+		return
+	}
+
+	c.pending, c.received = value, true
+}
+
+func (c *Console) Read(register uint16) byte {
+	switch register {
+	case REG_DATA:
+		c.fetch()
+
+		value := c.pending
+		c.pending, c.received = 0, false
+
+		return value
+	case REG_STATUS:
+		c.fetch()
+	}
+
+	return c.Peek(register)
+}
+
+// Reads the registers without taking the input
+func (c *Console) Peek(register uint16) byte {
+	switch register {
+	case REG_DATA:
+		return c.pending
+	case REG_STATUS:
+		status := STATUS_READY
+
+		if c.received {
+			status |= STATUS_RECEIVED
+		}
+
+		if c.eof && !c.received {
+			status |= STATUS_EOF
+		}
+
+		return status
+	}
+
+	return 0
+}
+
+func (c *Console) Write(register uint16, data byte) {
+	if register == REG_DATA && c.Output != nil {
+		c.Output.Write([]byte{data})
+	}
+}
@@ -0,0 +1,85 @@
+package console
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/costamauricio/6502-emulator/pkg/acia6551"
+	"github.com/costamauricio/6502-emulator/pkg/bus"
+	"github.com/costamauricio/6502-emulator/pkg/cpu6502"
+)
+
+// Echoes the input until it ends
+var echo = []byte{
+	0xAD, 0x01, 0xF0, // $0200 LDA $F001
+	0x30, 0x09, //       $0203 BMI $020E
+	0xAD, 0x00, 0xF0, // $0205 LDA $F000
+	0x8D, 0x00, 0xF0, // $0208 STA $F000
+	0x4C, 0x00, 0x02, // $020B JMP $0200
+	0x00, //             $020E BRK
+}
+
+func TestEcho(t *testing.T) {
+	var output bytes.Buffer
+
+	dataBus := &bus.Bus{}
+	dataBus.Map(0xF000, 0xF001, New(&output, strings.NewReader("HELLO\n")))
+	dataBus.LoadRam(echo, 0x0200)
+	dataBus.LoadRam([]byte{0x00, 0x02}, 0xFFFC)
+
+	cpu := cpu6502.New(dataBus)
+	cpu.Reset()
+
+	for ticks := 0; ticks < 10000; ticks++ {
+		if cpu.InstructionCompleted() && cpu.PC == 0x020E {
+			break
+		}
+
+		cpu.Tick()
+	}
+
+	if cpu.PC != 0x020E {
+		t.Fatalf("expected the program at the end of the input, it's at $%04X", cpu.PC)
+	}
+
+	if output.String() != "HELLO\n" {
+		t.Errorf("expected the input echoed, got %q", output.String())
+	}
+}
+
+func TestPolled(t *testing.T) {
+	pipe := acia6551.NewPipe()
+	console := Polled(pipe, pipe)
+
+	if status := console.Read(REG_STATUS); status != STATUS_READY {
+		t.Errorf("expected no input waiting, got status $%02X", status)
+	}
+
+	if value := console.Read(REG_DATA); value != 0 {
+		t.Errorf("expected 0 without input, got $%02X", value)
+	}
+
+	pipe.Send([]byte("A"))
+
+	if status := console.Read(REG_STATUS); status != STATUS_READY|STATUS_RECEIVED {
+		t.Errorf("expected the input waiting, got status $%02X", status)
+	}
+
+	if value := console.Peek(REG_DATA); value != 'A' {
+		t.Errorf("expected to peek the input, got $%02X", value)
+	}
+
+	if value := console.Read(REG_DATA); value != 'A' {
+		t.Errorf("expected the input, got $%02X", value)
+	}
+
+	console.Write(REG_DATA, 'B')
+	if output := string(pipe.Output()); output != "B" {
+		t.Errorf("expected the byte printed, got %q", output)
+	}
+
+	if status := New(nil, nil).Read(REG_STATUS); status != STATUS_READY|STATUS_EOF {
+		t.Errorf("expected the end of a missing input, got status $%02X", status)
+	}
+}
@@ -29,8 +29,10 @@ type CPU struct {
 	PC     uint16 // Program Counter
 	Status byte
 
-	bus    Bus
-	cicles int // Current instruction cicles
+	bus     Bus
+	cicles  int    // Current instruction cicles
+	address uint16 // Address of the current instruction opcode
+	total   uint64 // Cicles ticked since the CPU was created
 
 	addressingModes AddressingModes
 	instructions    Instructions
@@ -86,6 +88,7 @@ func (cpu *CPU) pullFromStack() byte {
 // Perform a CPU clock cicle
 func (cpu *CPU) Tick() {
 	defer func() {
+		cpu.total++
 		cpu.cicles--
 
 		// since when we get an invalid opcode we still decrease the cicles
@@ -99,6 +102,7 @@ func (cpu *CPU) Tick() {
 		return
 	}
 
+	cpu.address = cpu.PC
 	opcode := cpu.read(cpu.PC)
 	cpu.PC++
 
@@ -113,11 +117,21 @@ func (cpu *CPU) Tick() {
 	cpu.instructions[operation.instruction](operation.addressMode)
 }
 
+// Total of cicles ticked, it keeps counting through resets
+func (cpu *CPU) Cycles() uint64 {
+	return cpu.total
+}
+
 // Verify if the current instruction has already completed
 func (cpu *CPU) InstructionCompleted() bool {
 	return cpu.cicles == 0
 }
 
+// Address of the instruction being executed, or the last executed when it has completed
+func (cpu *CPU) InstructionAddress() uint16 {
+	return cpu.address
+}
+
 // Calls for RES (Reset or start the CPU)
 func (cpu *CPU) Reset() {
 	var progAddress uint16 = 0xFFFC
@@ -142,8 +156,6 @@ func (cpu *CPU) InterruptRequest() {
 		return
 	}
 
-	cpu.SetFlag(FLAG_I, true)
-
 	cpu.interrupt(0xFFFE)
 	cpu.cicles = 7
 }
@@ -163,7 +175,9 @@ func (cpu *CPU) interrupt(progAddress uint16) {
 	cpu.pushOnStack(pch)
 	cpu.pushOnStack(pcl)
 
-	cpu.pushOnStack(cpu.Status)
+	// the status is pushed before disabling the interrupts, so RTI enables them back
+	cpu.pushOnStack((cpu.Status | FLAG_U) &^ FLAG_B)
+	cpu.SetFlag(FLAG_I, true)
 
 	cpu.PC = (high << 8) | low
 }
@@ -0,0 +1,59 @@
+package cpu6502
+
+import "testing"
+
+func stepInstruction(cpu *CPU) {
+	for cpu.Tick(); !cpu.InstructionCompleted(); cpu.Tick() {
+	}
+}
+
+// Takes the interrupt after the flag instruction at $8000 and SEC, returning the pushed status and
+// the I flag in the handler and after RTI
+func interruptStatus(flag byte, interrupt func(cpu *CPU)) (byte, bool, bool) {
+	bus := &testBus{}
+	// flag; SEC
+	copy(bus[0x8000:], []byte{flag, 0x38, 0xEA})
+	// handler: PLA; PHA; RTI
+	copy(bus[0x9000:], []byte{0x68, 0x48, 0x40})
+	copy(bus[0xFFFA:], []byte{0x00, 0x90, 0x00, 0x80, 0x00, 0x90})
+
+	cpu := New(bus)
+	stepInstruction(cpu)
+	stepInstruction(cpu)
+	stepInstruction(cpu)
+
+	interrupt(cpu)
+	stepInstruction(cpu)
+	handler := cpu.GetFlag(FLAG_I) > 0
+
+	stepInstruction(cpu)
+	pushed := cpu.A
+
+	stepInstruction(cpu)
+	stepInstruction(cpu)
+
+	if cpu.PC != 0x8002 {
+		return pushed, handler, false
+	}
+
+	return pushed, handler, cpu.GetFlag(FLAG_I) > 0
+}
+
+func TestInterruptStatus(t *testing.T) {
+	// CLI
+	pushed, handler, after := interruptStatus(0x58, (*CPU).InterruptRequest)
+	if pushed != FLAG_U|FLAG_C || !handler || after {
+		t.Errorf("IRQ: expected $%02X pushed, I set in the handler and clear after RTI, got $%02X, %t, %t", FLAG_U|FLAG_C, pushed, handler, after)
+	}
+
+	pushed, handler, after = interruptStatus(0x58, (*CPU).NonMaskableInterrupt)
+	if pushed != FLAG_U|FLAG_C || !handler || after {
+		t.Errorf("NMI: expected $%02X pushed, I set in the handler and clear after RTI, got $%02X, %t, %t", FLAG_U|FLAG_C, pushed, handler, after)
+	}
+
+	// SEI, the NMI is taken anyway and RTI keeps the interrupts disabled
+	pushed, handler, after = interruptStatus(0x78, (*CPU).NonMaskableInterrupt)
+	if pushed != FLAG_U|FLAG_I|FLAG_C || !handler || !after {
+		t.Errorf("NMI: expected $%02X pushed and I set after RTI, got $%02X, %t, %t", FLAG_U|FLAG_I|FLAG_C, pushed, handler, after)
+	}
+}
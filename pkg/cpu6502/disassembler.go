@@ -4,6 +4,8 @@ import (
 	"fmt"
 )
 
+// Linear sweep disassembly of the range, the data bytes are decoded as instructions too
+// The disasm package separates the code from the data following the execution flow
 func (cpu *CPU) DisassembleInstructions(startAt uint16, endAt uint16) (map[uint16]string, []uint16) {
 	var order []uint16
 	instructions := make(map[uint16]string)
@@ -48,3 +50,110 @@ func (cpu *CPU) DisassembleInstructions(startAt uint16, endAt uint16) (map[uint1
 
 	return instructions, order
 }
+
+// Decoded instruction
+type Operation struct {
+	Address     uint16 // Address of the opcode
+	Opcode      byte
+	Instruction Instruction
+	AddressMode AddressingMode
+	Operand     uint16 // Operand value, for relative branches the target address
+	Size        uint16 // Instruction size in bytes
+	Cicles      int
+}
+
+// Decodes the instruction at the address reading the bytes with the read function
+// It doesn't touch the CPU state, so it is safe to use while the program is running
+func Decode(read func(uint16) byte, address uint16) (Operation, bool) {
+	code := read(address)
+	operation := Operation{Address: address, Opcode: code, Size: 1}
+
+	found, ok := OPCODES[code]
+	if !ok {
+		return operation, false
+	}
+
+	operation.Instruction = found.instruction
+	operation.AddressMode = found.addressMode
+	operation.Cicles = found.cicles
+
+	switch found.addressMode {
+	case MODE_IMM, MODE_ZP0, MODE_ZPX, MODE_ZPY, MODE_INX, MODE_INY:
+		operation.Operand = uint16(read(address + 1))
+		operation.Size = 2
+	case MODE_REL:
+		offset := uint16(read(address + 1))
+		if offset&0x80 > 0 {
+			offset |= 0xFF00
+		}
+		operation.Operand = address + 2 + offset
+		operation.Size = 2
+	case MODE_ABS, MODE_ABX, MODE_ABY, MODE_IND:
+		low := uint16(read(address + 1))
+		high := uint16(read(address + 2))
+		operation.Operand = (high << 8) | low
+		operation.Size = 3
+	}
+
+	return operation, true
+}
+
+// Decodes the instruction at the address using the CPU bus
+func (cpu *CPU) Decode(address uint16) (Operation, bool) {
+	return Decode(cpu.read, address)
+}
+
+// Formats the operation using the usual assembler syntax, e.g. "LDA ($10),Y"
+func (operation Operation) String() string {
+	return operation.Format(nil)
+}
+
+// Formats the operation naming the operand addresses with label, see FormatOperand
+func (operation Operation) Format(label func(uint16) (string, bool)) string {
+	if operation.Instruction == "" {
+		return fmt.Sprintf(".byte $%02X", operation.Opcode)
+	}
+
+	return string(operation.Instruction) + operation.FormatOperand(label)
+}
+
+// Formats the operand of the operation, prefixed by a space when there is one
+// label is used to name addresses, when it is nil or doesn't know the address it is rendered in hexadecimal
+func (operation Operation) FormatOperand(label func(uint16) (string, bool)) string {
+	address := func(width int) string {
+		if label != nil {
+			if name, found := label(operation.Operand); found {
+				return name
+			}
+		}
+
+		return fmt.Sprintf("$%0*X", width, operation.Operand)
+	}
+
+	switch operation.AddressMode {
+	case MODE_ACC:
+		return " A"
+	case MODE_IMM:
+		return fmt.Sprintf(" #$%02X", operation.Operand)
+	case MODE_ZP0:
+		return " " + address(2)
+	case MODE_ZPX:
+		return " " + address(2) + ",X"
+	case MODE_ZPY:
+		return " " + address(2) + ",Y"
+	case MODE_INX:
+		return " (" + address(2) + ",X)"
+	case MODE_INY:
+		return " (" + address(2) + "),Y"
+	case MODE_ABS, MODE_REL:
+		return " " + address(4)
+	case MODE_ABX:
+		return " " + address(4) + ",X"
+	case MODE_ABY:
+		return " " + address(4) + ",Y"
+	case MODE_IND:
+		return " (" + address(4) + ")"
+	}
+
+	return ""
+}
@@ -207,8 +207,8 @@ func (cpu *CPU) adc(mode AddressingMode) {
 func (cpu *CPU) sbc(mode AddressingMode) {
 	data, _ := cpu.loadData(mode)
 
-	// uses two complement to get the inverse signal of the memory value
-	data = (^data) + 1
+	// adds the one's complement, the carry completes the two's complement of the memory value
+	data = ^data
 
 	result := uint16(cpu.A) + uint16(data) + uint16(cpu.GetFlag(FLAG_C))
 
@@ -285,8 +285,8 @@ func (cpu *CPU) bit(mode AddressingMode) {
 	result := cpu.A & data
 
 	cpu.SetFlag(FLAG_Z, result == 0x00)
-	cpu.SetFlag(FLAG_N, data&0x40 > 0) // Memory bit 7
-	cpu.SetFlag(FLAG_V, data&0x20 > 0) // Memory bit 6
+	cpu.SetFlag(FLAG_N, data&0x80 > 0) // Memory bit 7
+	cpu.SetFlag(FLAG_V, data&0x40 > 0) // Memory bit 6
 }
 
 // Branch on result minus (when negative flag set)
@@ -587,10 +587,10 @@ func (cpu *CPU) plp(mode AddressingMode) {
 func (cpu *CPU) rol(mode AddressingMode) {
 	data, address := cpu.loadData(mode)
 
-	cpu.SetFlag(FLAG_C, data&0x80 > 0)
-
 	result := (data << 1) | cpu.GetFlag(FLAG_C)
 
+	cpu.SetFlag(FLAG_C, data&0x80 > 0)
+
 	cpu.SetFlag(FLAG_Z, result == 0x00)
 	cpu.SetFlag(FLAG_N, result&0x80 > 0)
 
@@ -601,10 +601,10 @@ func (cpu *CPU) rol(mode AddressingMode) {
 func (cpu *CPU) ror(mode AddressingMode) {
 	data, address := cpu.loadData(mode)
 
-	cpu.SetFlag(FLAG_C, data&0x01 > 0)
-
 	result := (data >> 1) | (cpu.GetFlag(FLAG_C) << 7)
 
+	cpu.SetFlag(FLAG_C, data&0x01 > 0)
+
 	cpu.SetFlag(FLAG_Z, result == 0x00)
 	cpu.SetFlag(FLAG_N, result&0x80 > 0)
 
@@ -5,3 +5,77 @@ import "testing"
 func TestADC(t *testing.T) {
 
 }
+
+// Memory bus for the instruction tests
+type testBus [64 * 1024]byte
+
+func (bus *testBus) Read(address uint16) byte {
+	return bus[address]
+}
+
+func (bus *testBus) Write(address uint16, data byte) {
+	bus[address] = data
+}
+
+// Runs the program loaded at $8000 for the count of instructions
+func run(program []byte, count int) *CPU {
+	bus := &testBus{}
+	copy(bus[0x8000:], program)
+	bus[0xFFFD] = 0x80
+
+	cpu := New(bus)
+	for index := 0; index <= count; index++ {
+		for cpu.Tick(); !cpu.InstructionCompleted(); cpu.Tick() {
+		}
+	}
+
+	return cpu
+}
+
+func TestSBC(t *testing.T) {
+	// SEC, LDA #$50, SBC #$10
+	cpu := run([]byte{0x38, 0xA9, 0x50, 0xE9, 0x10}, 3)
+	if cpu.A != 0x40 || cpu.GetFlag(FLAG_C) == 0 {
+		t.Errorf("expected $40 without borrow, got $%02X", cpu.A)
+	}
+
+	// CLC, LDA #$50, SBC #$00 borrows the carry
+	cpu = run([]byte{0x18, 0xA9, 0x50, 0xE9, 0x00}, 3)
+	if cpu.A != 0x4F || cpu.GetFlag(FLAG_C) == 0 {
+		t.Errorf("expected $4F without borrow, got $%02X", cpu.A)
+	}
+
+	// SEC, LDA #$10, SBC #$20
+	cpu = run([]byte{0x38, 0xA9, 0x10, 0xE9, 0x20}, 3)
+	if cpu.A != 0xF0 || cpu.GetFlag(FLAG_C) != 0 || cpu.GetFlag(FLAG_N) == 0 {
+		t.Errorf("expected $F0 with borrow, got $%02X", cpu.A)
+	}
+
+	// SEC, LDA #$80, SBC #$01 overflows
+	cpu = run([]byte{0x38, 0xA9, 0x80, 0xE9, 0x01}, 3)
+	if cpu.A != 0x7F || cpu.GetFlag(FLAG_V) == 0 {
+		t.Errorf("expected $7F with overflow, got $%02X", cpu.A)
+	}
+}
+
+func TestRotate(t *testing.T) {
+	// SEC, LDA #$80, ROL A
+	cpu := run([]byte{0x38, 0xA9, 0x80, 0x2A}, 3)
+	if cpu.A != 0x01 || cpu.GetFlag(FLAG_C) == 0 {
+		t.Errorf("expected ROL to rotate the carry in and bit 7 out, got $%02X", cpu.A)
+	}
+
+	// CLC, LDA #$01, ROR A
+	cpu = run([]byte{0x18, 0xA9, 0x01, 0x6A}, 3)
+	if cpu.A != 0x00 || cpu.GetFlag(FLAG_C) == 0 || cpu.GetFlag(FLAG_Z) == 0 {
+		t.Errorf("expected ROR to rotate the carry in and bit 0 out, got $%02X", cpu.A)
+	}
+}
+
+func TestBIT(t *testing.T) {
+	// LDA #$C0, STA $10, LDA #$01, BIT $10
+	cpu := run([]byte{0xA9, 0xC0, 0x85, 0x10, 0xA9, 0x01, 0x24, 0x10}, 4)
+	if cpu.GetFlag(FLAG_N) == 0 || cpu.GetFlag(FLAG_V) == 0 || cpu.GetFlag(FLAG_Z) == 0 {
+		t.Errorf("expected N and V from the memory bits 7 and 6, status = $%02X", cpu.Status)
+	}
+}
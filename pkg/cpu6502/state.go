@@ -0,0 +1,39 @@
+package cpu6502
+
+// Snapshot of the registers and the internal execution state of the CPU
+type State struct {
+	A       byte
+	X       byte
+	Y       byte
+	S       byte
+	PC      uint16
+	Status  byte
+	Cicles  int    // Cicles left of the current instruction
+	Address uint16 // Address of the current instruction opcode
+	Total   uint64 // Cicles ticked since the CPU was created
+}
+
+// Takes a snapshot of the CPU state
+func (cpu *CPU) State() State {
+	return State{
+		A:       cpu.A,
+		X:       cpu.X,
+		Y:       cpu.Y,
+		S:       cpu.S,
+		PC:      cpu.PC,
+		Status:  cpu.Status,
+		Cicles:  cpu.cicles,
+		Address: cpu.address,
+		Total:   cpu.total,
+	}
+}
+
+// Restores the CPU to the snapshot, the memory isn't part of it
+func (cpu *CPU) Restore(state State) {
+	cpu.A, cpu.X, cpu.Y, cpu.S = state.A, state.X, state.Y, state.S
+	cpu.PC = state.PC
+	cpu.Status = state.Status
+	cpu.cicles = state.Cicles
+	cpu.address = state.Address
+	cpu.total = state.Total
+}
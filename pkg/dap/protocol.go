@@ -0,0 +1,180 @@
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Largest message accepted, the requests are small JSON objects
+const MAX_CONTENT_LENGTH = 1 << 20
+
+// Incoming request
+type request struct {
+	Seq       int             `json:"seq"`
+	Type      string          `json:"type"`
+	Command   string          `json:"command"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+type response struct {
+	Seq        int         `json:"seq"`
+	Type       string      `json:"type"`
+	RequestSeq int         `json:"request_seq"`
+	Command    string      `json:"command"`
+	Success    bool        `json:"success"`
+	Message    string      `json:"message,omitempty"`
+	Body       interface{} `json:"body,omitempty"`
+}
+
+type event struct {
+	Seq   int         `json:"seq"`
+	Type  string      `json:"type"`
+	Event string      `json:"event"`
+	Body  interface{} `json:"body,omitempty"`
+}
+
+// Base protocol framing: "Content-Length: n\r\n\r\n" followed by the JSON content
+type connection struct {
+	reader *bufio.Reader
+
+	lock   sync.Mutex
+	writer io.Writer
+	seq    int
+}
+
+func newConnection(in io.Reader, out io.Writer) *connection {
+	return &connection{reader: bufio.NewReader(in), writer: out}
+}
+
+func (c *connection) read() (*request, error) {
+	headers, err := textproto.NewReader(c.reader).ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+
+	length, err := strconv.Atoi(strings.TrimSpace(headers.Get("Content-Length")))
+	if err != nil {
+		return nil, fmt.Errorf("invalid Content-Length header: %w", err)
+	}
+
+	if length < 0 || length > MAX_CONTENT_LENGTH {
+		return nil, fmt.Errorf("invalid Content-Length %d, it goes up to %d", length, MAX_CONTENT_LENGTH)
+	}
+
+	content := make([]byte, length)
+	if _, err := io.ReadFull(c.reader, content); err != nil {
+		return nil, err
+	}
+
+	var message request
+	if err := json.Unmarshal(content, &message); err != nil {
+		return nil, fmt.Errorf("invalid message: %w", err)
+	}
+
+	return &message, nil
+}
+
+// Writes the message assigning its sequence number, safe to call from multiple goroutines
+func (c *connection) write(message interface{}) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.seq++
+
+	switch message := message.(type) {
+	case *response:
+		message.Seq = c.seq
+	case *event:
+		message.Seq = c.seq
+	}
+
+	content, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(c.writer, "Content-Length: %d\r\n\r\n%s", len(content), content)
+	return err
+}
+
+func (c *connection) respond(request *request, body interface{}) error {
+	return c.write(&response{Type: "response", RequestSeq: request.Seq, Command: request.Command, Success: true, Body: body})
+}
+
+func (c *connection) fail(request *request, err error) error {
+	return c.write(&response{Type: "response", RequestSeq: request.Seq, Command: request.Command, Message: err.Error()})
+}
+
+func (c *connection) event(name string, body interface{}) error {
+	return c.write(&event{Type: "event", Event: name, Body: body})
+}
+
+// Protocol types used in the bodies
+
+type capabilities struct {
+	SupportsConfigurationDoneRequest  bool `json:"supportsConfigurationDoneRequest"`
+	SupportsConditionalBreakpoints    bool `json:"supportsConditionalBreakpoints"`
+	SupportsHitConditionalBreakpoints bool `json:"supportsHitConditionalBreakpoints"`
+	SupportsEvaluateForHovers         bool `json:"supportsEvaluateForHovers"`
+	SupportsSetVariable               bool `json:"supportsSetVariable"`
+	SupportsReadMemoryRequest         bool `json:"supportsReadMemoryRequest"`
+	SupportsWriteMemoryRequest        bool `json:"supportsWriteMemoryRequest"`
+	SupportsDisassembleRequest        bool `json:"supportsDisassembleRequest"`
+	SupportsInstructionBreakpoints    bool `json:"supportsInstructionBreakpoints"`
+	SupportsSteppingGranularity       bool `json:"supportsSteppingGranularity"`
+	SupportsTerminateRequest          bool `json:"supportsTerminateRequest"`
+	SupportsStepBack                  bool `json:"supportsStepBack"`
+}
+
+type source struct {
+	Name string `json:"name,omitempty"`
+	Path string `json:"path,omitempty"`
+}
+
+type breakpoint struct {
+	Id                   int     `json:"id,omitempty"`
+	Verified             bool    `json:"verified"`
+	Message              string  `json:"message,omitempty"`
+	Source               *source `json:"source,omitempty"`
+	Line                 int     `json:"line,omitempty"`
+	InstructionReference string  `json:"instructionReference,omitempty"`
+}
+
+type stackFrame struct {
+	Id                          int     `json:"id"`
+	Name                        string  `json:"name"`
+	Source                      *source `json:"source,omitempty"`
+	Line                        int     `json:"line"`
+	Column                      int     `json:"column"`
+	InstructionPointerReference string  `json:"instructionPointerReference,omitempty"`
+}
+
+type scope struct {
+	Name               string `json:"name"`
+	PresentationHint   string `json:"presentationHint,omitempty"`
+	VariablesReference int    `json:"variablesReference"`
+	Expensive          bool   `json:"expensive"`
+}
+
+type variable struct {
+	Name               string `json:"name"`
+	Value              string `json:"value"`
+	Type               string `json:"type,omitempty"`
+	VariablesReference int    `json:"variablesReference"`
+	MemoryReference    string `json:"memoryReference,omitempty"`
+}
+
+type disassembledInstruction struct {
+	Address          string  `json:"address"`
+	InstructionBytes string  `json:"instructionBytes,omitempty"`
+	Instruction      string  `json:"instruction"`
+	Symbol           string  `json:"symbol,omitempty"`
+	Location         *source `json:"location,omitempty"`
+	Line             int     `json:"line,omitempty"`
+}
@@ -0,0 +1,1018 @@
+// Debug Adapter Protocol server so editors can debug programs running in the emulator
+//
+// The launch request loads a binary program to memory:
+//
+//	{
+//	  "program": "build/program.bin",
+//	  "loadAddress": "$8000",   // defaults to $8000
+//	  "entry": "$8000",         // optional, sets the reset vector
+//	  "stopOnEntry": true,
+//	  "debugInfo": "build/program.dbg", // optional, ca65 --dbgfile or JSON to debug the source lines
+//	  "symbols": ["build/program.lbl", "c64"], // optional, label files or memory maps naming the addresses
+//	  "history": 100000 // optional, instructions recorded to step back, 0 disables it
+//	}
+package dap
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/costamauricio/6502-emulator/pkg/bus"
+	"github.com/costamauricio/6502-emulator/pkg/cpu6502"
+	"github.com/costamauricio/6502-emulator/pkg/debugger"
+	"github.com/costamauricio/6502-emulator/pkg/debuginfo"
+	"github.com/costamauricio/6502-emulator/pkg/expr"
+	"github.com/costamauricio/6502-emulator/pkg/symbols"
+)
+
+const (
+	threadId = 1
+
+	registersReference = 1
+	flagsReference     = 2
+)
+
+type Server struct {
+	Logger *log.Logger // Optional, logs the received requests
+
+	conn *connection
+	bus  *bus.Bus
+	dbg  *debugger.Debugger
+
+	lock       sync.Mutex
+	running    bool
+	resumed    func() debugger.Stop // Execution started after the response of the request
+	executions sync.WaitGroup       // Goroutines waiting the stops of the executions
+
+	stopOnEntry            bool
+	instructionBreakpoints []int
+	sourceBreakpoints      map[string][]int // Breakpoint ids by source path
+}
+
+func New() *Server {
+	return &Server{}
+}
+
+// Listens on the TCP address serving one client at a time
+func (s *Server) ListenAndServe(address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+
+		if err := s.Serve(conn, conn); err != nil && s.Logger != nil {
+			s.Logger.Print("connection closed: ", err)
+		}
+
+		conn.Close()
+	}
+}
+
+var errDisconnected = errors.New("disconnected")
+
+// Serves the requests until the client disconnects or the input ends
+func (s *Server) Serve(in io.Reader, out io.Writer) error {
+	*s = Server{Logger: s.Logger, conn: newConnection(in, out)}
+	defer s.detach()
+
+	for {
+		request, err := s.conn.read()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+
+			return err
+		}
+
+		if s.Logger != nil {
+			s.Logger.Printf("<- %s %s", request.Command, request.Arguments)
+		}
+
+		err = s.handle(request)
+		if err == errDisconnected {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+type handler func(*request) (interface{}, error)
+
+func (s *Server) handle(request *request) error {
+	handlers := map[string]handler{
+		"initialize":                s.initialize,
+		"launch":                    s.launch,
+		"configurationDone":         s.configurationDone,
+		"setBreakpoints":            s.setBreakpoints,
+		"setInstructionBreakpoints": s.setInstructionBreakpoints,
+		"setExceptionBreakpoints":   s.setExceptionBreakpoints,
+		"threads":                   s.threads,
+		"stackTrace":                s.stackTrace,
+		"scopes":                    s.scopes,
+		"variables":                 s.variables,
+		"setVariable":               s.setVariable,
+		"evaluate":                  s.evaluate,
+		"readMemory":                s.readMemory,
+		"writeMemory":               s.writeMemory,
+		"disassemble":               s.disassemble,
+		"continue":                  s.cont,
+		"next":                      s.next,
+		"stepIn":                    s.stepIn,
+		"stepOut":                   s.stepOut,
+		"stepBack":                  s.stepBack,
+		"reverseContinue":           s.reverseContinue,
+		"pause":                     s.pause,
+	}
+
+	switch request.Command {
+	case "disconnect", "terminate":
+		if s.dbg != nil {
+			s.dbg.Interrupt()
+		}
+
+		if err := s.conn.respond(request, nil); err != nil {
+			return err
+		}
+
+		if request.Command == "terminate" {
+			return s.conn.event("terminated", nil)
+		}
+
+		return errDisconnected
+	}
+
+	handler, found := handlers[request.Command]
+	if !found {
+		return s.conn.fail(request, fmt.Errorf("unsupported request %q", request.Command))
+	}
+
+	// only pause can be handled while the program runs
+	if request.Command != "pause" && s.isRunning() {
+		return s.conn.fail(request, errors.New("the program is running"))
+	}
+
+	if s.dbg == nil && request.Command != "initialize" && request.Command != "launch" {
+		return s.conn.fail(request, errors.New("no program launched"))
+	}
+
+	s.resumed = nil
+
+	body, err := handler(request)
+	if err != nil {
+		return s.conn.fail(request, err)
+	}
+
+	if err := s.conn.respond(request, body); err != nil {
+		return err
+	}
+
+	// the executions start after the responses, so their stopped events follow them
+	if s.resumed != nil {
+		s.resume(s.resumed)
+	}
+
+	// events that must follow the responses
+	switch request.Command {
+	case "launch":
+		return s.conn.event("initialized", nil)
+	case "configurationDone":
+		if s.stopOnEntry {
+			return s.stopped("entry", nil)
+		}
+
+		s.resume(func() debugger.Stop {
+			return s.dbg.Continue(0)
+		})
+	}
+
+	return nil
+}
+
+func (s *Server) isRunning() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.running
+}
+
+// Resumes the execution once the response of the request is sent
+func (s *Server) resumeAfter(execute func() debugger.Stop) {
+	s.resumed = execute
+}
+
+// Runs the execution in the background sending the stopped event when it halts
+func (s *Server) resume(execute func() debugger.Stop) {
+	s.lock.Lock()
+	s.running = true
+	s.lock.Unlock()
+
+	stops := s.dbg.Start(execute)
+	s.executions.Add(1)
+
+	go func() {
+		defer s.executions.Done()
+		stop := <-stops
+
+		s.lock.Lock()
+		s.running = false
+		s.lock.Unlock()
+
+		switch stop.Reason {
+		case debugger.STOP_BREAKPOINT:
+			s.stopped("breakpoint", []int{stop.Breakpoint.Id})
+		case debugger.STOP_WATCHPOINT:
+			s.stopped("data breakpoint", nil)
+		case debugger.STOP_INTERRUPTED:
+			s.stopped("pause", nil)
+		default:
+			s.stopped("step", nil)
+		}
+	}()
+}
+
+func (s *Server) stopped(reason string, breakpoints []int) error {
+	body := map[string]interface{}{
+		"reason":            reason,
+		"threadId":          threadId,
+		"allThreadsStopped": true,
+	}
+
+	if len(breakpoints) > 0 {
+		body["hitBreakpointIds"] = breakpoints
+	}
+
+	return s.conn.event("stopped", body)
+}
+
+func decode(request *request, arguments interface{}) error {
+	if len(request.Arguments) == 0 {
+		return nil
+	}
+
+	return json.Unmarshal(request.Arguments, arguments)
+}
+
+func (s *Server) env() *expr.Env {
+	return &expr.Env{Cpu: s.dbg.Cpu, Bus: s.dbg.Bus, Symbols: s.symbols()}
+}
+
+func (s *Server) symbols() expr.Symbols {
+	return s.dbg
+}
+
+// Parses addresses given as numbers or strings like "$8000" and "0x8000"
+func parseAddress(value interface{}, fallback uint16) (uint16, error) {
+	switch value := value.(type) {
+	case nil:
+		return fallback, nil
+	case float64:
+		if value < 0 || value > 0xFFFF {
+			return 0, fmt.Errorf("address out of range: %v", value)
+		}
+
+		return uint16(value), nil
+	case string:
+		expression, err := expr.Parse(value)
+		if err != nil {
+			return 0, err
+		}
+
+		return expr.Address(expression, &expr.Env{})
+	}
+
+	return 0, fmt.Errorf("invalid address: %v", value)
+}
+
+func formatReference(address uint16) string {
+	return fmt.Sprintf("0x%04X", address)
+}
+
+func parseReference(reference string, offset int) (uint16, error) {
+	address, err := strconv.ParseUint(strings.TrimPrefix(strings.ToLower(reference), "0x"), 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid memory reference %q", reference)
+	}
+
+	return uint16(int(address) + offset), nil
+}
+
+func (s *Server) initialize(request *request) (interface{}, error) {
+	return capabilities{
+		SupportsConfigurationDoneRequest:  true,
+		SupportsConditionalBreakpoints:    true,
+		SupportsHitConditionalBreakpoints: true,
+		SupportsEvaluateForHovers:         true,
+		SupportsSetVariable:               true,
+		SupportsReadMemoryRequest:         true,
+		SupportsWriteMemoryRequest:        true,
+		SupportsDisassembleRequest:        true,
+		SupportsInstructionBreakpoints:    true,
+		SupportsSteppingGranularity:       true,
+		SupportsTerminateRequest:          true,
+		SupportsStepBack:                  true,
+	}, nil
+}
+
+func (s *Server) launch(request *request) (interface{}, error) {
+	var arguments struct {
+		Program     string      `json:"program"`
+		LoadAddress interface{} `json:"loadAddress"`
+		Entry       interface{} `json:"entry"`
+		StopOnEntry bool        `json:"stopOnEntry"`
+		DebugInfo   string      `json:"debugInfo"`
+		Symbols     []string    `json:"symbols"`
+		History     *int        `json:"history"`
+	}
+
+	if err := decode(request, &arguments); err != nil {
+		return nil, err
+	}
+
+	program, err := os.ReadFile(arguments.Program)
+	if err != nil {
+		return nil, err
+	}
+
+	loadAt, err := parseAddress(arguments.LoadAddress, 0x8000)
+	if err != nil {
+		return nil, err
+	}
+
+	if int(loadAt)+len(program) > 0x10000 {
+		return nil, fmt.Errorf("%d bytes don't fit at $%04X", len(program), loadAt)
+	}
+
+	entry, err := parseAddress(arguments.Entry, loadAt)
+	if err != nil {
+		return nil, err
+	}
+
+	s.bus = &bus.Bus{}
+	s.bus.LoadRam(program, loadAt)
+
+	// only sets the reset vector when the program didn't provide one
+	if arguments.Entry != nil || s.bus.Read(0xFFFC) == 0 && s.bus.Read(0xFFFD) == 0 {
+		s.bus.Write(0xFFFC, byte(entry&0x00FF))
+		s.bus.Write(0xFFFD, byte(entry>>8))
+	}
+
+	watchBus := debugger.NewWatchBus(s.bus)
+	s.dbg = debugger.New(cpu6502.New(watchBus), watchBus)
+	s.dbg.Step()
+
+	if arguments.DebugInfo != "" {
+		if s.dbg.DebugInfo, err = debuginfo.Load(arguments.DebugInfo); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, source := range arguments.Symbols {
+		table, err := symbols.Open(source)
+		if err != nil {
+			return nil, err
+		}
+
+		if s.dbg.Symbols == nil {
+			s.dbg.Symbols = symbols.New()
+		}
+
+		s.dbg.Symbols.Merge(table)
+	}
+
+	// the history is on by default so the editor can step back
+	depth := debugger.DEFAULT_HISTORY_DEPTH
+	if arguments.History != nil {
+		depth = *arguments.History
+	}
+
+	if depth > 0 {
+		if err := s.dbg.EnableHistory(depth); err != nil {
+			return nil, err
+		}
+	}
+
+	s.stopOnEntry = arguments.StopOnEntry
+	s.sourceBreakpoints = make(map[string][]int)
+
+	return nil, nil
+}
+
+func (s *Server) configurationDone(request *request) (interface{}, error) {
+	return nil, nil
+}
+
+type sourceBreakpoint struct {
+	Line         int    `json:"line"`
+	Condition    string `json:"condition"`
+	HitCondition string `json:"hitCondition"`
+}
+
+// Adds a breakpoint with the DAP conditions
+func (s *Server) addBreakpoint(address uint16, condition string, hitCondition string) (*debugger.Breakpoint, error) {
+	var breakpoint *debugger.Breakpoint
+	var err error
+
+	if condition != "" {
+		breakpoint, err = s.dbg.AddConditionalBreakpoint(address, condition, s.symbols())
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		breakpoint = s.dbg.Breakpoints.Add(debugger.Breakpoint{Address: address})
+	}
+
+	if hitCondition != "" {
+		hits, err := strconv.Atoi(strings.TrimSpace(hitCondition))
+		if err != nil || hits < 1 {
+			s.dbg.Breakpoints.Remove(breakpoint.Id)
+			return nil, fmt.Errorf("invalid hit condition %q, expected the hit number", hitCondition)
+		}
+
+		breakpoint.IgnoreCount = hits - 1
+	}
+
+	return breakpoint, nil
+}
+
+func (s *Server) setBreakpoints(request *request) (interface{}, error) {
+	var arguments struct {
+		Source      source             `json:"source"`
+		Breakpoints []sourceBreakpoint `json:"breakpoints"`
+	}
+
+	if err := decode(request, &arguments); err != nil {
+		return nil, err
+	}
+
+	// the request replaces all the breakpoints of the source
+	for _, id := range s.sourceBreakpoints[arguments.Source.Path] {
+		s.dbg.Breakpoints.Remove(id)
+	}
+	delete(s.sourceBreakpoints, arguments.Source.Path)
+
+	breakpoints := []breakpoint{}
+	for _, requested := range arguments.Breakpoints {
+		// source lines need debug information to be mapped to addresses
+		if s.dbg.DebugInfo == nil {
+			breakpoints = append(breakpoints, breakpoint{
+				Verified: false,
+				Message:  "no debug information to map source lines to addresses",
+				Source:   &arguments.Source,
+				Line:     requested.Line,
+			})
+			continue
+		}
+
+		// lines without code are moved to the next line with code
+		address, line, found := s.dbg.DebugInfo.NearestLine(arguments.Source.Path, requested.Line)
+		if !found {
+			breakpoints = append(breakpoints, breakpoint{
+				Verified: false,
+				Message:  "no code generated for the line",
+				Source:   &arguments.Source,
+				Line:     requested.Line,
+			})
+			continue
+		}
+
+		added, err := s.addBreakpoint(address, requested.Condition, requested.HitCondition)
+		if err != nil {
+			breakpoints = append(breakpoints, breakpoint{Verified: false, Message: err.Error(), Source: &arguments.Source, Line: requested.Line})
+			continue
+		}
+
+		s.sourceBreakpoints[arguments.Source.Path] = append(s.sourceBreakpoints[arguments.Source.Path], added.Id)
+		breakpoints = append(breakpoints, breakpoint{
+			Id:                   added.Id,
+			Verified:             true,
+			Source:               &arguments.Source,
+			Line:                 line,
+			InstructionReference: formatReference(address),
+		})
+	}
+
+	return map[string]interface{}{"breakpoints": breakpoints}, nil
+}
+
+func (s *Server) setInstructionBreakpoints(request *request) (interface{}, error) {
+	var arguments struct {
+		Breakpoints []struct {
+			InstructionReference string `json:"instructionReference"`
+			Offset               int    `json:"offset"`
+			Condition            string `json:"condition"`
+			HitCondition         string `json:"hitCondition"`
+		} `json:"breakpoints"`
+	}
+
+	if err := decode(request, &arguments); err != nil {
+		return nil, err
+	}
+
+	for _, id := range s.instructionBreakpoints {
+		s.dbg.Breakpoints.Remove(id)
+	}
+	s.instructionBreakpoints = nil
+
+	breakpoints := []breakpoint{}
+	for _, requested := range arguments.Breakpoints {
+		address, err := parseReference(requested.InstructionReference, requested.Offset)
+		if err != nil {
+			breakpoints = append(breakpoints, breakpoint{Verified: false, Message: err.Error()})
+			continue
+		}
+
+		added, err := s.addBreakpoint(address, requested.Condition, requested.HitCondition)
+		if err != nil {
+			breakpoints = append(breakpoints, breakpoint{Verified: false, Message: err.Error()})
+			continue
+		}
+
+		s.instructionBreakpoints = append(s.instructionBreakpoints, added.Id)
+		breakpoints = append(breakpoints, breakpoint{Id: added.Id, Verified: true, InstructionReference: formatReference(address)})
+	}
+
+	return map[string]interface{}{"breakpoints": breakpoints}, nil
+}
+
+func (s *Server) setExceptionBreakpoints(request *request) (interface{}, error) {
+	return map[string]interface{}{"breakpoints": []breakpoint{}}, nil
+}
+
+func (s *Server) threads(request *request) (interface{}, error) {
+	return map[string]interface{}{
+		"threads": []map[string]interface{}{{"id": threadId, "name": "6502"}},
+	}, nil
+}
+
+func (s *Server) stackTrace(request *request) (interface{}, error) {
+	pc := s.dbg.Cpu.PC
+	operation, _ := cpu6502.Decode(s.dbg.Peek, pc)
+
+	frame := stackFrame{
+		Id:                          0,
+		Name:                        fmt.Sprintf("$%04X: %s", pc, operation.Format(s.dbg.Name)),
+		InstructionPointerReference: formatReference(pc),
+	}
+
+	if label, found := s.dbg.Label(pc); found {
+		frame.Name = label
+	}
+
+	if info := s.dbg.DebugInfo; info != nil {
+		if scope, found := info.Scope(pc); found {
+			frame.Name = scope.Name
+		}
+
+		if location, found := info.Location(pc); found {
+			frame.Source = sourceOf(location)
+			frame.Line = location.Line
+			frame.Column = 1
+		}
+	}
+
+	return map[string]interface{}{"stackFrames": []stackFrame{frame}, "totalFrames": 1}, nil
+}
+
+func (s *Server) scopes(request *request) (interface{}, error) {
+	return map[string]interface{}{
+		"scopes": []scope{{Name: "Registers", PresentationHint: "registers", VariablesReference: registersReference}},
+	}, nil
+}
+
+var flagNames = []struct {
+	name string
+	flag cpu6502.Flag
+}{
+	{"N", cpu6502.FLAG_N},
+	{"V", cpu6502.FLAG_V},
+	{"U", cpu6502.FLAG_U},
+	{"B", cpu6502.FLAG_B},
+	{"D", cpu6502.FLAG_D},
+	{"I", cpu6502.FLAG_I},
+	{"Z", cpu6502.FLAG_Z},
+	{"C", cpu6502.FLAG_C},
+}
+
+func (s *Server) variables(request *request) (interface{}, error) {
+	var arguments struct {
+		VariablesReference int `json:"variablesReference"`
+	}
+
+	if err := decode(request, &arguments); err != nil {
+		return nil, err
+	}
+
+	cpu := s.dbg.Cpu
+	variables := []variable{}
+
+	switch arguments.VariablesReference {
+	case registersReference:
+		variables = append(variables,
+			variable{Name: "A", Value: fmt.Sprintf("$%02X", cpu.A), Type: "byte"},
+			variable{Name: "X", Value: fmt.Sprintf("$%02X", cpu.X), Type: "byte"},
+			variable{Name: "Y", Value: fmt.Sprintf("$%02X", cpu.Y), Type: "byte"},
+			variable{Name: "S", Value: fmt.Sprintf("$%02X", cpu.S), Type: "byte", MemoryReference: formatReference(0x0100 | uint16(cpu.S))},
+			variable{Name: "PC", Value: fmt.Sprintf("$%04X", cpu.PC), Type: "word", MemoryReference: formatReference(cpu.PC)},
+			variable{Name: "P", Value: fmt.Sprintf("$%02X", cpu.Status), Type: "flags", VariablesReference: flagsReference},
+		)
+	case flagsReference:
+		for _, flag := range flagNames {
+			value := "0"
+			if cpu.GetFlag(flag.flag) > 0 {
+				value = "1"
+			}
+
+			variables = append(variables, variable{Name: flag.name, Value: value, Type: "bit"})
+		}
+	}
+
+	return map[string]interface{}{"variables": variables}, nil
+}
+
+func (s *Server) setVariable(request *request) (interface{}, error) {
+	var arguments struct {
+		VariablesReference int    `json:"variablesReference"`
+		Name               string `json:"name"`
+		Value              string `json:"value"`
+	}
+
+	if err := decode(request, &arguments); err != nil {
+		return nil, err
+	}
+
+	value, err := expr.Eval(arguments.Value, s.env())
+	if err != nil {
+		return nil, err
+	}
+
+	cpu := s.dbg.Cpu
+	var formatted string
+
+	switch arguments.Name {
+	case "A":
+		cpu.A = byte(value)
+		formatted = fmt.Sprintf("$%02X", cpu.A)
+	case "X":
+		cpu.X = byte(value)
+		formatted = fmt.Sprintf("$%02X", cpu.X)
+	case "Y":
+		cpu.Y = byte(value)
+		formatted = fmt.Sprintf("$%02X", cpu.Y)
+	case "S":
+		cpu.S = byte(value)
+		formatted = fmt.Sprintf("$%02X", cpu.S)
+	case "PC":
+		cpu.PC = uint16(value)
+		formatted = fmt.Sprintf("$%04X", cpu.PC)
+	case "P":
+		cpu.Status = byte(value)
+		formatted = fmt.Sprintf("$%02X", cpu.Status)
+	default:
+		found := false
+		for _, flag := range flagNames {
+			if flag.name == arguments.Name {
+				cpu.SetFlag(flag.flag, value != 0)
+				formatted, found = strconv.Itoa(int(cpu.GetFlag(flag.flag)/flag.flag)), true
+			}
+		}
+
+		if !found {
+			return nil, fmt.Errorf("unknown register %q", arguments.Name)
+		}
+	}
+
+	return map[string]interface{}{"value": formatted}, nil
+}
+
+func (s *Server) evaluate(request *request) (interface{}, error) {
+	var arguments struct {
+		Expression string `json:"expression"`
+	}
+
+	if err := decode(request, &arguments); err != nil {
+		return nil, err
+	}
+
+	value, err := expr.Eval(arguments.Expression, s.env())
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[string]interface{}{
+		"result":             fmt.Sprintf("$%X (%d)", value, value),
+		"variablesReference": 0,
+	}
+
+	if value >= 0 && value <= 0xFFFF {
+		result["memoryReference"] = formatReference(uint16(value))
+	}
+
+	return result, nil
+}
+
+func (s *Server) readMemory(request *request) (interface{}, error) {
+	var arguments struct {
+		MemoryReference string `json:"memoryReference"`
+		Offset          int    `json:"offset"`
+		Count           int    `json:"count"`
+	}
+
+	if err := decode(request, &arguments); err != nil {
+		return nil, err
+	}
+
+	address, err := parseReference(arguments.MemoryReference, arguments.Offset)
+	if err != nil {
+		return nil, err
+	}
+
+	if arguments.Count < 0 {
+		return nil, fmt.Errorf("invalid count %d", arguments.Count)
+	}
+
+	count := arguments.Count
+	if int(address)+count > 0x10000 {
+		count = 0x10000 - int(address)
+	}
+
+	data := make([]byte, count)
+	for index := range data {
+		data[index] = s.dbg.Peek(address + uint16(index))
+	}
+
+	return map[string]interface{}{
+		"address":         formatReference(address),
+		"data":            base64.StdEncoding.EncodeToString(data),
+		"unreadableBytes": arguments.Count - count,
+	}, nil
+}
+
+func (s *Server) writeMemory(request *request) (interface{}, error) {
+	var arguments struct {
+		MemoryReference string `json:"memoryReference"`
+		Offset          int    `json:"offset"`
+		Data            string `json:"data"`
+	}
+
+	if err := decode(request, &arguments); err != nil {
+		return nil, err
+	}
+
+	address, err := parseReference(arguments.MemoryReference, arguments.Offset)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := base64.StdEncoding.DecodeString(arguments.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	if int(address)+len(data) > 0x10000 {
+		return nil, fmt.Errorf("%d bytes don't fit at $%04X", len(data), address)
+	}
+
+	for index, value := range data {
+		s.dbg.Poke(address+uint16(index), value)
+	}
+
+	return map[string]interface{}{"bytesWritten": len(data)}, nil
+}
+
+func (s *Server) disassemble(request *request) (interface{}, error) {
+	var arguments struct {
+		MemoryReference   string `json:"memoryReference"`
+		Offset            int    `json:"offset"`
+		InstructionOffset int    `json:"instructionOffset"`
+		InstructionCount  int    `json:"instructionCount"`
+	}
+
+	if err := decode(request, &arguments); err != nil {
+		return nil, err
+	}
+
+	address, err := parseReference(arguments.MemoryReference, arguments.Offset)
+	if err != nil {
+		return nil, err
+	}
+
+	var operations []cpu6502.Operation
+
+	if arguments.InstructionOffset < 0 {
+		// the instruction boundaries before the address are unknown, so it decodes
+		// from further back hoping the instructions realign before reaching the address
+		before := -arguments.InstructionOffset
+		start := int(address) - before*3
+		if start < 0 {
+			start = 0
+		}
+
+		var previous []cpu6502.Operation
+		for current := start; current < int(address); {
+			operation, _ := cpu6502.Decode(s.dbg.Peek, uint16(current))
+			previous = append(previous, operation)
+			current += int(operation.Size)
+		}
+
+		if len(previous) > before {
+			previous = previous[len(previous)-before:]
+		}
+
+		operations = append(operations, previous...)
+	}
+
+	current := uint(address)
+	for index := 0; index < arguments.InstructionOffset; index++ {
+		operation, _ := cpu6502.Decode(s.dbg.Peek, uint16(current))
+		current += uint(operation.Size)
+	}
+
+	for len(operations) < arguments.InstructionCount && current <= 0xFFFF {
+		operation, _ := cpu6502.Decode(s.dbg.Peek, uint16(current))
+		operations = append(operations, operation)
+		current += uint(operation.Size)
+	}
+
+	instructions := []disassembledInstruction{}
+	for _, operation := range operations {
+		var raw []string
+		for index := uint16(0); index < operation.Size; index++ {
+			raw = append(raw, fmt.Sprintf("%02X", s.dbg.Peek(operation.Address+index)))
+		}
+
+		instruction := disassembledInstruction{
+			Address:          formatReference(operation.Address),
+			InstructionBytes: strings.Join(raw, " "),
+			Instruction:      operation.Format(s.dbg.Name),
+		}
+
+		instruction.Symbol, _ = s.dbg.Label(operation.Address)
+
+		if info := s.dbg.DebugInfo; info != nil {
+			if location, found := info.Location(operation.Address); found {
+				instruction.Location = sourceOf(location)
+				instruction.Line = location.Line
+			}
+		}
+
+		instructions = append(instructions, instruction)
+	}
+
+	return map[string]interface{}{"instructions": instructions}, nil
+}
+
+func (s *Server) cont(request *request) (interface{}, error) {
+	s.resumeAfter(func() debugger.Stop {
+		return s.dbg.Continue(0)
+	})
+
+	return map[string]interface{}{"allThreadsContinued": true}, nil
+}
+
+func sourceOf(location debuginfo.Location) *source {
+	return &source{Name: filepath.Base(location.File), Path: location.File}
+}
+
+type stepArguments struct {
+	Granularity string `json:"granularity"`
+}
+
+// Steps by source lines when there is debug information and the instruction granularity wasn't requested
+// The step is repeated until the PC reaches another source line
+func (s *Server) stepping(request *request, step func() debugger.Stop) func() debugger.Stop {
+	var arguments stepArguments
+	if err := decode(request, &arguments); err != nil || s.dbg.DebugInfo == nil || arguments.Granularity == "instruction" {
+		return step
+	}
+
+	return func() debugger.Stop {
+		start, found := s.dbg.DebugInfo.Location(s.dbg.Cpu.PC)
+		if !found {
+			return step()
+		}
+
+		for {
+			stop := step()
+			if stop.Reason != debugger.STOP_STEP {
+				return stop
+			}
+
+			// instructions without source, like the ones of libraries, are stepped through
+			if location, found := s.dbg.DebugInfo.Location(s.dbg.Cpu.PC); found && location != start {
+				return stop
+			}
+		}
+	}
+}
+
+func (s *Server) stepIn(request *request) (interface{}, error) {
+	s.resumeAfter(s.stepping(request, func() debugger.Stop {
+		return s.dbg.Continue(1)
+	}))
+
+	return nil, nil
+}
+
+// Steps over subroutine calls
+func (s *Server) next(request *request) (interface{}, error) {
+	s.resumeAfter(s.stepping(request, func() debugger.Stop {
+		operation, _ := cpu6502.Decode(s.dbg.Peek, s.dbg.Cpu.PC)
+
+		if operation.Instruction != cpu6502.INS_JSR {
+			return s.dbg.Continue(1)
+		}
+
+		stop := s.dbg.RunTo(operation.Address + operation.Size)
+
+		if stop.Breakpoint != nil && stop.Breakpoint.Temporary {
+			return debugger.Stop{Reason: debugger.STOP_STEP}
+		}
+
+		return stop
+	}))
+
+	return nil, nil
+}
+
+// Runs until the current subroutine returns
+func (s *Server) stepOut(request *request) (interface{}, error) {
+	s.resumeAfter(func() debugger.Stop {
+		stack := s.dbg.Cpu.S
+
+		for {
+			operation, _ := cpu6502.Decode(s.dbg.Peek, s.dbg.Cpu.PC)
+
+			stop := s.dbg.Continue(1)
+			if stop.Reason != debugger.STOP_STEP {
+				return stop
+			}
+
+			returned := operation.Instruction == cpu6502.INS_RTS || operation.Instruction == cpu6502.INS_RTI
+			if returned && s.dbg.Cpu.S > stack {
+				return stop
+			}
+		}
+	})
+
+	return nil, nil
+}
+
+func (s *Server) stepBack(request *request) (interface{}, error) {
+	if s.dbg.History == nil {
+		return nil, errors.New("the history is disabled")
+	}
+
+	s.resumeAfter(s.stepping(request, func() debugger.Stop {
+		return s.dbg.ReverseContinue(1)
+	}))
+
+	return nil, nil
+}
+
+func (s *Server) reverseContinue(request *request) (interface{}, error) {
+	if s.dbg.History == nil {
+		return nil, errors.New("the history is disabled")
+	}
+
+	s.resumeAfter(func() debugger.Stop {
+		return s.dbg.ReverseContinue(0)
+	})
+
+	return nil, nil
+}
+
+func (s *Server) pause(request *request) (interface{}, error) {
+	s.dbg.Interrupt()
+
+	return nil, nil
+}
+
+// Halts the execution the client left running and waits for it, so the next Serve starts clean
+func (s *Server) detach() {
+	if s.dbg != nil {
+		s.dbg.Interrupt()
+	}
+
+	s.executions.Wait()
+}
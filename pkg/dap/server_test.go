@@ -0,0 +1,329 @@
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type client struct {
+	t      *testing.T
+	in     io.Writer
+	reader *bufio.Reader
+	seq    int
+}
+
+// Sends the request and returns the response, collecting the events received before it
+func (c *client) request(command string, arguments interface{}) (map[string]interface{}, []map[string]interface{}) {
+	c.t.Helper()
+
+	response, events := c.exchange(command, arguments)
+	if response["success"] != true {
+		c.t.Fatalf("%s failed: %v", command, response["message"])
+	}
+
+	return response, events
+}
+
+// Sends the request expecting it to fail, returns the error message
+func (c *client) failure(command string, arguments interface{}) string {
+	c.t.Helper()
+
+	response, _ := c.exchange(command, arguments)
+	if response["success"] != false {
+		c.t.Fatalf("expected %s to fail", command)
+	}
+
+	message, _ := response["message"].(string)
+	return message
+}
+
+func (c *client) exchange(command string, arguments interface{}) (map[string]interface{}, []map[string]interface{}) {
+	c.t.Helper()
+	c.seq++
+
+	content, _ := json.Marshal(map[string]interface{}{
+		"seq":       c.seq,
+		"type":      "request",
+		"command":   command,
+		"arguments": arguments,
+	})
+	fmt.Fprintf(c.in, "Content-Length: %d\r\n\r\n%s", len(content), content)
+
+	var events []map[string]interface{}
+	for {
+		message := c.receive()
+		if message["type"] == "response" {
+			return message, events
+		}
+
+		events = append(events, message)
+	}
+}
+
+func (c *client) receive() map[string]interface{} {
+	c.t.Helper()
+
+	received := make(chan map[string]interface{})
+	go func() {
+		var length int
+		if _, err := fmt.Fscanf(c.reader, "Content-Length: %d\r\n\r\n", &length); err != nil {
+			received <- nil
+			return
+		}
+
+		content := make([]byte, length)
+		io.ReadFull(c.reader, content)
+
+		var message map[string]interface{}
+		json.Unmarshal(content, &message)
+		received <- message
+	}()
+
+	select {
+	case message := <-received:
+		if message == nil {
+			c.t.Fatal("connection closed")
+		}
+
+		return message
+	case <-time.After(5 * time.Second):
+		c.t.Fatal("timeout waiting for a message")
+	}
+
+	return nil
+}
+
+// Waits the event, skipping the others
+func (c *client) event(name string) map[string]interface{} {
+	c.t.Helper()
+
+	for {
+		message := c.receive()
+		if message["type"] == "event" && message["event"] == name {
+			return message
+		}
+	}
+}
+
+func newClient(t *testing.T) *client {
+	serverIn, clientOut := io.Pipe()
+	clientIn, serverOut := io.Pipe()
+
+	go New().Serve(serverIn, serverOut)
+
+	t.Cleanup(func() {
+		clientOut.Close()
+		serverOut.Close()
+	})
+
+	return &client{t: t, in: clientOut, reader: bufio.NewReader(clientIn)}
+}
+
+func TestSession(t *testing.T) {
+	// JSR sub; LDX #$02; loop: JMP loop; sub: LDA #$10; RTS
+	program := filepath.Join(t.TempDir(), "program.bin")
+	os.WriteFile(program, []byte{0x20, 0x08, 0x80, 0xA2, 0x02, 0x4C, 0x05, 0x80, 0xA9, 0x10, 0x60}, 0644)
+
+	client := newClient(t)
+
+	response, _ := client.request("initialize", map[string]interface{}{"adapterID": "6502"})
+	if response["body"].(map[string]interface{})["supportsDisassembleRequest"] != true {
+		t.Errorf("expected disassemble support")
+	}
+
+	client.request("launch", map[string]interface{}{"program": program, "loadAddress": "$8000", "stopOnEntry": true})
+	client.event("initialized")
+
+	response, _ = client.request("setInstructionBreakpoints", map[string]interface{}{
+		"breakpoints": []map[string]interface{}{{"instructionReference": "0x8008", "condition": "A == 0"}},
+	})
+	breakpoints := response["body"].(map[string]interface{})["breakpoints"].([]interface{})
+	if breakpoints[0].(map[string]interface{})["verified"] != true {
+		t.Errorf("expected verified breakpoint: %v", breakpoints)
+	}
+
+	client.request("configurationDone", nil)
+	if stopped := client.event("stopped"); stopped["body"].(map[string]interface{})["reason"] != "entry" {
+		t.Errorf("expected entry stop: %v", stopped)
+	}
+
+	client.request("continue", map[string]interface{}{"threadId": 1})
+	if stopped := client.event("stopped"); stopped["body"].(map[string]interface{})["reason"] != "breakpoint" {
+		t.Errorf("expected breakpoint stop: %v", stopped)
+	}
+
+	// the stopped event follows the response
+	if _, events := client.request("stepOut", map[string]interface{}{"threadId": 1}); len(events) > 0 {
+		t.Errorf("expected no event before the response: %v", events)
+	}
+	client.event("stopped")
+
+	response, _ = client.request("variables", map[string]interface{}{"variablesReference": registersReference})
+	registers := map[string]string{}
+	for _, register := range response["body"].(map[string]interface{})["variables"].([]interface{}) {
+		register := register.(map[string]interface{})
+		registers[register["name"].(string)] = register["value"].(string)
+	}
+
+	if registers["A"] != "$10" || registers["PC"] != "$8003" {
+		t.Errorf("unexpected registers %v", registers)
+	}
+
+	response, _ = client.request("readMemory", map[string]interface{}{"memoryReference": "0x8000", "count": 3})
+	if data := response["body"].(map[string]interface{})["data"]; data != "IAiA" {
+		t.Errorf("unexpected memory %v", data)
+	}
+
+	if message := client.failure("readMemory", map[string]interface{}{"memoryReference": "0x8000", "count": -1}); message != "invalid count -1" {
+		t.Errorf("expected the negative count refused, got %q", message)
+	}
+
+	response, _ = client.request("disassemble", map[string]interface{}{"memoryReference": "0x8000", "instructionCount": 2})
+	instructions := response["body"].(map[string]interface{})["instructions"].([]interface{})
+	if instruction := instructions[1].(map[string]interface{})["instruction"]; instruction != "LDX #$02" {
+		t.Errorf("unexpected disassembly %v", instruction)
+	}
+
+	response, _ = client.request("evaluate", map[string]interface{}{"expression": "A + 1"})
+	if result := response["body"].(map[string]interface{})["result"]; result != "$11 (17)" {
+		t.Errorf("unexpected evaluation %v", result)
+	}
+
+	client.request("continue", map[string]interface{}{"threadId": 1})
+	client.request("pause", map[string]interface{}{"threadId": 1})
+	if stopped := client.event("stopped"); stopped["body"].(map[string]interface{})["reason"] != "pause" {
+		t.Errorf("expected pause stop: %v", stopped)
+	}
+
+	// the step out of the top frame runs until the pause, it halts between the steps
+	client.request("stepOut", map[string]interface{}{"threadId": 1})
+	client.request("pause", map[string]interface{}{"threadId": 1})
+	if stopped := client.event("stopped"); stopped["body"].(map[string]interface{})["reason"] != "pause" {
+		t.Errorf("expected pause stop: %v", stopped)
+	}
+
+	client.request("disconnect", nil)
+}
+
+func TestReconnect(t *testing.T) {
+	// loop: JMP loop
+	program := filepath.Join(t.TempDir(), "program.bin")
+	os.WriteFile(program, []byte{0x4C, 0x00, 0x80}, 0644)
+
+	server := New()
+
+	for session := 0; session < 2; session++ {
+		serverIn, clientOut := io.Pipe()
+		clientIn, serverOut := io.Pipe()
+
+		served := make(chan error)
+		go func() { served <- server.Serve(serverIn, serverOut) }()
+
+		client := &client{t: t, in: clientOut, reader: bufio.NewReader(clientIn)}
+		client.request("initialize", map[string]interface{}{"adapterID": "6502"})
+		client.request("launch", map[string]interface{}{"program": program, "loadAddress": "$8000"})
+		client.event("initialized")
+		client.request("configurationDone", nil)
+
+		// the client goes away with the program running, the stopped event isn't read
+		go io.Copy(io.Discard, clientIn)
+		clientOut.Close()
+
+		if err := <-served; err != nil {
+			t.Fatal(err)
+		}
+
+		if server.isRunning() {
+			t.Fatal("expected the execution halted once served")
+		}
+
+		serverOut.Close()
+	}
+}
+
+func TestContentLength(t *testing.T) {
+	for _, length := range []int{-1, MAX_CONTENT_LENGTH + 1} {
+		header := fmt.Sprintf("Content-Length: %d\r\n\r\n{}", length)
+
+		if _, err := newConnection(strings.NewReader(header), io.Discard).read(); err == nil {
+			t.Errorf("expected the length %d refused", length)
+		}
+	}
+}
+
+func TestSourceBreakpoints(t *testing.T) {
+	// JSR sub; LDX #$02; loop: JMP loop; sub: LDA #$10; RTS
+	directory := t.TempDir()
+	program := filepath.Join(directory, "program.bin")
+	os.WriteFile(program, []byte{0x20, 0x08, 0x80, 0xA2, 0x02, 0x4C, 0x05, 0x80, 0xA9, 0x10, 0x60}, 0644)
+
+	debugInfo := filepath.Join(directory, "program.json")
+	os.WriteFile(debugInfo, []byte(`{
+		"lines": [
+			{"file": "main.s", "line": 1, "start": 32768, "size": 3},
+			{"file": "main.s", "line": 2, "start": 32771, "size": 2},
+			{"file": "main.s", "line": 3, "start": 32773, "size": 3},
+			{"file": "main.s", "line": 6, "start": 32776, "size": 2},
+			{"file": "main.s", "line": 7, "start": 32778, "size": 1}
+		],
+		"symbols": [{"name": "loop", "address": 32773}, {"name": "sub", "address": 32776}],
+		"scopes": [{"name": "sub", "start": 32776, "end": 32778}]
+	}`), 0644)
+
+	client := newClient(t)
+	client.request("initialize", map[string]interface{}{"adapterID": "6502"})
+	client.request("launch", map[string]interface{}{"program": program, "debugInfo": debugInfo})
+	client.event("initialized")
+
+	// line 5 has no code, so the breakpoint moves to the line 6
+	response, _ := client.request("setBreakpoints", map[string]interface{}{
+		"source":      map[string]interface{}{"path": filepath.Join(directory, "main.s")},
+		"breakpoints": []map[string]interface{}{{"line": 5}},
+	})
+	breakpoint := response["body"].(map[string]interface{})["breakpoints"].([]interface{})[0].(map[string]interface{})
+	if breakpoint["verified"] != true || breakpoint["line"] != float64(6) {
+		t.Errorf("expected verified breakpoint at line 6: %v", breakpoint)
+	}
+
+	client.request("configurationDone", nil)
+	if stopped := client.event("stopped"); stopped["body"].(map[string]interface{})["reason"] != "breakpoint" {
+		t.Errorf("expected breakpoint stop: %v", stopped)
+	}
+
+	response, _ = client.request("stackTrace", map[string]interface{}{"threadId": 1})
+	frame := response["body"].(map[string]interface{})["stackFrames"].([]interface{})[0].(map[string]interface{})
+	if frame["name"] != "sub" || frame["line"] != float64(6) || frame["source"].(map[string]interface{})["name"] != "main.s" {
+		t.Errorf("unexpected stack frame %v", frame)
+	}
+
+	// steps the source lines out of the subroutine
+	client.request("next", map[string]interface{}{"threadId": 1})
+	client.event("stopped")
+	client.request("next", map[string]interface{}{"threadId": 1})
+	client.event("stopped")
+
+	response, _ = client.request("stackTrace", map[string]interface{}{"threadId": 1})
+	frame = response["body"].(map[string]interface{})["stackFrames"].([]interface{})[0].(map[string]interface{})
+	if frame["line"] != float64(2) {
+		t.Errorf("expected line 2 after stepping: %v", frame)
+	}
+
+	if message := client.failure("readMemory", map[string]interface{}{"memoryReference": "0x8000", "count": -1}); message != "invalid count -1" {
+		t.Errorf("expected the negative count refused, got %q", message)
+	}
+
+	response, _ = client.request("disassemble", map[string]interface{}{"memoryReference": "0x8000", "instructionCount": 1})
+	instruction := response["body"].(map[string]interface{})["instructions"].([]interface{})[0].(map[string]interface{})
+	if instruction["instruction"] != "JSR sub" || instruction["line"] != float64(1) {
+		t.Errorf("unexpected disassembly %v", instruction)
+	}
+
+	client.request("disconnect", nil)
+}
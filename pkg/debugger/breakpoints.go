@@ -0,0 +1,189 @@
+package debugger
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/costamauricio/6502-emulator/pkg/cpu6502"
+)
+
+// Condition evaluated against the current CPU and memory state
+type Condition func(cpu *cpu6502.CPU, bus cpu6502.Bus) bool
+
+type Breakpoint struct {
+	Id        int
+	Address   uint16
+	Condition Condition // Optional, the breakpoint only halts when it returns true
+
+	// Description of the condition, used when listing the breakpoints
+	Expression string
+
+	IgnoreCount int  // Number of hits to skip before halting
+	Temporary   bool // Removed after the first time it halts
+	Disabled    bool
+	Hits        int // Number of times the breakpoint halted the execution
+}
+
+func (breakpoint *Breakpoint) String() string {
+	description := fmt.Sprintf("breakpoint %d at $%04X", breakpoint.Id, breakpoint.Address)
+
+	if breakpoint.Expression != "" {
+		description += " if " + breakpoint.Expression
+	}
+
+	if breakpoint.IgnoreCount > 0 {
+		description += fmt.Sprintf(" (ignore next %d)", breakpoint.IgnoreCount)
+	}
+
+	if breakpoint.Temporary {
+		description += " (temporary)"
+	}
+
+	if breakpoint.Disabled {
+		description += " (disabled)"
+	}
+
+	return description
+}
+
+// Keeps the breakpoints indexed by address
+type Breakpoints struct {
+	byAddress map[uint16][]*Breakpoint
+	nextId    int
+}
+
+func NewBreakpoints() *Breakpoints {
+	return &Breakpoints{byAddress: make(map[uint16][]*Breakpoint), nextId: 1}
+}
+
+// Adds a new breakpoint, returning it with the assigned id
+func (b *Breakpoints) Add(breakpoint Breakpoint) *Breakpoint {
+	breakpoint.Id = b.nextId
+	b.nextId++
+
+	b.byAddress[breakpoint.Address] = append(b.byAddress[breakpoint.Address], &breakpoint)
+	return &breakpoint
+}
+
+// Removes the breakpoint, returning false when it doesn't exist
+func (b *Breakpoints) Remove(id int) bool {
+	breakpoint := b.Get(id)
+	if breakpoint == nil {
+		return false
+	}
+
+	list := b.byAddress[breakpoint.Address]
+	for index := range list {
+		if list[index].Id == id {
+			list = append(list[:index], list[index+1:]...)
+			break
+		}
+	}
+
+	if len(list) == 0 {
+		delete(b.byAddress, breakpoint.Address)
+	} else {
+		b.byAddress[breakpoint.Address] = list
+	}
+
+	return true
+}
+
+// Removes all the breakpoints on the address
+func (b *Breakpoints) RemoveAt(address uint16) {
+	delete(b.byAddress, address)
+}
+
+func (b *Breakpoints) Clear() {
+	b.byAddress = make(map[uint16][]*Breakpoint)
+}
+
+func (b *Breakpoints) Get(id int) *Breakpoint {
+	for _, list := range b.byAddress {
+		for _, breakpoint := range list {
+			if breakpoint.Id == id {
+				return breakpoint
+			}
+		}
+	}
+
+	return nil
+}
+
+// Breakpoints on the address
+func (b *Breakpoints) At(address uint16) []*Breakpoint {
+	return b.byAddress[address]
+}
+
+// All the breakpoints ordered by id
+func (b *Breakpoints) List() []*Breakpoint {
+	var list []*Breakpoint
+
+	for _, breakpoints := range b.byAddress {
+		list = append(list, breakpoints...)
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Id < list[j].Id
+	})
+
+	return list
+}
+
+// First enabled breakpoint at the current PC with its condition true, without updating it
+// The reverse execution halts on it without changing the breakpoints of the forward one
+func (b *Breakpoints) Match(cpu *cpu6502.CPU, bus cpu6502.Bus) *Breakpoint {
+	for _, breakpoint := range b.byAddress[cpu.PC] {
+		if !breakpoint.Disabled && (breakpoint.Condition == nil || breakpoint.Condition(cpu, bus)) {
+			return breakpoint
+		}
+	}
+
+	return nil
+}
+
+// Verifies if the execution should halt at the current PC
+// It updates the hit and ignore counts and removes the temporary breakpoints that were hit
+func (b *Breakpoints) Check(cpu *cpu6502.CPU, bus cpu6502.Bus) *Breakpoint {
+	list, found := b.byAddress[cpu.PC]
+	if !found {
+		return nil
+	}
+
+	var halted *Breakpoint
+
+	for _, breakpoint := range list {
+		if breakpoint.Disabled {
+			continue
+		}
+
+		if breakpoint.Condition != nil && !breakpoint.Condition(cpu, bus) {
+			continue
+		}
+
+		if breakpoint.IgnoreCount > 0 {
+			breakpoint.IgnoreCount--
+			continue
+		}
+
+		breakpoint.Hits++
+		if halted == nil {
+			halted = breakpoint
+		}
+	}
+
+	if halted != nil {
+		var temporary []int
+		for _, breakpoint := range list {
+			if breakpoint.Temporary && breakpoint.Hits > 0 {
+				temporary = append(temporary, breakpoint.Id)
+			}
+		}
+
+		for _, id := range temporary {
+			b.Remove(id)
+		}
+	}
+
+	return halted
+}
@@ -0,0 +1,62 @@
+package debugger
+
+import (
+	"fmt"
+
+	"github.com/costamauricio/6502-emulator/pkg/cpu6502"
+	"github.com/costamauricio/6502-emulator/pkg/expr"
+)
+
+// Compiles an expression like "A == $10 && C" into a breakpoint condition
+// Evaluation errors are treated as a met condition so the problem is noticed
+func CompileCondition(source string, symbols expr.Symbols) (Condition, error) {
+	expression, err := expr.Parse(source)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(cpu *cpu6502.CPU, bus cpu6502.Bus) bool {
+		value, err := expression.Eval(&expr.Env{Cpu: cpu, Bus: bus, Symbols: symbols})
+		return err != nil || value != 0
+	}, nil
+}
+
+// Compiles an expression into a watchpoint condition
+// Besides the registers and memory it can use the variables:
+//
+//	VALUE     value read or written
+//	PREVIOUS  value stored before a write
+//	ADDRESS   accessed address
+//
+// e.g. "VALUE > $7F" for writes of negative values
+func CompileWatchCondition(source string, cpu *cpu6502.CPU, bus cpu6502.Bus, symbols expr.Symbols) (func(Hit) bool, error) {
+	expression, err := expr.Parse(source)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(hit Hit) bool {
+		value, err := expression.Eval(&expr.Env{
+			Cpu:     cpu,
+			Bus:     bus,
+			Symbols: symbols,
+			Variables: map[string]int{
+				"VALUE":    int(hit.Value),
+				"PREVIOUS": int(hit.Previous),
+				"ADDRESS":  int(hit.Address),
+			},
+		})
+
+		return err != nil || value != 0
+	}, nil
+}
+
+// Adds a breakpoint halting only when the expression is true
+func (d *Debugger) AddConditionalBreakpoint(address uint16, source string, symbols expr.Symbols) (*Breakpoint, error) {
+	condition, err := CompileCondition(source, symbols)
+	if err != nil {
+		return nil, fmt.Errorf("invalid condition: %w", err)
+	}
+
+	return d.Breakpoints.Add(Breakpoint{Address: address, Condition: condition, Expression: source}), nil
+}
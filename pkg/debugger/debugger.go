@@ -0,0 +1,292 @@
+package debugger
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/costamauricio/6502-emulator/pkg/cpu6502"
+	"github.com/costamauricio/6502-emulator/pkg/debuginfo"
+	"github.com/costamauricio/6502-emulator/pkg/replay"
+	"github.com/costamauricio/6502-emulator/pkg/symbols"
+)
+
+type StopReason string
+
+const (
+	STOP_STEP        StopReason = "step"        // Requested instructions were executed
+	STOP_BREAKPOINT  StopReason = "breakpoint"  // PC reached a breakpoint
+	STOP_WATCHPOINT  StopReason = "watchpoint"  // A watchpoint was triggered
+	STOP_INTERRUPTED StopReason = "interrupted" // Interrupt was called while running
+	STOP_HISTORY     StopReason = "history"     // Running backwards reached the oldest recorded step
+)
+
+type Stop struct {
+	Reason     StopReason
+	Breakpoint *Breakpoint // When stopped by a breakpoint
+	Hits       []Hit       // When stopped by watchpoints
+}
+
+// Controls the execution of the CPU halting on breakpoints and watchpoints
+// It is shared by the debugger frontends (visualizer, wasm, monitor, ...)
+type Debugger struct {
+	Cpu         *cpu6502.CPU
+	Bus         cpu6502.Bus // Bus used by the CPU
+	Watch       *WatchBus   // Optional, when the CPU bus is wrapped to support watchpoints
+	Breakpoints *Breakpoints
+	DebugInfo   *debuginfo.Info  // Optional, used to show labels and source lines
+	Symbols     *symbols.Table   // Optional, names the addresses without debug information
+	History     *History         // Enabled by EnableHistory to step back
+	Tick        func()           // Optional, clocks the whole machine instead of only the CPU, e.g. System.Tick
+	Recorder    *replay.Recorder // Optional, logs the interrupts and resets requested to replay the run
+
+	mutex       sync.Mutex // Guards running against Interrupt
+	running     int        // Nested executions, e.g. the steps of a Start
+	interrupted atomic.Bool
+}
+
+// Creates a debugger for the CPU, bus should be the same bus used by the CPU
+// When it is a *WatchBus the watchpoints are also checked
+func New(cpu *cpu6502.CPU, bus cpu6502.Bus) *Debugger {
+	debugger := &Debugger{Cpu: cpu, Bus: bus, Breakpoints: NewBreakpoints()}
+
+	if watchBus, ok := bus.(*WatchBus); ok {
+		debugger.Watch = watchBus
+		watchBus.Attach(cpu)
+	}
+
+	return debugger
+}
+
+// Reads memory without triggering watchpoints
+func (d *Debugger) Peek(address uint16) byte {
+	if peeker, ok := d.Bus.(Peeker); ok {
+		return peeker.Peek(address)
+	}
+
+	return d.Bus.Read(address)
+}
+
+// Writes memory without triggering watchpoints
+func (d *Debugger) Poke(address uint16, data byte) {
+	if d.Watch != nil {
+		d.Watch.Poke(address, data)
+		return
+	}
+
+	d.Bus.Write(address, data)
+}
+
+// Executes a single instruction, running out the pending cicles first
+func (d *Debugger) Step() {
+	d.record(func() {
+		for {
+			if d.Tick != nil {
+				d.Tick()
+			} else {
+				d.Cpu.Tick()
+			}
+
+			if d.Cpu.InstructionCompleted() {
+				break
+			}
+		}
+	})
+}
+
+// Requests an IRQ, recorded in the history so it can be stepped back
+func (d *Debugger) InterruptRequest() {
+	if d.Recorder != nil {
+		d.record(d.Recorder.InterruptRequest)
+		return
+	}
+
+	d.record(d.Cpu.InterruptRequest)
+}
+
+// Requests an NMI, recorded in the history so it can be stepped back
+func (d *Debugger) NonMaskableInterrupt() {
+	if d.Recorder != nil {
+		d.record(d.Recorder.NonMaskableInterrupt)
+		return
+	}
+
+	d.record(d.Cpu.NonMaskableInterrupt)
+}
+
+// Resets the CPU, logged by the recorder when recording
+func (d *Debugger) Reset() {
+	if d.Recorder != nil {
+		d.Recorder.Reset()
+		return
+	}
+
+	d.Cpu.Reset()
+}
+
+// Executes up to count instructions, halting on breakpoints and watchpoints
+// When count is 0 it runs until something halts it
+// The breakpoint on the current PC isn't checked, so it is possible to continue from a breakpoint
+func (d *Debugger) Continue(count int) Stop {
+	d.begin()
+	defer d.end()
+
+	for executed := 0; count == 0 || executed < count; executed++ {
+		if d.interrupted.Swap(false) {
+			return Stop{Reason: STOP_INTERRUPTED}
+		}
+
+		d.Step()
+
+		if d.Watch != nil {
+			if hits := d.Watch.Triggered(); len(hits) > 0 {
+				return Stop{Reason: STOP_WATCHPOINT, Hits: hits}
+			}
+		}
+
+		if breakpoint := d.Breakpoints.Check(d.Cpu, d.Bus); breakpoint != nil {
+			return Stop{Reason: STOP_BREAKPOINT, Breakpoint: breakpoint}
+		}
+	}
+
+	return Stop{Reason: STOP_STEP}
+}
+
+// Runs until the PC reaches the address using a temporary breakpoint
+func (d *Debugger) RunTo(address uint16) Stop {
+	temporary := d.Breakpoints.Add(Breakpoint{Address: address, Temporary: true})
+
+	stop := d.Continue(0)
+
+	d.Breakpoints.Remove(temporary.Id)
+	return stop
+}
+
+// Runs the execution in the background, sending its stop on the returned channel
+// It's running from the call, so Interrupt halts it even before it starts, and the executions
+// it's made of, like the steps over the source lines, are halted between any of them
+func (d *Debugger) Start(execute func() Stop) <-chan Stop {
+	stops := make(chan Stop, 1)
+	d.begin()
+
+	go func() {
+		stop := execute()
+		d.end()
+
+		stops <- stop
+	}()
+
+	return stops
+}
+
+// Halts a running Continue or Start, it's safe to call from another goroutine
+// When nothing is running it's ignored, so the next Continue executes
+func (d *Debugger) Interrupt() {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.running > 0 {
+		d.interrupted.Store(true)
+	}
+}
+
+// Marks an execution running so Interrupt halts it, the nested ones keep the pending interrupt
+func (d *Debugger) begin() {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.running == 0 {
+		d.interrupted.Store(false)
+	}
+
+	d.running++
+}
+
+func (d *Debugger) end() {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.running--; d.running == 0 {
+		d.interrupted.Store(false)
+	}
+}
+
+// Name of the symbol exactly at the address, from the debug information or the symbol table
+func (d *Debugger) Label(address uint16) (string, bool) {
+	if d.DebugInfo != nil {
+		if label, found := d.DebugInfo.Label(address); found {
+			return label, true
+		}
+	}
+
+	if d.Symbols != nil {
+		return d.Symbols.Label(address)
+	}
+
+	return "", false
+}
+
+// Names the address like Label, also relative to a symbol before it, e.g. "SCREEN+1"
+// It's used to name the operands
+func (d *Debugger) Name(address uint16) (string, bool) {
+	if label, found := d.Label(address); found {
+		return label, true
+	}
+
+	if d.Symbols != nil {
+		return d.Symbols.Name(address)
+	}
+
+	return "", false
+}
+
+// Address of the symbol from the debug information or the symbol table, it implements expr.Symbols
+func (d *Debugger) Lookup(name string) (uint16, bool) {
+	if d.DebugInfo != nil {
+		if address, found := d.DebugInfo.Lookup(name); found {
+			return address, true
+		}
+	}
+
+	if d.Symbols != nil {
+		return d.Symbols.Lookup(name)
+	}
+
+	return 0, false
+}
+
+// Source line of the code at the address as "main.s:12: LDA #$01"
+func (d *Debugger) SourceLine(address uint16) (string, bool) {
+	if d.DebugInfo == nil {
+		return "", false
+	}
+
+	location, found := d.DebugInfo.Location(address)
+	if !found {
+		return "", false
+	}
+
+	if source, found := d.DebugInfo.Source(location); found {
+		return location.String() + ": " + strings.TrimSpace(source), true
+	}
+
+	return location.String(), true
+}
+
+// Describes the address as "$8005 <done> main.s:7", the label and the location are included when known
+func (d *Debugger) DescribeAddress(address uint16) string {
+	description := fmt.Sprintf("$%04X", address)
+
+	if name, found := d.Name(address); found {
+		description += " <" + name + ">"
+	}
+
+	if d.DebugInfo != nil {
+		if location, found := d.DebugInfo.Location(address); found {
+			description += " " + location.String()
+		}
+	}
+
+	return description
+}
@@ -0,0 +1,156 @@
+package debugger
+
+import (
+	"testing"
+
+	"github.com/costamauricio/6502-emulator/pkg/bus"
+	"github.com/costamauricio/6502-emulator/pkg/cpu6502"
+	"github.com/costamauricio/6502-emulator/pkg/replay"
+)
+
+// LDX #$03; loop: DEX; BNE loop; BRK
+const countdown = "A2 03 CA D0 FD 00"
+
+func newDebugger(program string) *Debugger {
+	dataBus := &bus.Bus{}
+	dataBus.LoadRamFromString(program, 0x8000)
+	dataBus.LoadRamFromString("00 80", 0xFFFC)
+
+	cpu := cpu6502.New(dataBus)
+	debugger := New(cpu, dataBus)
+
+	// runs out the reset cicles
+	debugger.Step()
+	return debugger
+}
+
+func TestBreakpointIgnoreCount(t *testing.T) {
+	debugger := newDebugger(countdown)
+	breakpoint := debugger.Breakpoints.Add(Breakpoint{Address: 0x8002, IgnoreCount: 1})
+
+	stop := debugger.Continue(100)
+	if stop.Reason != STOP_BREAKPOINT || stop.Breakpoint != breakpoint {
+		t.Fatalf("expected breakpoint stop, got %s", stop.Reason)
+	}
+
+	if debugger.Cpu.X != 0x02 {
+		t.Errorf("expected to skip the first hit, X = %d", debugger.Cpu.X)
+	}
+}
+
+func TestBreakpointCondition(t *testing.T) {
+	debugger := newDebugger(countdown)
+	debugger.Breakpoints.Add(Breakpoint{
+		Address: 0x8002,
+		Condition: func(cpu *cpu6502.CPU, bus cpu6502.Bus) bool {
+			return cpu.X == 0x01
+		},
+	})
+
+	if stop := debugger.Continue(100); stop.Reason != STOP_BREAKPOINT || debugger.Cpu.X != 0x01 {
+		t.Errorf("expected to halt when X = 1, got %s with X = %d", stop.Reason, debugger.Cpu.X)
+	}
+}
+
+func TestTemporaryBreakpoint(t *testing.T) {
+	debugger := newDebugger(countdown)
+	debugger.Breakpoints.Add(Breakpoint{Address: 0x8002, Temporary: true})
+
+	if stop := debugger.Continue(100); stop.Reason != STOP_BREAKPOINT {
+		t.Fatalf("expected breakpoint stop, got %s", stop.Reason)
+	}
+
+	if len(debugger.Breakpoints.List()) != 0 {
+		t.Errorf("temporary breakpoint should be removed after the hit")
+	}
+
+	if stop := debugger.RunTo(0x8005); stop.Reason != STOP_BREAKPOINT || debugger.Cpu.X != 0x00 {
+		t.Errorf("expected to run to the end of the loop, got %s with X = %d", stop.Reason, debugger.Cpu.X)
+	}
+}
+
+func TestConditionExpression(t *testing.T) {
+	debugger := newDebugger(countdown)
+
+	if _, err := debugger.AddConditionalBreakpoint(0x8002, "X == 1 && !Z", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if stop := debugger.Continue(100); stop.Reason != STOP_BREAKPOINT || debugger.Cpu.X != 0x01 {
+		t.Errorf("expected to halt when X = 1, got %s with X = %d", stop.Reason, debugger.Cpu.X)
+	}
+
+	if _, err := debugger.AddConditionalBreakpoint(0x8002, "X ==", nil); err == nil {
+		t.Errorf("invalid conditions should fail")
+	}
+}
+
+func TestInterruptWhileIdle(t *testing.T) {
+	debugger := newDebugger(countdown)
+
+	debugger.Interrupt()
+	debugger.Step()
+
+	if stop := debugger.Continue(1); stop.Reason != STOP_STEP || debugger.Cpu.PC != 0x8003 {
+		t.Fatalf("expected the step executed, got %s at $%04X", stop.Reason, debugger.Cpu.PC)
+	}
+
+	// the interrupt halts the running Continue
+	done := make(chan Stop)
+	go func() { done <- debugger.Continue(0) }()
+
+	for {
+		debugger.Interrupt()
+
+		select {
+		case stop := <-done:
+			if stop.Reason != STOP_INTERRUPTED {
+				t.Fatalf("expected interrupted, got %s", stop.Reason)
+			}
+			return
+		default:
+		}
+	}
+}
+
+func TestStart(t *testing.T) {
+	debugger := newDebugger(countdown)
+	debugger.Step()
+
+	// the interrupt halts the loop of steps whenever it comes, even between two of them
+	steps := 0
+	stops := debugger.Start(func() Stop {
+		for {
+			stop := debugger.Continue(1)
+			if stop.Reason != STOP_STEP {
+				return stop
+			}
+
+			steps++
+		}
+	})
+	debugger.Interrupt()
+
+	if stop := <-stops; stop.Reason != STOP_INTERRUPTED {
+		t.Fatalf("expected interrupted, got %s after %d steps", stop.Reason, steps)
+	}
+
+	if stop := debugger.Continue(1); stop.Reason != STOP_STEP {
+		t.Errorf("expected the interrupt consumed, got %s", stop.Reason)
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	debugger := newDebugger(countdown)
+	debugger.Recorder = replay.NewRecorder(replay.Target{Cpu: debugger.Cpu, Bus: debugger.Bus})
+
+	debugger.Continue(2)
+	cycle := debugger.Cpu.Cycles()
+	debugger.NonMaskableInterrupt()
+	debugger.Reset()
+
+	events := debugger.Recorder.Events
+	if len(events) != 2 || events[0] != (replay.Event{Cycle: cycle, Kind: replay.EVENT_NMI}) || events[1].Kind != replay.EVENT_RESET {
+		t.Errorf("expected the NMI and the reset logged at cycle %d, got %v", cycle, events)
+	}
+}
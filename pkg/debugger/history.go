@@ -0,0 +1,247 @@
+package debugger
+
+import (
+	"errors"
+
+	"github.com/costamauricio/6502-emulator/pkg/cpu6502"
+)
+
+// Instructions kept by the history when no depth is given
+const DEFAULT_HISTORY_DEPTH = 100000
+
+// Memory write recorded to be undone
+type Write struct {
+	Address  uint16
+	Value    byte
+	Previous byte
+}
+
+// Executed step: the CPU state before it and the memory writes it did
+type Record struct {
+	State  cpu6502.State
+	Writes []Write
+}
+
+// Ring buffer with the last executed steps
+type History struct {
+	records []Record
+	next    int // Where the next record goes
+	count   int
+
+	recording *Record
+}
+
+func NewHistory(depth int) *History {
+	if depth <= 0 {
+		depth = DEFAULT_HISTORY_DEPTH
+	}
+
+	return &History{records: make([]Record, depth)}
+}
+
+// Maximum number of records kept
+func (h *History) Depth() int {
+	return len(h.records)
+}
+
+// Number of records available to step back
+func (h *History) Len() int {
+	return h.count
+}
+
+func (h *History) Clear() {
+	h.next, h.count = 0, 0
+	h.recording = nil
+}
+
+func (h *History) begin(state cpu6502.State) {
+	record := &h.records[h.next]
+	record.State = state
+	record.Writes = record.Writes[:0]
+
+	h.recording = record
+}
+
+func (h *History) write(address uint16, value byte, previous byte) {
+	if h.recording != nil {
+		h.recording.Writes = append(h.recording.Writes, Write{Address: address, Value: value, Previous: previous})
+	}
+}
+
+func (h *History) commit() {
+	if h.recording == nil {
+		return
+	}
+
+	h.recording = nil
+	h.next = (h.next + 1) % len(h.records)
+
+	if h.count < len(h.records) {
+		h.count++
+	}
+}
+
+// Most recent record without removing it
+func (h *History) Last() (Record, bool) {
+	if h.count == 0 {
+		return Record{}, false
+	}
+
+	return h.records[(h.next-1+len(h.records))%len(h.records)], true
+}
+
+func (h *History) pop() (Record, bool) {
+	record, found := h.Last()
+	if !found {
+		return Record{}, false
+	}
+
+	h.next = (h.next - 1 + len(h.records)) % len(h.records)
+	h.count--
+
+	return record, true
+}
+
+var ErrHistoryNeedsWatchBus = errors.New("the history needs the CPU bus wrapped by a WatchBus to record the memory writes")
+
+// Starts recording the executed instructions keeping the last depth ones, 0 uses DEFAULT_HISTORY_DEPTH
+// The memory writes are recorded through the WatchBus, so the debugger must have one
+func (d *Debugger) EnableHistory(depth int) error {
+	if d.Watch == nil {
+		return ErrHistoryNeedsWatchBus
+	}
+
+	d.History = NewHistory(depth)
+	d.Watch.OnWrite = d.History.write
+
+	return nil
+}
+
+func (d *Debugger) DisableHistory() {
+	if d.Watch != nil {
+		d.Watch.OnWrite = nil
+	}
+
+	d.History = nil
+}
+
+// Runs the change recording it as a step, so it can be undone, e.g. an interrupt
+func (d *Debugger) record(change func()) {
+	if d.History == nil {
+		change()
+		return
+	}
+
+	d.History.begin(d.Cpu.State())
+	change()
+	d.History.commit()
+}
+
+// Undoes the last recorded step restoring the CPU state and the memory it wrote
+// It returns false when there is no history left
+func (d *Debugger) StepBack() bool {
+	if d.History == nil {
+		return false
+	}
+
+	record, found := d.History.pop()
+	if !found {
+		return false
+	}
+
+	d.undo(record)
+	return true
+}
+
+func (d *Debugger) undo(record Record) {
+	for index := len(record.Writes) - 1; index >= 0; index-- {
+		d.Poke(record.Writes[index].Address, record.Writes[index].Previous)
+	}
+
+	d.Cpu.Restore(record.State)
+}
+
+// Steps back up to count instructions, halting on breakpoints and watchpoints like Continue
+// The watchpoints halt before the instruction that wrote the watched memory, only writes and changes are recorded
+// When count is 0 it runs back until something halts it or the history ends
+func (d *Debugger) ReverseContinue(count int) Stop {
+	d.begin()
+	defer d.end()
+
+	for executed := 0; count == 0 || executed < count; executed++ {
+		if d.interrupted.Swap(false) {
+			return Stop{Reason: STOP_INTERRUPTED}
+		}
+
+		if d.History == nil {
+			return Stop{Reason: STOP_HISTORY}
+		}
+
+		record, found := d.History.pop()
+		if !found {
+			return Stop{Reason: STOP_HISTORY}
+		}
+
+		d.undo(record)
+
+		// the steps that only run out the cicles of an instruction aren't instructions
+		if record.State.Cicles > 0 {
+			executed--
+			continue
+		}
+
+		if hits := d.reverseHits(record); len(hits) > 0 {
+			return Stop{Reason: STOP_WATCHPOINT, Hits: hits}
+		}
+
+		if breakpoint := d.Breakpoints.Match(d.Cpu, d.Bus); breakpoint != nil {
+			return Stop{Reason: STOP_BREAKPOINT, Breakpoint: breakpoint}
+		}
+	}
+
+	return Stop{Reason: STOP_STEP}
+}
+
+// Watchpoints triggered by the writes of the undone record
+func (d *Debugger) reverseHits(record Record) []Hit {
+	if d.Watch == nil {
+		return nil
+	}
+
+	var hits []Hit
+
+	for _, write := range record.Writes {
+		for _, watchpoint := range d.Watch.Watchpoints() {
+			if watchpoint.Log || write.Address < watchpoint.Start || write.Address > watchpoint.End {
+				continue
+			}
+
+			access := ACCESS_WRITE
+			if watchpoint.Access&ACCESS_WRITE == 0 {
+				if watchpoint.Access&ACCESS_CHANGE == 0 || write.Value == write.Previous {
+					continue
+				}
+
+				access = ACCESS_CHANGE
+			}
+
+			hit := Hit{
+				Watchpoint: watchpoint,
+				Access:     access,
+				Address:    write.Address,
+				Value:      write.Value,
+				Previous:   write.Previous,
+				PC:         d.Cpu.PC,
+			}
+			hit.Operation, _ = cpu6502.Decode(d.Peek, hit.PC)
+
+			if watchpoint.Condition != nil && !watchpoint.Condition(hit) {
+				continue
+			}
+
+			hits = append(hits, hit)
+		}
+	}
+
+	return hits
+}
@@ -0,0 +1,102 @@
+package debugger
+
+import (
+	"testing"
+
+	"github.com/costamauricio/6502-emulator/pkg/bus"
+	"github.com/costamauricio/6502-emulator/pkg/cpu6502"
+)
+
+// LDX #$00; loop: INX; STX $0200; CPX #$05; BNE loop; BRK
+const counter = "A2 00 E8 8E 00 02 E0 05 D0 F8 00"
+
+func newHistoryDebugger(t *testing.T) *Debugger {
+	dataBus := &bus.Bus{}
+	dataBus.LoadRamFromString(counter, 0x8000)
+	dataBus.LoadRamFromString("00 80", 0xFFFC)
+
+	watchBus := NewWatchBus(dataBus)
+	debugger := New(cpu6502.New(watchBus), watchBus)
+	debugger.Step()
+
+	if err := debugger.EnableHistory(100); err != nil {
+		t.Fatal(err)
+	}
+
+	return debugger
+}
+
+func TestStepBack(t *testing.T) {
+	debugger := newHistoryDebugger(t)
+	start := debugger.Cpu.State()
+
+	debugger.Continue(10)
+	end := debugger.Cpu.State()
+
+	if debugger.Peek(0x0200) != 0x02 || debugger.History.Len() != 10 {
+		t.Fatalf("unexpected run, $0200 = %d, %d recorded", debugger.Peek(0x0200), debugger.History.Len())
+	}
+
+	if !debugger.StepBack() || debugger.Cpu.PC != 0x8002 {
+		t.Errorf("expected to step back to $8002, PC = $%04X", debugger.Cpu.PC)
+	}
+
+	if stop := debugger.ReverseContinue(9); stop.Reason != STOP_STEP {
+		t.Errorf("expected step stop, got %s", stop.Reason)
+	}
+
+	if debugger.Cpu.State() != start || debugger.Peek(0x0200) != 0x00 {
+		t.Errorf("expected the initial state, got %+v, $0200 = %d", debugger.Cpu.State(), debugger.Peek(0x0200))
+	}
+
+	if stop := debugger.ReverseContinue(1); stop.Reason != STOP_HISTORY {
+		t.Errorf("expected history stop, got %s", stop.Reason)
+	}
+
+	// running forward again reaches the same state
+	debugger.Continue(10)
+	if debugger.Cpu.State() != end || debugger.Peek(0x0200) != 0x02 {
+		t.Errorf("expected the same state running again, got %+v", debugger.Cpu.State())
+	}
+}
+
+func TestReverseContinue(t *testing.T) {
+	debugger := newHistoryDebugger(t)
+	debugger.Continue(13)
+
+	watchpoint := debugger.Watch.Add(Watchpoint{Start: 0x0200, End: 0x0200, Access: ACCESS_WRITE})
+
+	// halts before the instruction that wrote the watched address
+	stop := debugger.ReverseContinue(0)
+	if stop.Reason != STOP_WATCHPOINT || debugger.Cpu.PC != 0x8003 {
+		t.Fatalf("expected watchpoint stop at $8003, got %s at $%04X", stop.Reason, debugger.Cpu.PC)
+	}
+
+	if hit := stop.Hits[0]; hit.Value != 0x03 || hit.Previous != 0x02 || debugger.Peek(0x0200) != 0x02 {
+		t.Errorf("unexpected hit %s", hit)
+	}
+
+	debugger.Watch.Remove(watchpoint.Id)
+	debugger.Breakpoints.Add(Breakpoint{Address: 0x8000})
+
+	stop = debugger.ReverseContinue(0)
+	if stop.Reason != STOP_BREAKPOINT || debugger.Cpu.X != 0x00 {
+		t.Errorf("expected breakpoint stop with X = 0, got %s with X = %d", stop.Reason, debugger.Cpu.X)
+	}
+}
+
+func TestReverseKeepsBreakpoints(t *testing.T) {
+	debugger := newHistoryDebugger(t)
+	debugger.Continue(13)
+
+	counted := debugger.Breakpoints.Add(Breakpoint{Address: 0x8006, IgnoreCount: 1})
+	temporary := debugger.Breakpoints.Add(Breakpoint{Address: 0x8006, Temporary: true})
+
+	if stop := debugger.ReverseContinue(0); stop.Reason != STOP_BREAKPOINT || debugger.Cpu.PC != 0x8006 {
+		t.Fatalf("expected breakpoint stop at $8006, got %s at $%04X", stop.Reason, debugger.Cpu.PC)
+	}
+
+	if counted.IgnoreCount != 1 || counted.Hits != 0 || debugger.Breakpoints.Get(temporary.Id) == nil {
+		t.Errorf("expected the breakpoints unchanged, got %s and %s", counted, temporary)
+	}
+}
@@ -0,0 +1,279 @@
+package debugger
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/costamauricio/6502-emulator/pkg/cpu6502"
+)
+
+type Access = byte
+
+const (
+	ACCESS_READ   Access = 1 << 0 // Memory read
+	ACCESS_WRITE  Access = 1 << 1 // Memory write, even when the value is the same
+	ACCESS_CHANGE Access = 1 << 2 // Memory write that changes the stored value
+)
+
+// Buses that can be read without side effects, like reading a device register
+type Peeker interface {
+	Peek(address uint16) byte
+}
+
+type Watchpoint struct {
+	Id     int
+	Start  uint16 // First address of the watched range
+	End    uint16 // Last address of the watched range
+	Access Access // Accesses that trigger the watchpoint, combined with |
+
+	// Optional condition evaluated for each matching access
+	Condition func(hit Hit) bool
+
+	// Description of the condition, used when listing the watchpoints
+	Expression string
+
+	// When true the hit is only reported to the OnHit callback and doesn't halt the execution
+	Log bool
+}
+
+func (watchpoint *Watchpoint) String() string {
+	var accesses []string
+
+	if watchpoint.Access&ACCESS_READ > 0 {
+		accesses = append(accesses, "read")
+	}
+
+	if watchpoint.Access&ACCESS_WRITE > 0 {
+		accesses = append(accesses, "write")
+	}
+
+	if watchpoint.Access&ACCESS_CHANGE > 0 {
+		accesses = append(accesses, "change")
+	}
+
+	description := fmt.Sprintf("watchpoint %d on %s of $%04X", watchpoint.Id, strings.Join(accesses, "/"), watchpoint.Start)
+
+	if watchpoint.End != watchpoint.Start {
+		description += fmt.Sprintf("-$%04X", watchpoint.End)
+	}
+
+	if watchpoint.Expression != "" {
+		description += " if " + watchpoint.Expression
+	}
+
+	if watchpoint.Log {
+		description += " (log)"
+	}
+
+	return description
+}
+
+// A memory access that triggered a watchpoint
+type Hit struct {
+	Watchpoint *Watchpoint
+	Access     Access
+	Address    uint16
+	Value      byte // Value read or written
+	Previous   byte // Value stored before a write
+
+	PC        uint16            // Address of the instruction that caused the access
+	Operation cpu6502.Operation // Instruction that caused the access
+}
+
+func (hit Hit) String() string {
+	return hit.Format(nil)
+}
+
+// Formats the hit naming the addresses with label, when it is nil or doesn't know the address it is rendered in hexadecimal
+func (hit Hit) Format(label func(uint16) (string, bool)) string {
+	var access string
+
+	switch hit.Access {
+	case ACCESS_READ:
+		access = fmt.Sprintf("read $%02X from", hit.Value)
+	case ACCESS_WRITE:
+		access = fmt.Sprintf("write $%02X to", hit.Value)
+	case ACCESS_CHANGE:
+		access = fmt.Sprintf("change $%02X -> $%02X at", hit.Previous, hit.Value)
+	}
+
+	address := func(address uint16) string {
+		if label != nil {
+			if name, found := label(address); found {
+				return name
+			}
+		}
+
+		return fmt.Sprintf("$%04X", address)
+	}
+
+	return fmt.Sprintf("watchpoint %d: %s %s by %s: %s", hit.Watchpoint.Id, access, address(hit.Address), address(hit.PC), hit.Operation.Format(label))
+}
+
+// Condition that matches when the accessed value is greater than value
+func ValueAbove(value byte) func(Hit) bool {
+	return func(hit Hit) bool {
+		return hit.Value > value
+	}
+}
+
+// Condition that matches when the accessed value is lower than value
+func ValueBelow(value byte) func(Hit) bool {
+	return func(hit Hit) bool {
+		return hit.Value < value
+	}
+}
+
+// Condition that matches when the accessed value is equal to value
+func ValueEquals(value byte) func(Hit) bool {
+	return func(hit Hit) bool {
+		return hit.Value == value
+	}
+}
+
+// Bus wrapper that checks every access against the watchpoints
+// It sits between the CPU and the real bus:
+//
+//	watchBus := debugger.NewWatchBus(&dataBus)
+//	cpu := cpu6502.New(watchBus)
+//	watchBus.Attach(cpu)
+type WatchBus struct {
+	// Called for every hit, including the ones from log only watchpoints
+	OnHit func(hit Hit)
+
+	// Called for every write by the CPU with the value it replaced, used to record the history
+	OnWrite func(address uint16, value byte, previous byte)
+
+	bus         cpu6502.Bus
+	cpu         *cpu6502.CPU
+	watchpoints []*Watchpoint
+	nextId      int
+	triggered   []Hit
+}
+
+func NewWatchBus(bus cpu6502.Bus) *WatchBus {
+	return &WatchBus{bus: bus, nextId: 1}
+}
+
+// Attach the CPU used to report which instruction caused the access
+func (w *WatchBus) Attach(cpu *cpu6502.CPU) {
+	w.cpu = cpu
+}
+
+// Wrapped bus
+func (w *WatchBus) Bus() cpu6502.Bus {
+	return w.bus
+}
+
+// Adds a new watchpoint, returning it with the assigned id
+func (w *WatchBus) Add(watchpoint Watchpoint) *Watchpoint {
+	if watchpoint.End < watchpoint.Start {
+		watchpoint.End = watchpoint.Start
+	}
+
+	watchpoint.Id = w.nextId
+	w.nextId++
+
+	w.watchpoints = append(w.watchpoints, &watchpoint)
+	return &watchpoint
+}
+
+// Removes the watchpoint, returning false when it doesn't exist
+func (w *WatchBus) Remove(id int) bool {
+	for index, watchpoint := range w.watchpoints {
+		if watchpoint.Id == id {
+			w.watchpoints = append(w.watchpoints[:index], w.watchpoints[index+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+func (w *WatchBus) Watchpoints() []*Watchpoint {
+	return w.watchpoints
+}
+
+// Returns the hits that should halt the execution since the last call
+func (w *WatchBus) Triggered() []Hit {
+	triggered := w.triggered
+	w.triggered = nil
+
+	return triggered
+}
+
+func (w *WatchBus) Read(address uint16) byte {
+	data := w.bus.Read(address)
+
+	if len(w.watchpoints) > 0 {
+		w.check(ACCESS_READ, address, data, data)
+	}
+
+	return data
+}
+
+func (w *WatchBus) Write(address uint16, data byte) {
+	if len(w.watchpoints) == 0 && w.OnWrite == nil {
+		w.bus.Write(address, data)
+		return
+	}
+
+	previous := w.Peek(address)
+	w.bus.Write(address, data)
+
+	if w.OnWrite != nil {
+		w.OnWrite(address, data, previous)
+	}
+
+	w.check(ACCESS_WRITE, address, data, previous)
+	if previous != data {
+		w.check(ACCESS_CHANGE, address, data, previous)
+	}
+}
+
+// Reads the memory without triggering watchpoints or device side effects
+func (w *WatchBus) Peek(address uint16) byte {
+	if peeker, ok := w.bus.(Peeker); ok {
+		return peeker.Peek(address)
+	}
+
+	return w.bus.Read(address)
+}
+
+// Writes the memory without triggering watchpoints
+func (w *WatchBus) Poke(address uint16, data byte) {
+	w.bus.Write(address, data)
+}
+
+func (w *WatchBus) check(access Access, address uint16, value byte, previous byte) {
+	for _, watchpoint := range w.watchpoints {
+		if watchpoint.Access&access == 0 || address < watchpoint.Start || address > watchpoint.End {
+			continue
+		}
+
+		hit := Hit{
+			Watchpoint: watchpoint,
+			Access:     access,
+			Address:    address,
+			Value:      value,
+			Previous:   previous,
+		}
+
+		if w.cpu != nil {
+			hit.PC = w.cpu.InstructionAddress()
+			hit.Operation, _ = cpu6502.Decode(w.Peek, hit.PC)
+		}
+
+		if watchpoint.Condition != nil && !watchpoint.Condition(hit) {
+			continue
+		}
+
+		if w.OnHit != nil {
+			w.OnHit(hit)
+		}
+
+		if !watchpoint.Log {
+			w.triggered = append(w.triggered, hit)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package debugger
+
+import (
+	"testing"
+
+	"github.com/costamauricio/6502-emulator/pkg/bus"
+	"github.com/costamauricio/6502-emulator/pkg/cpu6502"
+)
+
+func TestWatchpointConditionAndReport(t *testing.T) {
+	dataBus := bus.Bus{}
+	// LDA #$10; STA $0210; LDA #$80; STA $0220
+	dataBus.LoadRamFromString("A9 10 8D 10 02 A9 80 8D 20 02", 0x8000)
+	dataBus.LoadRamFromString("00 80", 0xFFFC)
+
+	watchBus := NewWatchBus(&dataBus)
+	cpu := cpu6502.New(watchBus)
+	watchBus.Attach(cpu)
+
+	watchBus.Add(Watchpoint{Start: 0x0200, End: 0x02FF, Access: ACCESS_WRITE, Condition: ValueAbove(0x7F)})
+
+	for cpu.PC < 0x800A {
+		cpu.Tick()
+	}
+
+	hits := watchBus.Triggered()
+	if len(hits) != 1 {
+		t.Fatalf("expected 1 hit, got %d", len(hits))
+	}
+
+	hit := hits[0]
+	if hit.Address != 0x0220 || hit.Value != 0x80 || hit.PC != 0x8007 {
+		t.Errorf("unexpected hit %s", hit)
+	}
+
+	if hit.Operation.String() != "STA $0220" {
+		t.Errorf("unexpected operation %q", hit.Operation)
+	}
+}
+
+func TestWatchpointChange(t *testing.T) {
+	dataBus := bus.Bus{}
+	watchBus := NewWatchBus(&dataBus)
+
+	var logged []Hit
+	watchBus.OnHit = func(hit Hit) {
+		logged = append(logged, hit)
+	}
+
+	watchBus.Add(Watchpoint{Start: 0x10, Access: ACCESS_CHANGE, Log: true})
+
+	watchBus.Write(0x10, 0x00)
+	watchBus.Write(0x10, 0x05)
+	watchBus.Write(0x10, 0x05)
+
+	if len(logged) != 1 || logged[0].Previous != 0x00 || logged[0].Value != 0x05 {
+		t.Errorf("unexpected hits %v", logged)
+	}
+
+	if len(watchBus.Triggered()) != 0 {
+		t.Errorf("log only watchpoints shouldn't halt")
+	}
+}
@@ -0,0 +1,216 @@
+package debuginfo
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Line types of the cc65 debug files
+const (
+	CA65_LINE_ASM   = 0 // Assembler source
+	CA65_LINE_C     = 1 // C source
+	CA65_LINE_MACRO = 2 // Macro expansion
+)
+
+type ca65Record map[string]string
+
+func (record ca65Record) int(key string) int {
+	value, err := strconv.ParseInt(record[key], 0, 64)
+	if err != nil {
+		return 0
+	}
+
+	return int(value)
+}
+
+func (record ca65Record) ids(key string) []int {
+	var ids []int
+
+	for _, id := range strings.Split(record[key], "+") {
+		if value, err := strconv.Atoi(id); err == nil {
+			ids = append(ids, value)
+		}
+	}
+
+	return ids
+}
+
+// Splits the "key=value,key="quoted, value"" fields of a record
+func parseCA65Fields(fields string) (ca65Record, error) {
+	record := make(ca65Record)
+
+	for len(fields) > 0 {
+		equal := strings.IndexByte(fields, '=')
+		if equal == -1 {
+			return nil, fmt.Errorf("invalid field %q", fields)
+		}
+
+		key := fields[:equal]
+		fields = fields[equal+1:]
+
+		var value string
+		if strings.HasPrefix(fields, "\"") {
+			end := strings.IndexByte(fields[1:], '"')
+			if end == -1 {
+				return nil, fmt.Errorf("unterminated string in field %s", key)
+			}
+
+			value = fields[1 : end+1]
+			fields = fields[end+2:]
+		} else {
+			end := strings.IndexByte(fields, ',')
+			if end == -1 {
+				end = len(fields)
+			}
+
+			value = fields[:end]
+			fields = fields[end:]
+		}
+
+		record[key] = value
+		fields = strings.TrimPrefix(fields, ",")
+	}
+
+	return record, nil
+}
+
+// Parses the debug file written by the cc65 linker (ld65 --dbgfile)
+func ParseCA65(reader io.Reader) (*Info, error) {
+	records := make(map[string]map[int]ca65Record)
+	var version bool
+
+	scanner := bufio.NewScanner(reader)
+	for number := 1; scanner.Scan(); number++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		kind, fields, _ := strings.Cut(line, "\t")
+		record, err := parseCA65Fields(strings.TrimSpace(fields))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", number, err)
+		}
+
+		if kind == "version" {
+			if record.int("major") != 2 {
+				return nil, fmt.Errorf("unsupported debug file version %s", record["major"])
+			}
+
+			version = true
+			continue
+		}
+
+		if records[kind] == nil {
+			records[kind] = make(map[int]ca65Record)
+		}
+
+		records[kind][record.int("id")] = record
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if !version {
+		return nil, fmt.Errorf("not a cc65 debug file")
+	}
+
+	// address ranges of the spans
+	type span struct {
+		start uint16
+		size  int
+	}
+
+	spans := make(map[int]span)
+	for id, record := range records["span"] {
+		segment, found := records["seg"][record.int("seg")]
+		if !found {
+			continue
+		}
+
+		spans[id] = span{start: uint16(segment.int("start") + record.int("start")), size: record.int("size")}
+	}
+
+	info := &Info{}
+
+	for _, record := range records["line"] {
+		file, found := records["file"][record.int("file")]
+		if !found || record.int("type") == CA65_LINE_MACRO {
+			continue
+		}
+
+		for _, id := range record.ids("span") {
+			if span, found := spans[id]; found {
+				info.Lines = append(info.Lines, LineRange{File: file["name"], Line: record.int("line"), Start: span.start, Size: span.size})
+			}
+		}
+	}
+
+	scopeName := func(id string) string {
+		if id == "" {
+			return ""
+		}
+
+		value, _ := strconv.Atoi(id)
+		return records["scope"][value]["name"]
+	}
+
+	for _, record := range records["sym"] {
+		if record["type"] == "imp" || record["val"] == "" {
+			continue
+		}
+
+		// cheap local labels are ignored
+		if strings.HasPrefix(record["name"], "@") {
+			continue
+		}
+
+		info.Symbols = append(info.Symbols, Symbol{
+			Name:    record["name"],
+			Address: uint16(record.int("val")),
+			Size:    record.int("size"),
+			Scope:   scopeName(record["scope"]),
+		})
+	}
+
+	for _, record := range records["scope"] {
+		if record["name"] == "" {
+			continue
+		}
+
+		first, last := -1, -1
+		for _, id := range record.ids("span") {
+			span, found := spans[id]
+			if !found {
+				continue
+			}
+
+			if first == -1 || int(span.start) < first {
+				first = int(span.start)
+			}
+
+			if end := int(span.start) + span.size - 1; end > last {
+				last = end
+			}
+		}
+
+		if first == -1 || last < first {
+			continue
+		}
+
+		info.Scopes = append(info.Scopes, Scope{
+			Name:   record["name"],
+			Start:  uint16(first),
+			End:    uint16(last),
+			Parent: scopeName(record["parent"]),
+		})
+	}
+
+	info.sort()
+
+	return info, nil
+}
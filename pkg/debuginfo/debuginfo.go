@@ -0,0 +1,299 @@
+// Debug information mapping addresses back to source files, lines, symbols and scopes
+//
+// It can be loaded from the cc65 debug files (ld65 --dbgfile) or from the JSON format:
+//
+//	{
+//	  "lines":   [{"file": "main.s", "line": 12, "start": 32768, "size": 2}],
+//	  "symbols": [{"name": "reset", "address": 32768, "scope": "main"}],
+//	  "scopes":  [{"name": "main", "start": 32768, "end": 32800, "parent": ""}]
+//	}
+//
+// Relative file paths are resolved from the debug file directory
+package debuginfo
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+type Location struct {
+	File string
+	Line int
+}
+
+func (location Location) String() string {
+	return fmt.Sprintf("%s:%d", filepath.Base(location.File), location.Line)
+}
+
+// Range of addresses generated by a source line
+type LineRange struct {
+	File  string `json:"file"`
+	Line  int    `json:"line"`
+	Start uint16 `json:"start"`
+	Size  int    `json:"size"`
+}
+
+func (line LineRange) contains(address uint16) bool {
+	return address >= line.Start && int(address) < int(line.Start)+line.Size
+}
+
+type Symbol struct {
+	Name    string `json:"name"`
+	Address uint16 `json:"address"`
+	Size    int    `json:"size,omitempty"`
+	Scope   string `json:"scope,omitempty"`
+}
+
+// Lexical scope like a procedure or a .scope block
+type Scope struct {
+	Name   string `json:"name"`
+	Start  uint16 `json:"start"`
+	End    uint16 `json:"end"` // Last address of the scope
+	Parent string `json:"parent,omitempty"`
+}
+
+type Info struct {
+	Lines   []LineRange `json:"lines"`
+	Symbols []Symbol    `json:"symbols"`
+	Scopes  []Scope     `json:"scopes"`
+
+	// Directory the relative file paths are resolved from
+	Directory string `json:"-"`
+
+	lock    sync.Mutex
+	sources map[string][]string
+}
+
+// Loads the debug file detecting its format
+func Load(path string) (*Info, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var info *Info
+
+	if trimmed := bytes.TrimSpace(content); len(trimmed) > 0 && trimmed[0] == '{' {
+		info = &Info{}
+		if err := json.Unmarshal(content, info); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	} else if info, err = ParseCA65(bytes.NewReader(content)); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	info.Directory = filepath.Dir(path)
+	info.sort()
+
+	return info, nil
+}
+
+// Saves the debug information in the JSON format
+func (info *Info) Save(path string) error {
+	content, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, content, 0644)
+}
+
+func (info *Info) sort() {
+	sort.SliceStable(info.Lines, func(i, j int) bool {
+		if info.Lines[i].Start != info.Lines[j].Start {
+			return info.Lines[i].Start < info.Lines[j].Start
+		}
+
+		return info.Lines[i].Line < info.Lines[j].Line
+	})
+
+	sort.SliceStable(info.Symbols, func(i, j int) bool {
+		if info.Symbols[i].Address != info.Symbols[j].Address {
+			return info.Symbols[i].Address < info.Symbols[j].Address
+		}
+
+		return info.Symbols[i].Name < info.Symbols[j].Name
+	})
+
+	sort.SliceStable(info.Scopes, func(i, j int) bool {
+		if info.Scopes[i].Start != info.Scopes[j].Start {
+			return info.Scopes[i].Start < info.Scopes[j].Start
+		}
+
+		return info.Scopes[i].Name < info.Scopes[j].Name
+	})
+}
+
+// Source location of the code at the address
+func (info *Info) Location(address uint16) (Location, bool) {
+	// the lines are sorted by address, the innermost match wins
+	var found *LineRange
+
+	for index := range info.Lines {
+		line := &info.Lines[index]
+		if line.Start > address {
+			break
+		}
+
+		if line.contains(address) && (found == nil || line.Size <= found.Size) {
+			found = line
+		}
+	}
+
+	if found == nil {
+		return Location{}, false
+	}
+
+	return Location{File: info.path(found.File), Line: found.Line}, true
+}
+
+// First address of each range generated by the source line
+// The file matches by the full path or the path suffix, e.g. "main.s" matches "src/main.s"
+func (info *Info) Addresses(file string, line int) []uint16 {
+	var addresses []uint16
+
+	for _, lineRange := range info.Lines {
+		if lineRange.Line == line && lineRange.Size > 0 && sameFile(info.path(lineRange.File), file) {
+			addresses = append(addresses, lineRange.Start)
+		}
+	}
+
+	return addresses
+}
+
+// Finds the address of the first source line at or after the line, used to place breakpoints on lines
+// that don't generate code, returns the line found
+func (info *Info) NearestLine(file string, line int) (uint16, int, bool) {
+	best := -1
+	var address uint16
+
+	for _, lineRange := range info.Lines {
+		if lineRange.Line < line || lineRange.Size == 0 || !sameFile(info.path(lineRange.File), file) {
+			continue
+		}
+
+		if best == -1 || lineRange.Line < best || lineRange.Line == best && lineRange.Start < address {
+			best, address = lineRange.Line, lineRange.Start
+		}
+	}
+
+	return address, best, best != -1
+}
+
+func sameFile(path string, file string) bool {
+	path, file = filepath.ToSlash(filepath.Clean(path)), filepath.ToSlash(filepath.Clean(file))
+
+	return path == file || strings.HasSuffix(path, "/"+file) || strings.HasSuffix(file, "/"+path)
+}
+
+func (info *Info) path(file string) string {
+	if filepath.IsAbs(file) || info.Directory == "" {
+		return file
+	}
+
+	return filepath.Join(info.Directory, file)
+}
+
+// Address of the symbol, it implements expr.Symbols
+func (info *Info) Lookup(name string) (uint16, bool) {
+	for _, symbol := range info.Symbols {
+		if symbol.Name == name {
+			return symbol.Address, true
+		}
+	}
+
+	return 0, false
+}
+
+// Name of the symbol at the address
+func (info *Info) Label(address uint16) (string, bool) {
+	index := sort.Search(len(info.Symbols), func(index int) bool {
+		return info.Symbols[index].Address >= address
+	})
+
+	if index < len(info.Symbols) && info.Symbols[index].Address == address {
+		return info.Symbols[index].Name, true
+	}
+
+	return "", false
+}
+
+// Innermost scope containing the address
+func (info *Info) Scope(address uint16) (Scope, bool) {
+	var found *Scope
+
+	for index := range info.Scopes {
+		scope := &info.Scopes[index]
+		if address < scope.Start || address > scope.End {
+			continue
+		}
+
+		if found == nil || scope.End-scope.Start < found.End-found.Start {
+			found = scope
+		}
+	}
+
+	if found == nil {
+		return Scope{}, false
+	}
+
+	return *found, true
+}
+
+// Text of the source line, the files are read once and cached
+func (info *Info) Source(location Location) (string, bool) {
+	info.lock.Lock()
+	defer info.lock.Unlock()
+
+	if info.sources == nil {
+		info.sources = make(map[string][]string)
+	}
+
+	lines, loaded := info.sources[location.File]
+	if !loaded {
+		if file, err := os.Open(location.File); err == nil {
+			scanner := bufio.NewScanner(file)
+			for scanner.Scan() {
+				lines = append(lines, scanner.Text())
+			}
+
+			file.Close()
+		}
+
+		info.sources[location.File] = lines
+	}
+
+	if location.Line < 1 || location.Line > len(lines) {
+		return "", false
+	}
+
+	return lines[location.Line-1], true
+}
+
+// Describes the address as "label: file:line: source" with the parts available
+func (info *Info) Describe(address uint16) string {
+	var parts []string
+
+	if label, found := info.Label(address); found {
+		parts = append(parts, label+":")
+	}
+
+	if location, found := info.Location(address); found {
+		description := location.String()
+
+		if source, found := info.Source(location); found {
+			description += ": " + strings.TrimSpace(source)
+		}
+
+		parts = append(parts, description)
+	}
+
+	return strings.Join(parts, " ")
+}
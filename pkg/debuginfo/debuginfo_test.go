@@ -0,0 +1,105 @@
+package debuginfo
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const ca65DebugFile = `version	major=2,minor=0
+info	csym=0,file=1,lib=0,line=4,mod=1,scope=2,seg=1,span=5,sym=3,type=0
+file	id=0,name="main.s",size=120,mtime=0x00000000,mod=0
+line	id=0,file=0,line=3,span=0
+line	id=1,file=0,line=4,span=1
+line	id=2,file=0,line=7,span=2
+line	id=3,file=0,line=8,type=2,span=3
+mod	id=0,name="main.o",file=0
+seg	id=0,name="CODE",start=0x008000,size=0x0007,addrsize=absolute,type=ro,oname="main.bin",ooffs=0
+span	id=0,seg=0,start=0,size=2
+span	id=1,seg=0,start=2,size=3
+span	id=2,seg=0,start=5,size=1
+span	id=3,seg=0,start=6,size=1
+span	id=4,seg=0,start=0,size=7
+scope	id=0,name="",mod=0,size=7,span=4
+scope	id=1,name="done",mod=0,type=scope,size=2,parent=0,span=2+3
+sym	id=0,name="reset",addrsize=absolute,scope=0,def=1,ref=2,val=0x8000,seg=0,type=lab
+sym	id=1,name="done",addrsize=absolute,scope=0,def=5,val=0x8005,seg=0,type=lab
+sym	id=2,name="@loop",addrsize=absolute,scope=0,def=6,val=0x8002,seg=0,type=lab
+sym	id=3,name="putc",addrsize=absolute,scope=0,def=0,type=imp
+`
+
+func TestParseCA65(t *testing.T) {
+	info, err := ParseCA65(strings.NewReader(ca65DebugFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if location, found := info.Location(0x8003); !found || location.Line != 4 || location.File != "main.s" {
+		t.Errorf("expected main.s:4 at $8003, got %v %v", location, found)
+	}
+
+	if _, found := info.Location(0x8006); found {
+		t.Errorf("macro lines should be ignored")
+	}
+
+	if addresses := info.Addresses("main.s", 7); len(addresses) != 1 || addresses[0] != 0x8005 {
+		t.Errorf("expected line 7 at $8005, got %v", addresses)
+	}
+
+	if address, line, found := info.NearestLine("main.s", 5); !found || address != 0x8005 || line != 7 {
+		t.Errorf("expected line 5 to resolve to line 7, got $%04X %d %v", address, line, found)
+	}
+
+	if address, found := info.Lookup("done"); !found || address != 0x8005 {
+		t.Errorf("expected done at $8005, got $%04X", address)
+	}
+
+	if label, found := info.Label(0x8000); !found || label != "reset" {
+		t.Errorf("expected reset label, got %q", label)
+	}
+
+	if _, found := info.Lookup("putc"); found {
+		t.Errorf("imported symbols should be ignored")
+	}
+
+	if _, found := info.Label(0x8002); found {
+		t.Errorf("cheap local labels should be ignored")
+	}
+
+	if scope, found := info.Scope(0x8006); !found || scope.Name != "done" || scope.Start != 0x8005 || scope.End != 0x8006 {
+		t.Errorf("expected done scope, got %+v", scope)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	directory := t.TempDir()
+
+	source := "; countdown\nreset:\n  LDX #$03\nloop:\n  DEX\n"
+	if err := os.WriteFile(filepath.Join(directory, "main.s"), []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	info := &Info{
+		Lines:   []LineRange{{File: "main.s", Line: 3, Start: 0x8000, Size: 2}, {File: "main.s", Line: 5, Start: 0x8002, Size: 1}},
+		Symbols: []Symbol{{Name: "reset", Address: 0x8000}, {Name: "loop", Address: 0x8002}},
+	}
+
+	path := filepath.Join(directory, "main.json")
+	if err := info.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if description := loaded.Describe(0x8002); description != "loop: main.s:5: DEX" {
+		t.Errorf("unexpected description %q", description)
+	}
+
+	if addresses := loaded.Addresses(filepath.Join(directory, "main.s"), 3); len(addresses) != 1 || addresses[0] != 0x8000 {
+		t.Errorf("expected line 3 at $8000, got %v", addresses)
+	}
+}
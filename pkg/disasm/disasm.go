@@ -0,0 +1,267 @@
+// Recursive-descent disassembler separating code from data
+//
+// The code is traced from the entry points (usually the reset, NMI and IRQ vectors) following the
+// JMP, JSR and branch targets, so only reachable bytes are decoded as instructions and the rest is
+// kept as data. The referenced addresses get generated labels and the result can be written as
+// ca65 source that assembles back to the same bytes.
+package disasm
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/costamauricio/6502-emulator/pkg/cpu6502"
+)
+
+type Kind byte
+
+const (
+	KIND_DATA    Kind = iota // Not reached by the code tracing
+	KIND_CODE                // First byte of an instruction
+	KIND_OPERAND             // Operand bytes of an instruction
+)
+
+// Vector addresses traced by AddVectors
+const (
+	VECTOR_NMI   uint16 = 0xFFFA
+	VECTOR_RESET uint16 = 0xFFFC
+	VECTOR_IRQ   uint16 = 0xFFFE
+)
+
+type reference byte
+
+const (
+	REFERENCE_DATA reference = 1 << iota
+	REFERENCE_JUMP
+	REFERENCE_CALL
+)
+
+type Disassembler struct {
+	Read  func(uint16) byte
+	Start uint16 // First address of the disassembled range
+	End   uint16 // Last address of the disassembled range
+
+	// Optional, names the addresses instead of the generated labels, e.g. debugger.Label
+	Symbols func(uint16) (string, bool)
+
+	kinds      []Kind
+	operations map[uint16]cpu6502.Operation
+	references map[uint16]reference
+	entries    map[uint16]string
+	words      map[uint16]bool // Data words, like the vectors
+}
+
+func New(read func(uint16) byte, start uint16, end uint16) *Disassembler {
+	return &Disassembler{
+		Read:       read,
+		Start:      start,
+		End:        end,
+		kinds:      make([]Kind, int(end)-int(start)+1),
+		operations: make(map[uint16]cpu6502.Operation),
+		references: make(map[uint16]reference),
+		entries:    make(map[uint16]string),
+		words:      make(map[uint16]bool),
+	}
+}
+
+func (d *Disassembler) contains(address uint16) bool {
+	return address >= d.Start && address <= d.End
+}
+
+// Kind of the byte at the address, the addresses out of the range are data
+func (d *Disassembler) Kind(address uint16) Kind {
+	if !d.contains(address) {
+		return KIND_DATA
+	}
+
+	return d.kinds[address-d.Start]
+}
+
+// Traces the code from the reset, NMI and IRQ vectors
+func (d *Disassembler) AddVectors() {
+	for _, vector := range []struct {
+		address uint16
+		name    string
+	}{{VECTOR_NMI, "nmi"}, {VECTOR_RESET, "reset"}, {VECTOR_IRQ, "irq"}} {
+		if d.contains(vector.address) && d.contains(vector.address+1) {
+			d.words[vector.address] = true
+		}
+
+		d.AddEntry(uint16(d.Read(vector.address+1))<<8|uint16(d.Read(vector.address)), vector.name)
+	}
+}
+
+// Traces the code from the entry point, name is optional and labels the entry
+// An address already named keeps the first name
+func (d *Disassembler) AddEntry(address uint16, name string) {
+	if _, found := d.entries[address]; !found || d.entries[address] == "" {
+		d.entries[address] = name
+	}
+
+	d.references[address] |= REFERENCE_JUMP
+	d.trace(address)
+}
+
+// Whether the address is the first byte of a traced instruction
+func (d *Disassembler) IsCode(address uint16) bool {
+	return d.Kind(address) == KIND_CODE
+}
+
+// Traced instruction at the address
+func (d *Disassembler) Operation(address uint16) (cpu6502.Operation, bool) {
+	operation, found := d.operations[address]
+	return operation, found
+}
+
+// Addresses of the traced instructions in order
+func (d *Disassembler) Code() []uint16 {
+	addresses := make([]uint16, 0, len(d.operations))
+	for address := range d.operations {
+		addresses = append(addresses, address)
+	}
+
+	sort.Slice(addresses, func(i, j int) bool {
+		return addresses[i] < addresses[j]
+	})
+
+	return addresses
+}
+
+func (d *Disassembler) trace(address uint16) {
+	pending := []uint16{address}
+
+	for len(pending) > 0 {
+		address := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+
+		for d.contains(address) && d.Kind(address) == KIND_DATA {
+			operation, found := cpu6502.Decode(d.Read, address)
+			if !found || int(address)+int(operation.Size)-1 > int(d.End) {
+				break
+			}
+
+			// an instruction overlapping another one means this path isn't code
+			overlaps := false
+			for offset := uint16(1); offset < operation.Size; offset++ {
+				overlaps = overlaps || d.Kind(address+offset) != KIND_DATA
+			}
+
+			if overlaps {
+				break
+			}
+
+			d.kinds[address-d.Start] = KIND_CODE
+			for offset := uint16(1); offset < operation.Size; offset++ {
+				d.kinds[address+offset-d.Start] = KIND_OPERAND
+			}
+
+			d.operations[address] = operation
+
+			continues := true
+
+			switch operation.Instruction {
+			case cpu6502.INS_JMP:
+				if operation.AddressMode == cpu6502.MODE_ABS {
+					d.references[operation.Operand] |= REFERENCE_JUMP
+					pending = append(pending, operation.Operand)
+				} else {
+					d.references[operation.Operand] |= REFERENCE_DATA
+				}
+
+				continues = false
+			case cpu6502.INS_JSR:
+				d.references[operation.Operand] |= REFERENCE_CALL
+				pending = append(pending, operation.Operand)
+			case cpu6502.INS_RTS, cpu6502.INS_RTI, cpu6502.INS_BRK:
+				continues = false
+			default:
+				switch operation.AddressMode {
+				case cpu6502.MODE_REL:
+					d.references[operation.Operand] |= REFERENCE_JUMP
+					pending = append(pending, operation.Operand)
+				case cpu6502.MODE_IMP, cpu6502.MODE_ACC, cpu6502.MODE_IMM:
+				default:
+					d.references[operation.Operand] |= REFERENCE_DATA
+				}
+			}
+
+			if !continues || int(address)+int(operation.Size) > 0xFFFF {
+				break
+			}
+
+			address += operation.Size
+		}
+	}
+}
+
+// Start of the instruction the operand byte belongs to
+func (d *Disassembler) instructionAt(address uint16) uint16 {
+	for address > d.Start && d.Kind(address) == KIND_OPERAND {
+		address--
+	}
+
+	return address
+}
+
+// Label of the address, the symbols and entry names are preferred over the generated ones:
+// Sxxxx for subroutines, Lxxxx for other code and Dxxxx for data
+func (d *Disassembler) Label(address uint16) (string, bool) {
+	// the operand bytes don't start a line, they are named relative to the instruction
+	if d.Kind(address) == KIND_OPERAND {
+		return "", false
+	}
+
+	if d.Symbols != nil {
+		if name, found := d.Symbols(address); found {
+			return name, true
+		}
+	}
+
+	if !d.contains(address) {
+		return "", false
+	}
+
+	if name := d.entries[address]; name != "" {
+		return name, true
+	}
+
+	switch d.Kind(address) {
+	case KIND_CODE:
+		// referenced by itself or by an address inside its operand
+		var kind reference
+		for offset := uint16(0); offset < d.operations[address].Size; offset++ {
+			kind |= d.references[address+offset]
+		}
+
+		if kind&REFERENCE_CALL > 0 {
+			return fmt.Sprintf("S%04X", address), true
+		}
+
+		if kind != 0 {
+			return fmt.Sprintf("L%04X", address), true
+		}
+	case KIND_DATA:
+		if d.references[address] != 0 {
+			return fmt.Sprintf("D%04X", address), true
+		}
+	}
+
+	return "", false
+}
+
+// Names the address for the operands: its label, relative to the instruction when it points
+// inside an operand, e.g. "L8003+1", or the symbol for the addresses out of the range
+func (d *Disassembler) Name(address uint16) (string, bool) {
+	if label, found := d.Label(address); found {
+		return label, true
+	}
+
+	if d.Kind(address) == KIND_OPERAND {
+		start := d.instructionAt(address)
+		if label, found := d.Label(start); found {
+			return fmt.Sprintf("%s+%d", label, address-start), true
+		}
+	}
+
+	return "", false
+}
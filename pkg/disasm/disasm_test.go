@@ -0,0 +1,83 @@
+package disasm
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/costamauricio/6502-emulator/pkg/bus"
+)
+
+func TestDisassemble(t *testing.T) {
+	memory := &bus.Bus{}
+	memory.LoadRamFromString("A2 00 BD 12 80 F0 08 20 16 80 E8 4C 02 80 EA 4C 0F 80 48 49 00 FF 8D 01 00 60", 0x8000)
+
+	disassembler := New(memory.Read, 0x8000, 0x801F)
+	disassembler.Symbols = func(address uint16) (string, bool) {
+		return "PORT", address == 0x0001
+	}
+	disassembler.AddEntry(0x8000, "start")
+
+	if !disassembler.IsCode(0x800F) || disassembler.IsCode(0x800E) || disassembler.IsCode(0x8012) {
+		t.Errorf("code and data weren't separated")
+	}
+
+	var source bytes.Buffer
+	if err := disassembler.WriteSource(&source); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"PORT = $0001",
+		"\t.org $8000",
+		"start:\n\tLDX #$00",
+		"L8002:\n\tLDA D8012,X\n\tBEQ L800F\n\tJSR S8016\n\tINX\n\tJMP L8002",
+		"\t.byte $EA",
+		"L800F:\n\tJMP L800F",
+		"D8012:\n\t.byte $48, $49, $00, $FF",
+		"S8016:\n\tSTA a:PORT\n\tRTS",
+		"\t.byte $00, $00, $00, $00, $00, $00\n",
+	}
+
+	for _, line := range expected {
+		if !strings.Contains(source.String(), line) {
+			t.Errorf("expected %q in the source:\n%s", line, source.String())
+		}
+	}
+
+	// the lines cover the whole range
+	var size int
+	for _, line := range disassembler.Lines() {
+		size += int(line.Size)
+	}
+
+	if size != 0x20 {
+		t.Errorf("expected the lines to cover 32 bytes, got %d", size)
+	}
+}
+
+func TestVectors(t *testing.T) {
+	memory := &bus.Bus{}
+	// reset: LDA $FFFD; JMP reset; irq: RTI
+	memory.LoadRamFromString("AD FD FF 4C F0 FF 40", 0xFFF0)
+	memory.LoadRamFromString("F6 FF F0 FF F6 FF", 0xFFFA)
+
+	disassembler := New(memory.Read, 0xFFF0, 0xFFFF)
+	disassembler.AddVectors()
+
+	var source bytes.Buffer
+	disassembler.WriteSource(&source)
+
+	// the reset vector high byte is referenced, so the words are split around the label
+	if !strings.Contains(source.String(), "reset:\n\tLDA DFFFD\n\tJMP reset") {
+		t.Errorf("unexpected reset code:\n%s", source.String())
+	}
+
+	if !strings.Contains(source.String(), "nmi:\n\tRTI") || !strings.Contains(source.String(), "\t.word nmi\n") {
+		t.Errorf("unexpected vectors:\n%s", source.String())
+	}
+
+	if !strings.Contains(source.String(), "DFFFD:\n\t.byte $FF\n\t.word nmi\n") {
+		t.Errorf("expected the referenced vector byte to be labeled:\n%s", source.String())
+	}
+}
@@ -0,0 +1,180 @@
+package disasm
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/costamauricio/6502-emulator/pkg/cpu6502"
+)
+
+// Maximum bytes of a .byte line
+const BYTES_PER_LINE = 8
+
+// Line of the disassembly, an instruction or a data directive
+type Line struct {
+	Address uint16
+	Kind    Kind   // KIND_CODE or KIND_DATA
+	Label   string // Empty when the address isn't labeled
+	Text    string // Instruction or data directive, e.g. "LDA D9000,X" or ".byte $01, $02"
+	Size    uint16
+}
+
+// Lines of the disassembled range in address order
+func (d *Disassembler) Lines() []Line {
+	var lines []Line
+
+	for address := uint(d.Start); address <= uint(d.End); {
+		line := d.line(uint16(address))
+		lines = append(lines, line)
+		address += uint(line.Size)
+	}
+
+	return lines
+}
+
+func (d *Disassembler) line(address uint16) Line {
+	line := Line{Address: address, Kind: d.Kind(address)}
+	line.Label, _ = d.Label(address)
+
+	if operation, found := d.operations[address]; found {
+		line.Text = d.Format(operation)
+		line.Size = operation.Size
+		return line
+	}
+
+	line.Kind = KIND_DATA
+
+	// vectors and other words are kept together when nothing points between their bytes
+	if d.words[address] && address < d.End && d.Kind(address+1) == KIND_DATA {
+		if _, labeled := d.Label(address + 1); !labeled {
+			value := uint16(d.Read(address+1))<<8 | uint16(d.Read(address))
+
+			name, found := d.Name(value)
+			if !found {
+				name = fmt.Sprintf("$%04X", value)
+			}
+
+			line.Text = ".word " + name
+			line.Size = 2
+			return line
+		}
+	}
+
+	var values []string
+	for current := uint(address); current <= uint(d.End) && len(values) < BYTES_PER_LINE; current++ {
+		if current != uint(address) {
+			if _, labeled := d.Label(uint16(current)); labeled || d.words[uint16(current)] {
+				break
+			}
+		}
+
+		if d.Kind(uint16(current)) != KIND_DATA {
+			break
+		}
+
+		values = append(values, fmt.Sprintf("$%02X", d.Read(uint16(current))))
+	}
+
+	line.Text = ".byte " + strings.Join(values, ", ")
+	line.Size = uint16(len(values))
+
+	return line
+}
+
+// Formats the instruction naming the operand, absolute operands in the zero page are
+// prefixed with "a:" so the assembler keeps the absolute addressing
+func (d *Disassembler) Format(operation cpu6502.Operation) string {
+	operand := operation.FormatOperand(d.Name)
+
+	switch operation.AddressMode {
+	case cpu6502.MODE_ABS, cpu6502.MODE_ABX, cpu6502.MODE_ABY:
+		if operation.Operand < 0x100 && operation.Instruction != cpu6502.INS_JMP && operation.Instruction != cpu6502.INS_JSR {
+			operand = " a:" + operand[1:]
+		}
+	}
+
+	return string(operation.Instruction) + operand
+}
+
+// Symbols used by the instructions out of the range, they are written as assignments
+func (d *Disassembler) equates() map[string]uint16 {
+	equates := make(map[string]uint16)
+
+	add := func(address uint16) {
+		if d.contains(address) || d.Symbols == nil {
+			return
+		}
+
+		if name, found := d.Symbols(address); found {
+			equates[name] = address
+		}
+	}
+
+	for _, operation := range d.operations {
+		switch operation.AddressMode {
+		case cpu6502.MODE_IMP, cpu6502.MODE_ACC, cpu6502.MODE_IMM:
+		default:
+			add(operation.Operand)
+		}
+	}
+
+	for address := range d.words {
+		if d.contains(address) && d.contains(address+1) {
+			add(uint16(d.Read(address+1))<<8 | uint16(d.Read(address)))
+		}
+	}
+
+	return equates
+}
+
+// Writes the disassembly as ca65 source
+func (d *Disassembler) WriteSource(writer io.Writer) error {
+	output := bufio.NewWriter(writer)
+
+	fmt.Fprintf(output, "; Disassembly of $%04X-$%04X\n\n", d.Start, d.End)
+	fmt.Fprintf(output, "\t.setcpu \"6502\"\n\n")
+
+	equates := d.equates()
+	if len(equates) > 0 {
+		var names []string
+		for name := range equates {
+			names = append(names, name)
+		}
+
+		sort.Slice(names, func(i, j int) bool {
+			if equates[names[i]] != equates[names[j]] {
+				return equates[names[i]] < equates[names[j]]
+			}
+
+			return names[i] < names[j]
+		})
+
+		for _, name := range names {
+			fmt.Fprintf(output, "%s = $%04X\n", name, equates[name])
+		}
+
+		fmt.Fprintln(output)
+	}
+
+	fmt.Fprintf(output, "\t.org $%04X\n\n", d.Start)
+
+	previous := KIND_CODE
+	for _, line := range d.Lines() {
+		// a blank line between code and data blocks
+		if line.Kind != previous {
+			fmt.Fprintln(output)
+			previous = line.Kind
+		}
+
+		if line.Label != "" {
+			fmt.Fprintf(output, "%s:\n", line.Label)
+		}
+
+		fmt.Fprintf(output, "\t%s\n", line.Text)
+	}
+
+	return output.Flush()
+}
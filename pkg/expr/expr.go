@@ -0,0 +1,323 @@
+// Small expression language used by the debugger conditions and commands
+//
+// Supported terms:
+//
+//	$0200, 0x200, %1010, 512   numbers in hexadecimal, binary or decimal
+//	A X Y S PC P               registers
+//	C Z I D B U V N            status flags, evaluate to 1 when set
+//	[address]                  byte in memory
+//	{address}                  little endian word in memory
+//	name                       symbols and variables
+//
+// Operators, from the highest to the lowest precedence:
+//
+//	unary - ~ ! < (low byte) > (high byte)
+//	* / %
+//	+ -
+//	<< >>
+//	< <= > >=
+//	== !=
+//	&
+//	^
+//	|
+//	&&
+//	||
+package expr
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/costamauricio/6502-emulator/pkg/cpu6502"
+)
+
+// Symbol tables used to resolve names
+type Symbols interface {
+	Lookup(name string) (uint16, bool)
+}
+
+// State an expression is evaluated against
+type Env struct {
+	Cpu     *cpu6502.CPU
+	Bus     cpu6502.Bus
+	Symbols Symbols // Optional
+
+	// Optional values resolved before the symbols, e.g. the value of a watched access
+	Variables map[string]int
+}
+
+type Expr interface {
+	Eval(env *Env) (int, error)
+	String() string
+}
+
+// Parses and evaluates the expression
+func Eval(source string, env *Env) (int, error) {
+	expression, err := Parse(source)
+	if err != nil {
+		return 0, err
+	}
+
+	return expression.Eval(env)
+}
+
+// Parses and evaluates the expression as a condition, non zero values are true
+func Test(source string, env *Env) (bool, error) {
+	value, err := Eval(source, env)
+	return value != 0, err
+}
+
+// Evaluates the expression as an address
+func Address(expression Expr, env *Env) (uint16, error) {
+	value, err := expression.Eval(env)
+	if err != nil {
+		return 0, err
+	}
+
+	if value < 0 || value > 0xFFFF {
+		return 0, fmt.Errorf("address out of range: %s = %d", expression, value)
+	}
+
+	return uint16(value), nil
+}
+
+func (env *Env) read(address uint16) (byte, error) {
+	if env.Bus == nil {
+		return 0, fmt.Errorf("no memory to read $%04X from", address)
+	}
+
+	// avoids triggering watchpoints and device side effects while evaluating
+	if peeker, ok := env.Bus.(interface{ Peek(uint16) byte }); ok {
+		return peeker.Peek(address), nil
+	}
+
+	return env.Bus.Read(address), nil
+}
+
+var registers = map[string]func(cpu *cpu6502.CPU) int{
+	"A":  func(cpu *cpu6502.CPU) int { return int(cpu.A) },
+	"X":  func(cpu *cpu6502.CPU) int { return int(cpu.X) },
+	"Y":  func(cpu *cpu6502.CPU) int { return int(cpu.Y) },
+	"S":  func(cpu *cpu6502.CPU) int { return int(cpu.S) },
+	"SP": func(cpu *cpu6502.CPU) int { return int(cpu.S) },
+	"PC": func(cpu *cpu6502.CPU) int { return int(cpu.PC) },
+	"P":  func(cpu *cpu6502.CPU) int { return int(cpu.Status) },
+}
+
+var flags = map[string]cpu6502.Flag{
+	"C": cpu6502.FLAG_C,
+	"Z": cpu6502.FLAG_Z,
+	"I": cpu6502.FLAG_I,
+	"D": cpu6502.FLAG_D,
+	"B": cpu6502.FLAG_B,
+	"U": cpu6502.FLAG_U,
+	"V": cpu6502.FLAG_V,
+	"N": cpu6502.FLAG_N,
+}
+
+// Verifies if the name is a register or flag, which can't be used as symbols
+func IsRegister(name string) bool {
+	name = strings.ToUpper(name)
+
+	_, register := registers[name]
+	_, flag := flags[name]
+
+	return register || flag
+}
+
+type number int
+
+func (n number) Eval(env *Env) (int, error) {
+	return int(n), nil
+}
+
+func (n number) String() string {
+	return fmt.Sprintf("$%X", int(n))
+}
+
+type name string
+
+func (n name) Eval(env *Env) (int, error) {
+	upper := strings.ToUpper(string(n))
+
+	if register, found := registers[upper]; found {
+		if env.Cpu == nil {
+			return 0, fmt.Errorf("no CPU to read %s from", upper)
+		}
+
+		return register(env.Cpu), nil
+	}
+
+	if flag, found := flags[upper]; found {
+		if env.Cpu == nil {
+			return 0, fmt.Errorf("no CPU to read %s from", upper)
+		}
+
+		if env.Cpu.GetFlag(flag) > 0 {
+			return 1, nil
+		}
+
+		return 0, nil
+	}
+
+	if value, found := env.Variables[string(n)]; found {
+		return value, nil
+	}
+
+	if env.Symbols != nil {
+		if address, found := env.Symbols.Lookup(string(n)); found {
+			return int(address), nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown symbol: %s", string(n))
+}
+
+func (n name) String() string {
+	return string(n)
+}
+
+type memory struct {
+	address Expr
+	word    bool
+}
+
+func (m memory) Eval(env *Env) (int, error) {
+	address, err := Address(m.address, env)
+	if err != nil {
+		return 0, err
+	}
+
+	low, err := env.read(address)
+	if err != nil || !m.word {
+		return int(low), err
+	}
+
+	high, err := env.read(address + 1)
+	return int(high)<<8 | int(low), err
+}
+
+func (m memory) String() string {
+	if m.word {
+		return "{" + m.address.String() + "}"
+	}
+
+	return "[" + m.address.String() + "]"
+}
+
+type unary struct {
+	operator string
+	operand  Expr
+}
+
+func (u unary) Eval(env *Env) (int, error) {
+	value, err := u.operand.Eval(env)
+	if err != nil {
+		return 0, err
+	}
+
+	switch u.operator {
+	case "-":
+		return -value, nil
+	case "~":
+		return ^value, nil
+	case "!":
+		return boolean(value == 0), nil
+	case "<":
+		return value & 0xFF, nil
+	case ">":
+		return (value >> 8) & 0xFF, nil
+	}
+
+	return 0, fmt.Errorf("unknown operator: %s", u.operator)
+}
+
+func (u unary) String() string {
+	return u.operator + u.operand.String()
+}
+
+type binary struct {
+	operator    string
+	left, right Expr
+}
+
+func (b binary) Eval(env *Env) (int, error) {
+	left, err := b.left.Eval(env)
+	if err != nil {
+		return 0, err
+	}
+
+	// short circuit so conditions like "X < 4 && [table + X] == 0" don't read needlessly
+	switch b.operator {
+	case "&&":
+		if left == 0 {
+			return 0, nil
+		}
+	case "||":
+		if left != 0 {
+			return 1, nil
+		}
+	}
+
+	right, err := b.right.Eval(env)
+	if err != nil {
+		return 0, err
+	}
+
+	switch b.operator {
+	case "*":
+		return left * right, nil
+	case "/", "%":
+		if right == 0 {
+			return 0, fmt.Errorf("division by zero: %s", b)
+		}
+
+		if b.operator == "/" {
+			return left / right, nil
+		}
+
+		return left % right, nil
+	case "+":
+		return left + right, nil
+	case "-":
+		return left - right, nil
+	case "<<":
+		return left << uint(right), nil
+	case ">>":
+		return left >> uint(right), nil
+	case "<":
+		return boolean(left < right), nil
+	case "<=":
+		return boolean(left <= right), nil
+	case ">":
+		return boolean(left > right), nil
+	case ">=":
+		return boolean(left >= right), nil
+	case "==":
+		return boolean(left == right), nil
+	case "!=":
+		return boolean(left != right), nil
+	case "&":
+		return left & right, nil
+	case "^":
+		return left ^ right, nil
+	case "|":
+		return left | right, nil
+	case "&&", "||":
+		return boolean(right != 0), nil
+	}
+
+	return 0, fmt.Errorf("unknown operator: %s", b.operator)
+}
+
+func (b binary) String() string {
+	return "(" + b.left.String() + " " + b.operator + " " + b.right.String() + ")"
+}
+
+func boolean(value bool) int {
+	if value {
+		return 1
+	}
+
+	return 0
+}
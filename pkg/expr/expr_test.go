@@ -0,0 +1,68 @@
+package expr
+
+import (
+	"testing"
+
+	"github.com/costamauricio/6502-emulator/pkg/bus"
+	"github.com/costamauricio/6502-emulator/pkg/cpu6502"
+)
+
+type symbols map[string]uint16
+
+func (s symbols) Lookup(name string) (uint16, bool) {
+	address, found := s[name]
+	return address, found
+}
+
+func TestEval(t *testing.T) {
+	dataBus := &bus.Bus{}
+	dataBus.LoadRamFromString("34 12", 0x0200)
+
+	cpu := cpu6502.New(dataBus)
+	cpu.A = 0x10
+	cpu.X = 0x01
+	cpu.SetFlag(cpu6502.FLAG_C, true)
+
+	env := &Env{Cpu: cpu, Bus: dataBus, Symbols: symbols{"buffer": 0x0200}}
+
+	tests := map[string]int{
+		"A == $10 && C":          1,
+		"A == $10 && z":          0,
+		"[$0200]":                0x34,
+		"{buffer}":               0x1234,
+		"[buffer + X]":           0x12,
+		"1 + 2 * 3":              7,
+		"(1 + 2) * 3":            9,
+		"%1010 | 0x05":           0x0F,
+		"7 % 4":                  3,
+		"<$1234 + >$1234":        0x34 + 0x12,
+		"~0 & $FF":               0xFF,
+		"-1 < 0":                 1,
+		"1 << 4 >= 16":           1,
+		"!C || [$0201] != $12":   0,
+		"PC == $0000 || P & $01": 1,
+		"A ^ $FF":                0xEF,
+	}
+
+	for source, expected := range tests {
+		value, err := Eval(source, env)
+		if err != nil {
+			t.Errorf("%s: %s", source, err)
+			continue
+		}
+
+		if value != expected {
+			t.Errorf("%s = %d, expected %d", source, value, expected)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	env := &Env{Cpu: cpu6502.New(&bus.Bus{})}
+
+	for _, source := range []string{"1 +", "(1", "[1", "unknown", "1 / 0", "1 # 2", ""} {
+		if _, err := Eval(source, env); err == nil {
+			t.Errorf("%q should fail", source)
+		}
+	}
+}
@@ -0,0 +1,294 @@
+package expr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Binary operators grouped by precedence, from the lowest to the highest
+var precedence = [][]string{
+	{"||"},
+	{"&&"},
+	{"|"},
+	{"^"},
+	{"&"},
+	{"==", "!="},
+	{"<", "<=", ">", ">="},
+	{"<<", ">>"},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+// Operators sorted so the longest ones are matched first
+var operators = []string{
+	"||", "&&", "==", "!=", "<=", ">=", "<<", ">>",
+	"|", "^", "&", "<", ">", "+", "-", "*", "/", "%", "~", "!",
+	"(", ")", "[", "]", "{", "}",
+}
+
+type token struct {
+	kind     int
+	text     string
+	value    int
+	position int
+}
+
+const (
+	tokenEnd = iota
+	tokenNumber
+	tokenName
+	tokenOperator
+)
+
+type parser struct {
+	source string
+	tokens []token
+	index  int
+}
+
+// Parses the expression source
+func Parse(source string) (Expr, error) {
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{source: source, tokens: tokens}
+
+	expression, err := p.parseBinary(0)
+	if err != nil {
+		return nil, err
+	}
+
+	if current := p.current(); current.kind != tokenEnd {
+		return nil, p.errorAt(current, "unexpected %q", current.text)
+	}
+
+	return expression, nil
+}
+
+func tokenize(source string) ([]token, error) {
+	var tokens []token
+
+	for position := 0; position < len(source); {
+		char := rune(source[position])
+
+		switch {
+		case unicode.IsSpace(char):
+			position++
+		case char == '$' || char == '%' && position+1 < len(source) && isBinary(source[position+1]) && !afterOperand(tokens):
+			base, digits := 16, isHex
+			if char == '%' {
+				base, digits = 2, isBinary
+			}
+
+			end := position + 1
+			for end < len(source) && digits(source[end]) {
+				end++
+			}
+
+			value, err := strconv.ParseInt(source[position+1:end], base, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q at %d", source[position:end], position)
+			}
+
+			tokens = append(tokens, token{kind: tokenNumber, text: source[position:end], value: int(value), position: position})
+			position = end
+		case unicode.IsDigit(char):
+			end := position
+			for end < len(source) && (isHex(source[end]) || source[end] == 'x' || source[end] == 'X') {
+				end++
+			}
+
+			text, base := source[position:end], 10
+			if strings.HasPrefix(strings.ToLower(text), "0x") {
+				text, base = text[2:], 16
+			}
+
+			value, err := strconv.ParseInt(text, base, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q at %d", source[position:end], position)
+			}
+
+			tokens = append(tokens, token{kind: tokenNumber, text: source[position:end], value: int(value), position: position})
+			position = end
+		case isNameStart(char):
+			end := position
+			for end < len(source) && isNamePart(rune(source[end])) {
+				end++
+			}
+
+			tokens = append(tokens, token{kind: tokenName, text: source[position:end], position: position})
+			position = end
+		default:
+			found := false
+			for _, operator := range operators {
+				if strings.HasPrefix(source[position:], operator) {
+					tokens = append(tokens, token{kind: tokenOperator, text: operator, position: position})
+					position += len(operator)
+					found = true
+					break
+				}
+			}
+
+			if !found {
+				return nil, fmt.Errorf("unexpected %q at %d", string(char), position)
+			}
+		}
+	}
+
+	return append(tokens, token{kind: tokenEnd, position: len(source)}), nil
+}
+
+// % is the modulo operator after an operand and a binary number otherwise
+func afterOperand(tokens []token) bool {
+	if len(tokens) == 0 {
+		return false
+	}
+
+	last := tokens[len(tokens)-1]
+	if last.kind == tokenOperator {
+		return last.text == ")" || last.text == "]" || last.text == "}"
+	}
+
+	return true
+}
+
+func isHex(char byte) bool {
+	return char >= '0' && char <= '9' || char >= 'a' && char <= 'f' || char >= 'A' && char <= 'F'
+}
+
+func isBinary(char byte) bool {
+	return char == '0' || char == '1'
+}
+
+func isNameStart(char rune) bool {
+	return unicode.IsLetter(char) || char == '_' || char == '.' || char == '@'
+}
+
+func isNamePart(char rune) bool {
+	return isNameStart(char) || unicode.IsDigit(char) || char == ':'
+}
+
+func (p *parser) current() token {
+	return p.tokens[p.index]
+}
+
+func (p *parser) next() token {
+	current := p.tokens[p.index]
+	if current.kind != tokenEnd {
+		p.index++
+	}
+
+	return current
+}
+
+func (p *parser) isOperator(operators ...string) bool {
+	current := p.current()
+	if current.kind != tokenOperator {
+		return false
+	}
+
+	for _, operator := range operators {
+		if current.text == operator {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *parser) expect(operator string) error {
+	if !p.isOperator(operator) {
+		current := p.current()
+		if current.kind == tokenEnd {
+			return p.errorAt(current, "expected %q", operator)
+		}
+
+		return p.errorAt(current, "expected %q, found %q", operator, current.text)
+	}
+
+	p.next()
+	return nil
+}
+
+func (p *parser) errorAt(at token, format string, args ...interface{}) error {
+	return fmt.Errorf("%s at %d in %q", fmt.Sprintf(format, args...), at.position, p.source)
+}
+
+func (p *parser) parseBinary(level int) (Expr, error) {
+	if level == len(precedence) {
+		return p.parseUnary()
+	}
+
+	left, err := p.parseBinary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+
+	for p.isOperator(precedence[level]...) {
+		operator := p.next().text
+
+		right, err := p.parseBinary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+
+		left = binary{operator: operator, left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseUnary() (Expr, error) {
+	if p.isOperator("-", "~", "!", "<", ">") {
+		operator := p.next().text
+
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		return unary{operator: operator, operand: operand}, nil
+	}
+
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (Expr, error) {
+	current := p.next()
+
+	switch current.kind {
+	case tokenNumber:
+		return number(current.value), nil
+	case tokenName:
+		return name(current.text), nil
+	case tokenEnd:
+		return nil, p.errorAt(current, "unexpected end of expression")
+	}
+
+	closing := map[string]string{"(": ")", "[": "]", "{": "}"}[current.text]
+	if closing == "" {
+		return nil, p.errorAt(current, "unexpected %q", current.text)
+	}
+
+	inner, err := p.parseBinary(0)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.expect(closing); err != nil {
+		return nil, err
+	}
+
+	switch current.text {
+	case "[":
+		return memory{address: inner}, nil
+	case "{":
+		return memory{address: inner, word: true}, nil
+	}
+
+	return inner, nil
+}
@@ -0,0 +1,186 @@
+// Memory-mapped pixel display
+//
+// A byte per pixel, row after row, indexing a palette of 256 colors. The default palette repeats
+// the 16 colors of easy6502 and 6502asm, so their 32x32 screen at $0200 works as is:
+//
+//	screen := framebuffer.New(32, 32)
+//	dataBus.Map(0x0200, 0x05FF, screen)
+//	dataBus.Map(0xD000, 0xD003, screen.Registers())
+//	screen.WritePNG(file, 1)
+//
+// The palette is changed from the program on the registers: the index of the color, then its red,
+// green and blue levels.
+package framebuffer
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+)
+
+const PALETTE_SIZE = 256
+
+// Palette registers
+const (
+	REG_INDEX = 0x0 // Color changed by the levels, incremented after the blue one
+	REG_RED   = 0x1
+	REG_GREEN = 0x2
+	REG_BLUE  = 0x3
+	REGISTERS = 0x4
+)
+
+// Colors of easy6502
+var COLORS = [16]color.RGBA{
+	{0x00, 0x00, 0x00, 0xFF}, // Black
+	{0xFF, 0xFF, 0xFF, 0xFF}, // White
+	{0x88, 0x00, 0x00, 0xFF}, // Red
+	{0xAA, 0xFF, 0xEE, 0xFF}, // Cyan
+	{0xCC, 0x44, 0xCC, 0xFF}, // Purple
+	{0x00, 0xCC, 0x55, 0xFF}, // Green
+	{0x00, 0x00, 0xAA, 0xFF}, // Blue
+	{0xEE, 0xEE, 0x77, 0xFF}, // Yellow
+	{0xDD, 0x88, 0x55, 0xFF}, // Orange
+	{0x66, 0x44, 0x00, 0xFF}, // Brown
+	{0xFF, 0x77, 0x77, 0xFF}, // Light red
+	{0x33, 0x33, 0x33, 0xFF}, // Dark grey
+	{0x77, 0x77, 0x77, 0xFF}, // Grey
+	{0xAA, 0xFF, 0x66, 0xFF}, // Light green
+	{0x00, 0x88, 0xFF, 0xFF}, // Light blue
+	{0xBB, 0xBB, 0xBB, 0xFF}, // Light grey
+}
+
+type Framebuffer struct {
+	Width   int
+	Height  int
+	Pixels  []byte // Palette index of each pixel
+	Palette [PALETTE_SIZE]color.RGBA
+
+	index byte // Color selected on the registers
+}
+
+func New(width int, height int) *Framebuffer {
+	screen := &Framebuffer{Width: width, Height: height, Pixels: make([]byte, width*height)}
+	screen.ResetPalette()
+
+	return screen
+}
+
+// Sets the easy6502 colors over the whole palette
+func (f *Framebuffer) ResetPalette() {
+	for index := range f.Palette {
+		f.Palette[index] = COLORS[index%len(COLORS)]
+	}
+}
+
+// Size of the pixels on the bus
+func (f *Framebuffer) Size() int {
+	return len(f.Pixels)
+}
+
+func (f *Framebuffer) Read(register uint16) byte {
+	if int(register) >= len(f.Pixels) {
+		return 0
+	}
+
+	return f.Pixels[register]
+}
+
+func (f *Framebuffer) Write(register uint16, data byte) {
+	if int(register) < len(f.Pixels) {
+		f.Pixels[register] = data
+	}
+}
+
+// Color of the pixel
+func (f *Framebuffer) At(x int, y int) color.RGBA {
+	return f.Palette[f.Pixels[y*f.Width+x]]
+}
+
+// Picture of the screen
+func (f *Framebuffer) Image() *image.RGBA {
+	picture := image.NewRGBA(image.Rect(0, 0, f.Width, f.Height))
+
+	for y := 0; y < f.Height; y++ {
+		for x := 0; x < f.Width; x++ {
+			picture.SetRGBA(x, y, f.At(x, y))
+		}
+	}
+
+	return picture
+}
+
+// Writes the screen as a PNG, scaled by the integer factor
+func (f *Framebuffer) WritePNG(w io.Writer, scale int) error {
+	if scale < 1 {
+		scale = 1
+	}
+
+	picture := image.NewRGBA(image.Rect(0, 0, f.Width*scale, f.Height*scale))
+	for y := 0; y < f.Height*scale; y++ {
+		for x := 0; x < f.Width*scale; x++ {
+			picture.SetRGBA(x, y, f.At(x/scale, y/scale))
+		}
+	}
+
+	return png.Encode(w, picture)
+}
+
+// Pixels that differ between the pictures, all of them when the sizes differ
+// The golden image tests compare the screen with a stored PNG
+func Diff(expected image.Image, actual image.Image) int {
+	bounds := expected.Bounds()
+	if bounds.Size() != actual.Bounds().Size() {
+		return bounds.Dx() * bounds.Dy()
+	}
+
+	offset := actual.Bounds().Min.Sub(bounds.Min)
+	differences := 0
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r1, g1, b1, a1 := expected.At(x, y).RGBA()
+			r2, g2, b2, a2 := actual.At(x+offset.X, y+offset.Y).RGBA()
+
+			if r1 != r2 || g1 != g2 || b1 != b2 || a1 != a2 {
+				differences++
+			}
+		}
+	}
+
+	return differences
+}
+
+// Palette registers, mapped apart from the pixels
+func (f *Framebuffer) Registers() *Registers {
+	return (*Registers)(f)
+}
+
+type Registers Framebuffer
+
+func (r *Registers) Read(register uint16) byte {
+	switch register % REGISTERS {
+	case REG_RED:
+		return r.Palette[r.index].R
+	case REG_GREEN:
+		return r.Palette[r.index].G
+	case REG_BLUE:
+		return r.Palette[r.index].B
+	}
+
+	return r.index
+}
+
+func (r *Registers) Write(register uint16, data byte) {
+	switch register % REGISTERS {
+	case REG_INDEX:
+		r.index = data
+	case REG_RED:
+		r.Palette[r.index].R = data
+	case REG_GREEN:
+		r.Palette[r.index].G = data
+	case REG_BLUE:
+		r.Palette[r.index].B = data
+		r.index++
+	}
+}
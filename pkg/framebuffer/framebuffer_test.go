@@ -0,0 +1,141 @@
+package framebuffer
+
+import (
+	"bytes"
+	"flag"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/costamauricio/6502-emulator/pkg/bus"
+	"github.com/costamauricio/6502-emulator/pkg/cpu6502"
+)
+
+var update = flag.Bool("update", false, "Rewrites the golden images")
+
+// Stripes of the 16 colors and the black changed to dark blue on the palette registers
+var stripes = []byte{
+	0xA2, 0x00, // $0600 LDX #$00
+	0x8A,             // $0602 TXA
+	0x9D, 0x00, 0x02, // $0603 STA $0200,X
+	0x4A,             // $0606 LSR
+	0x9D, 0x00, 0x03, // $0607 STA $0300,X
+	0x4A,             // $060A LSR
+	0x9D, 0x00, 0x04, // $060B STA $0400,X
+	0x4A,             // $060E LSR
+	0x9D, 0x00, 0x05, // $060F STA $0500,X
+	0xE8,       // $0612 INX
+	0xD0, 0xED, // $0613 BNE $0602
+	0xA9, 0x00, 0x8D, 0x00, 0xD0, // LDA #$00 STA $D000
+	0xA9, 0x10, 0x8D, 0x01, 0xD0, // LDA #$10 STA $D001
+	0xA9, 0x20, 0x8D, 0x02, 0xD0, // LDA #$20 STA $D002
+	0xA9, 0x40, 0x8D, 0x03, 0xD0, // LDA #$40 STA $D003
+	0x00, // BRK
+}
+
+// Runs the program at $0600 until BRK with the screen at $0200
+func run(t *testing.T, program []byte) *Framebuffer {
+	screen := New(32, 32)
+
+	dataBus := &bus.Bus{}
+	dataBus.Map(0x0200, 0x05FF, screen)
+	dataBus.Map(0xD000, 0xD000+REGISTERS-1, screen.Registers())
+	dataBus.LoadRam(program, 0x0600)
+	dataBus.LoadRam([]byte{0x00, 0x06}, 0xFFFC)
+
+	cpu := cpu6502.New(dataBus)
+	cpu.Reset()
+
+	for ticks := 0; ticks < 100000; ticks++ {
+		if cpu.InstructionCompleted() && dataBus.Read(cpu.PC) == 0x00 {
+			return screen
+		}
+
+		cpu.Tick()
+	}
+
+	t.Fatal("the program didn't reach BRK")
+	return nil
+}
+
+// Compares the screen with the PNG of testdata, -update writes it
+func golden(t *testing.T, screen *Framebuffer, name string) {
+	path := filepath.Join("testdata", name)
+
+	var encoded bytes.Buffer
+	if err := screen.WritePNG(&encoded, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if *update {
+		if err := os.WriteFile(path, encoded.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	expected, err := png.Decode(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if differences := Diff(expected, screen.Image()); differences > 0 {
+		t.Errorf("%d pixels differ from %s", differences, path)
+	}
+}
+
+func TestStripes(t *testing.T) {
+	screen := run(t, stripes)
+
+	if pixel := screen.At(0, 0); pixel != (color.RGBA{0x10, 0x20, 0x40, 0xFF}) {
+		t.Errorf("expected the black changed to dark blue, got %v", pixel)
+	}
+
+	if pixel := screen.At(1, 0); pixel != COLORS[1] {
+		t.Errorf("expected white, got %v", pixel)
+	}
+
+	if pixel := screen.At(31, 7); pixel != COLORS[0xF] {
+		t.Errorf("expected the color index masked to light grey, got %v", pixel)
+	}
+
+	if index := screen.Registers().Read(REG_INDEX); index != 1 {
+		t.Errorf("expected the palette index moved on, got %d", index)
+	}
+
+	golden(t, screen, "stripes.png")
+}
+
+func TestWritePNG(t *testing.T) {
+	screen := New(4, 2)
+	screen.Write(5, 2)
+
+	var encoded bytes.Buffer
+	if err := screen.WritePNG(&encoded, 3); err != nil {
+		t.Fatal(err)
+	}
+
+	picture, err := png.Decode(&encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if size := picture.Bounds().Size(); size.X != 12 || size.Y != 6 {
+		t.Fatalf("expected the picture scaled to 12x6, got %v", size)
+	}
+
+	if r, _, _, _ := picture.At(5, 5).RGBA(); r>>8 != 0x88 {
+		t.Errorf("expected the red pixel scaled, got %v", picture.At(5, 5))
+	}
+
+	if differences := Diff(screen.Image(), picture); differences != 8 {
+		t.Errorf("expected all the pixels different with other sizes, got %d", differences)
+	}
+}
@@ -0,0 +1,384 @@
+// GDB Remote Serial Protocol server exposing the emulator to standard debugger front ends
+//
+// Registers, in the order used by the g/G packets:
+//
+//	0 a   8 bits
+//	1 x   8 bits
+//	2 y   8 bits
+//	3 sp  8 bits, stack pointer at page 1
+//	4 pc  16 bits little endian
+//	5 p   8 bits, status flags
+package gdbstub
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/costamauricio/6502-emulator/pkg/debugger"
+)
+
+const targetXML = `<?xml version="1.0"?>
+<!DOCTYPE target SYSTEM "gdb-target.dtd">
+<target version="1.0">
+  <feature name="org.6502.cpu">
+    <reg name="a" bitsize="8" type="uint8" regnum="0"/>
+    <reg name="x" bitsize="8" type="uint8" regnum="1"/>
+    <reg name="y" bitsize="8" type="uint8" regnum="2"/>
+    <reg name="sp" bitsize="8" type="uint8" regnum="3"/>
+    <reg name="pc" bitsize="16" type="code_ptr" regnum="4"/>
+    <flags id="status_flags" size="1">
+      <field name="C" start="0" end="0"/>
+      <field name="Z" start="1" end="1"/>
+      <field name="I" start="2" end="2"/>
+      <field name="D" start="3" end="3"/>
+      <field name="B" start="4" end="4"/>
+      <field name="U" start="5" end="5"/>
+      <field name="V" start="6" end="6"/>
+      <field name="N" start="7" end="7"/>
+    </flags>
+    <reg name="p" bitsize="8" type="status_flags" regnum="5"/>
+  </feature>
+</target>
+`
+
+const (
+	SIGINT  = 0x02
+	SIGTRAP = 0x05
+)
+
+// Byte sent by the client to halt the running program
+const interruptByte = 0x03
+
+type Server struct {
+	Debugger *debugger.Debugger
+	Logger   *log.Logger // Optional, logs the exchanged packets
+
+	breakpoints map[uint16]int // Breakpoint ids by address
+	watchpoints map[string]int // Watchpoint ids by "type,address,length"
+}
+
+func New(dbg *debugger.Debugger) *Server {
+	return &Server{
+		Debugger:    dbg,
+		breakpoints: make(map[uint16]int),
+		watchpoints: make(map[string]int),
+	}
+}
+
+// Listens on the TCP address, e.g. "localhost:1234", serving one client at a time
+func (s *Server) ListenAndServe(address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+
+	return s.Serve(listener)
+}
+
+func (s *Server) Serve(listener net.Listener) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+
+		if err := s.ServeConn(conn); err != nil && s.Logger != nil {
+			s.Logger.Print("connection closed: ", err)
+		}
+
+		conn.Close()
+	}
+}
+
+// Serves a single client until it detaches or the connection closes
+func (s *Server) ServeConn(conn io.ReadWriter) error {
+	session := &session{
+		server:  s,
+		writer:  bufio.NewWriter(conn),
+		packets: make(chan string),
+		errors:  make(chan error, 1),
+		closed:  make(chan struct{}),
+		ack:     true,
+	}
+	defer close(session.closed)
+
+	go session.read(bufio.NewReader(conn))
+
+	return session.run()
+}
+
+type session struct {
+	server  *Server
+	writer  *bufio.Writer
+	packets chan string // Received packets, interrupts are sent as a single 0x03
+	errors  chan error
+	closed  chan struct{}
+	ack     bool // Acknowledgments are disabled with QStartNoAckMode
+	last    string
+	stop    debugger.Stop
+	done    bool
+}
+
+var errDetached = errors.New("detached")
+
+func (s *session) read(reader *bufio.Reader) {
+	deliver := func(packet string) bool {
+		select {
+		case s.packets <- packet:
+			return true
+		case <-s.closed:
+			return false
+		}
+	}
+
+	for {
+		char, err := reader.ReadByte()
+		if err != nil {
+			s.errors <- err
+			return
+		}
+
+		var packet string
+
+		switch char {
+		case interruptByte:
+			packet = string(rune(interruptByte))
+		case '-':
+			packet = "-"
+		case '$':
+			data, err := reader.ReadString('#')
+			if err != nil {
+				s.errors <- err
+				return
+			}
+
+			checksum := make([]byte, 2)
+			if _, err := io.ReadFull(reader, checksum); err != nil {
+				s.errors <- err
+				return
+			}
+
+			data = data[:len(data)-1]
+			packet = "$" + data
+
+			// asks the client to resend corrupted packets
+			if expected, err := strconv.ParseUint(string(checksum), 16, 8); err != nil || byte(expected) != sum(data) {
+				packet = "!"
+			}
+		default:
+			// acknowledgments
+			continue
+		}
+
+		if !deliver(packet) {
+			return
+		}
+	}
+}
+
+func (s *session) run() error {
+	for {
+		select {
+		case err := <-s.errors:
+			return err
+		case packet := <-s.packets:
+			if err := s.received(packet); err != nil {
+				if err == errDetached {
+					return nil
+				}
+
+				return err
+			}
+		}
+	}
+}
+
+func (s *session) received(packet string) error {
+	switch {
+	case packet == "!":
+		return s.write("-")
+	case packet == "-":
+		// the client asks to resend the last packet
+		if s.last != "" {
+			return s.send(s.last)
+		}
+
+		return nil
+	case packet == string(rune(interruptByte)):
+		// nothing is running, reports the current state
+		return s.send(fmt.Sprintf("S%02X", SIGINT))
+	}
+
+	data := packet[1:]
+
+	if s.ack {
+		if err := s.write("+"); err != nil {
+			return err
+		}
+	}
+
+	if s.server.Logger != nil {
+		s.server.Logger.Print("<- ", data)
+	}
+
+	reply, resume := s.handle(data)
+
+	if resume != nil {
+		return s.resume(resume)
+	}
+
+	if err := s.send(reply); err != nil {
+		return err
+	}
+
+	if s.done {
+		return errDetached
+	}
+
+	return nil
+}
+
+// Runs the program until it stops or the client interrupts it
+func (s *session) resume(execute func() debugger.Stop) error {
+	stops := s.server.Debugger.Start(execute)
+
+	var failed error
+
+	for {
+		select {
+		case s.stop = <-stops:
+			if failed != nil {
+				return failed
+			}
+
+			return s.send(s.stopReply())
+		case err := <-s.errors:
+			failed = err
+			s.server.Debugger.Interrupt()
+		case packet := <-s.packets:
+			if packet == string(rune(interruptByte)) {
+				s.server.Debugger.Interrupt()
+			}
+		}
+	}
+}
+
+func (s *session) stopReply() string {
+	switch s.stop.Reason {
+	case debugger.STOP_INTERRUPTED:
+		return fmt.Sprintf("S%02X", SIGINT)
+	case debugger.STOP_BREAKPOINT:
+		return fmt.Sprintf("T%02Xswbreak:;", SIGTRAP)
+	case debugger.STOP_HISTORY:
+		return fmt.Sprintf("T%02Xreplaylog:begin;", SIGTRAP)
+	case debugger.STOP_WATCHPOINT:
+		hit := s.stop.Hits[0]
+		kind := "watch"
+
+		switch {
+		case hit.Watchpoint.Access&debugger.ACCESS_READ > 0 && hit.Watchpoint.Access&debugger.ACCESS_WRITE > 0:
+			kind = "awatch"
+		case hit.Watchpoint.Access&debugger.ACCESS_READ > 0:
+			kind = "rwatch"
+		}
+
+		return fmt.Sprintf("T%02X%s:%04x;", SIGTRAP, kind, hit.Address)
+	}
+
+	return fmt.Sprintf("S%02X", SIGTRAP)
+}
+
+func (s *session) send(data string) error {
+	if s.server.Logger != nil {
+		s.server.Logger.Print("-> ", data)
+	}
+
+	s.last = data
+	return s.write(fmt.Sprintf("$%s#%02x", data, sum(data)))
+}
+
+func (s *session) write(data string) error {
+	if _, err := s.writer.WriteString(data); err != nil {
+		return err
+	}
+
+	return s.writer.Flush()
+}
+
+func sum(data string) byte {
+	var checksum byte
+	for index := 0; index < len(data); index++ {
+		checksum += data[index]
+	}
+
+	return checksum
+}
+
+// Escapes the characters with special meaning in binary replies
+func escape(data string) string {
+	var escaped strings.Builder
+
+	for index := 0; index < len(data); index++ {
+		switch char := data[index]; char {
+		case '#', '$', '}', '*':
+			escaped.WriteByte('}')
+			escaped.WriteByte(char ^ 0x20)
+		default:
+			escaped.WriteByte(char)
+		}
+	}
+
+	return escaped.String()
+}
+
+func unescape(data string) []byte {
+	var unescaped []byte
+
+	for index := 0; index < len(data); index++ {
+		if data[index] == '}' && index+1 < len(data) {
+			index++
+			unescaped = append(unescaped, data[index]^0x20)
+			continue
+		}
+
+		unescaped = append(unescaped, data[index])
+	}
+
+	return unescaped
+}
+
+func parseHex(text string) (uint64, error) {
+	return strconv.ParseUint(text, 16, 64)
+}
+
+// Parses "address,length"
+func parseRange(text string) (uint16, int, error) {
+	parts := strings.SplitN(text, ",", 2)
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("invalid range %q", text)
+	}
+
+	address, err := parseHex(parts[0])
+	if err != nil || address > 0xFFFF {
+		return 0, 0, fmt.Errorf("invalid address %q", parts[0])
+	}
+
+	length, err := parseHex(parts[1])
+	if err != nil || length > 0x10000 {
+		return 0, 0, fmt.Errorf("invalid length %q", parts[1])
+	}
+
+	return uint16(address), int(length), nil
+}
+
+func encodeByte(value byte) string {
+	return hex.EncodeToString([]byte{value})
+}
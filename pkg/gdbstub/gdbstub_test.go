@@ -0,0 +1,171 @@
+package gdbstub
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/costamauricio/6502-emulator/pkg/bus"
+	"github.com/costamauricio/6502-emulator/pkg/cpu6502"
+	"github.com/costamauricio/6502-emulator/pkg/debugger"
+)
+
+// Scripted client speaking the remote protocol
+type client struct {
+	t      *testing.T
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func (c *client) send(data string) {
+	fmt.Fprintf(c.conn, "$%s#%02x", data, sum(data))
+}
+
+func (c *client) receive() string {
+	c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	for {
+		char, err := c.reader.ReadByte()
+		if err != nil {
+			c.t.Fatal(err)
+		}
+
+		if char != '$' {
+			continue
+		}
+
+		data, err := c.reader.ReadString('#')
+		if err != nil {
+			c.t.Fatal(err)
+		}
+
+		checksum := make([]byte, 2)
+		c.reader.Read(checksum)
+		c.conn.Write([]byte("+"))
+
+		return data[:len(data)-1]
+	}
+}
+
+func (c *client) expect(data string, reply string) {
+	c.t.Helper()
+
+	c.send(data)
+	if received := c.receive(); received != reply {
+		c.t.Errorf("%s: expected %q, received %q", data, reply, received)
+	}
+}
+
+func newClient(t *testing.T, program string) (*client, *debugger.Debugger) {
+	dataBus := &bus.Bus{}
+	dataBus.LoadRamFromString(program, 0x8000)
+	dataBus.LoadRamFromString("00 80", 0xFFFC)
+
+	watchBus := debugger.NewWatchBus(dataBus)
+	dbg := debugger.New(cpu6502.New(watchBus), watchBus)
+	dbg.Step()
+
+	serverConn, clientConn := net.Pipe()
+	go New(dbg).ServeConn(serverConn)
+
+	t.Cleanup(func() {
+		clientConn.Close()
+	})
+
+	return &client{t: t, conn: clientConn, reader: bufio.NewReader(clientConn)}, dbg
+}
+
+func TestSession(t *testing.T) {
+	// LDA #$10; STA $0200; loop: INX; JMP loop
+	client, _ := newClient(t, "A9 10 8D 00 02 E8 4C 05 80")
+
+	client.expect("qSupported:swbreak+", "PacketSize=4000;qXfer:features:read+;QStartNoAckMode+;swbreak+")
+	client.expect("?", "S05")
+	client.expect("g", "000000ff008020")
+	client.expect("p4", "0080")
+
+	xml := client.receiveTransfer("qXfer:features:read:target.xml:0,40")
+	if !strings.Contains(xml, `<reg name="pc" bitsize="16"`) {
+		t.Errorf("unexpected target description %q", xml)
+	}
+
+	client.expect("vCont;", "E01")
+	client.expect("s", "S05")
+	client.expect("p0", "10")
+
+	client.expect("Z2,ffff,4", "E01")
+	client.expect("Z2,fffe,2", "OK")
+	client.expect("z2,fffe,2", "OK")
+
+	client.expect("Z2,200,1", "OK")
+	client.expect("c", "T05watch:0200;")
+	client.expect("z2,200,1", "OK")
+	client.expect("m200,2", "1000")
+
+	client.expect("Z0,8006,1", "OK")
+	client.expect("c", "T05swbreak:;")
+	client.expect("p4", "0680")
+	client.expect("z0,8006,1", "OK")
+
+	client.expect("M300,3:010203", "OK")
+	client.expect("m300,3", "010203")
+	client.expect("P0=42", "OK")
+	client.expect("p0", "42")
+	client.expect("G0102030405800f", "OK")
+	client.expect("g", "0102030405800f")
+}
+
+func TestInterrupt(t *testing.T) {
+	// loop: JMP loop
+	client, _ := newClient(t, "4C 00 80")
+
+	client.send("c")
+	time.Sleep(10 * time.Millisecond)
+	client.conn.Write([]byte{interruptByte})
+
+	if reply := client.receive(); reply != "S02" {
+		t.Errorf("expected interrupt stop, received %q", reply)
+	}
+
+	client.expect("D", "OK")
+}
+
+func TestReverse(t *testing.T) {
+	// LDA #$10; STA $0200; loop: INX; JMP loop
+	client, dbg := newClient(t, "A9 10 8D 00 02 E8 4C 05 80")
+	client.expect("bs", "")
+
+	dbg.EnableHistory(0)
+	client.expect("qSupported:swbreak+", "PacketSize=4000;qXfer:features:read+;QStartNoAckMode+;swbreak+;ReverseStep+;ReverseContinue+")
+
+	client.expect("vCont;", "E01")
+	client.expect("s", "S05")
+	client.expect("vCont;", "E01")
+	client.expect("s", "S05")
+	client.expect("Z2,200,1", "OK")
+	client.expect("bc", "T05watch:0200;")
+	client.expect("p4", "0280")
+	client.expect("m200,1", "00")
+	client.expect("z2,200,1", "OK")
+	client.expect("bs", "S05")
+	client.expect("bs", "T05replaylog:begin;")
+}
+
+// Reads the whole qXfer document
+func (c *client) receiveTransfer(packet string) string {
+	var document string
+
+	for {
+		c.send(packet[:strings.LastIndex(packet, ":")+1] + fmt.Sprintf("%x,40", len(document)))
+
+		reply := c.receive()
+		document += reply[1:]
+
+		if reply[0] == 'l' {
+			return document
+		}
+	}
+}
@@ -0,0 +1,381 @@
+package gdbstub
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/costamauricio/6502-emulator/pkg/debugger"
+)
+
+const (
+	replyOK    = "OK"
+	replyEmpty = "" // Unsupported packets are answered with an empty reply
+)
+
+func replyError(code int) string {
+	return fmt.Sprintf("E%02X", code)
+}
+
+// Handles a packet returning the reply
+// When the packet resumes the execution it returns the function to run instead
+func (s *session) handle(packet string) (string, func() debugger.Stop) {
+	dbg := s.server.Debugger
+
+	if packet == "" {
+		return replyEmpty, nil
+	}
+
+	arguments := packet[1:]
+
+	switch packet[0] {
+	case '?':
+		return s.stopReply(), nil
+	case 'g':
+		return s.readRegisters(), nil
+	case 'G':
+		return s.writeRegisters(arguments), nil
+	case 'p':
+		return s.readRegister(arguments), nil
+	case 'P':
+		return s.writeRegister(arguments), nil
+	case 'm':
+		return s.readMemory(arguments), nil
+	case 'M':
+		return s.writeMemory(arguments), nil
+	case 'X':
+		return s.writeBinaryMemory(arguments), nil
+	case 'c', 's':
+		if arguments != "" {
+			address, err := parseHex(arguments)
+			if err != nil || address > 0xFFFF {
+				return replyError(1), nil
+			}
+
+			dbg.Cpu.PC = uint16(address)
+		}
+
+		if packet[0] == 's' {
+			return "", func() debugger.Stop { return dbg.Continue(1) }
+		}
+
+		return "", func() debugger.Stop { return dbg.Continue(0) }
+	case 'b':
+		// reverse execution, only when the history is recording
+		if dbg.History == nil || (arguments != "s" && arguments != "c") {
+			return replyEmpty, nil
+		}
+
+		if arguments == "s" {
+			return "", func() debugger.Stop { return dbg.ReverseContinue(1) }
+		}
+
+		return "", func() debugger.Stop { return dbg.ReverseContinue(0) }
+	case 'Z', 'z':
+		return s.breakpoint(packet[0] == 'Z', arguments), nil
+	case 'H':
+		// single threaded target
+		return replyOK, nil
+	case 'T':
+		return replyOK, nil
+	case 'D':
+		s.done = true
+		return replyOK, nil
+	case 'k':
+		s.done = true
+		return replyOK, nil
+	case 'q', 'Q', 'v':
+		return s.query(packet)
+	}
+
+	return replyEmpty, nil
+}
+
+func (s *session) query(packet string) (string, func() debugger.Stop) {
+	dbg := s.server.Debugger
+
+	switch {
+	case strings.HasPrefix(packet, "qSupported"):
+		if dbg.History != nil {
+			return "PacketSize=4000;qXfer:features:read+;QStartNoAckMode+;swbreak+;ReverseStep+;ReverseContinue+", nil
+		}
+
+		return "PacketSize=4000;qXfer:features:read+;QStartNoAckMode+;swbreak+", nil
+	case packet == "QStartNoAckMode":
+		// the OK is still acknowledged by the client
+		s.ack = false
+		return replyOK, nil
+	case strings.HasPrefix(packet, "qXfer:features:read:target.xml:"):
+		return transfer(targetXML, strings.TrimPrefix(packet, "qXfer:features:read:target.xml:")), nil
+	case packet == "qAttached":
+		return "1", nil
+	case packet == "qC":
+		return "QC1", nil
+	case packet == "qfThreadInfo":
+		return "m1", nil
+	case packet == "qsThreadInfo":
+		return "l", nil
+	case strings.HasPrefix(packet, "qSymbol"):
+		return replyOK, nil
+	case packet == "vCont?":
+		return "vCont;c;s", nil
+	case strings.HasPrefix(packet, "vCont;"):
+		action := strings.SplitN(strings.TrimPrefix(packet, "vCont;"), ":", 2)[0]
+		if len(action) == 0 {
+			return replyError(1), nil
+		}
+
+		switch action[0] {
+		case 's', 'S':
+			return "", func() debugger.Stop { return dbg.Continue(1) }
+		case 'c', 'C':
+			return "", func() debugger.Stop { return dbg.Continue(0) }
+		}
+	case packet == "vKill" || strings.HasPrefix(packet, "vKill;"):
+		s.done = true
+		return replyOK, nil
+	}
+
+	return replyEmpty, nil
+}
+
+// Replies a qXfer read for "offset,length"
+func transfer(document string, arguments string) string {
+	parts := strings.SplitN(arguments, ",", 2)
+	if len(parts) != 2 {
+		return replyError(1)
+	}
+
+	offset, err := parseHex(parts[0])
+	if err != nil {
+		return replyError(1)
+	}
+
+	length, err := parseHex(parts[1])
+	if err != nil {
+		return replyError(1)
+	}
+
+	if offset >= uint64(len(document)) {
+		return "l"
+	}
+
+	end := offset + length
+	if end >= uint64(len(document)) {
+		return "l" + escape(document[offset:])
+	}
+
+	return "m" + escape(document[offset:end])
+}
+
+func (s *session) registers() []byte {
+	cpu := s.server.Debugger.Cpu
+	return []byte{cpu.A, cpu.X, cpu.Y, cpu.S, byte(cpu.PC & 0x00FF), byte(cpu.PC >> 8), cpu.Status}
+}
+
+func (s *session) setRegisters(data []byte) {
+	cpu := s.server.Debugger.Cpu
+
+	cpu.A, cpu.X, cpu.Y, cpu.S = data[0], data[1], data[2], data[3]
+	cpu.PC = uint16(data[5])<<8 | uint16(data[4])
+	cpu.Status = data[6]
+}
+
+// Offset and size in the registers data by register number
+var registerLayout = [][2]int{{0, 1}, {1, 1}, {2, 1}, {3, 1}, {4, 2}, {6, 1}}
+
+func (s *session) readRegisters() string {
+	return hex.EncodeToString(s.registers())
+}
+
+func (s *session) writeRegisters(arguments string) string {
+	data, err := hex.DecodeString(arguments)
+	if err != nil || len(data) < 7 {
+		return replyError(1)
+	}
+
+	s.setRegisters(data)
+	return replyOK
+}
+
+func (s *session) readRegister(arguments string) string {
+	number, err := parseHex(arguments)
+	if err != nil || number >= uint64(len(registerLayout)) {
+		return replyError(1)
+	}
+
+	layout := registerLayout[number]
+	return hex.EncodeToString(s.registers()[layout[0] : layout[0]+layout[1]])
+}
+
+func (s *session) writeRegister(arguments string) string {
+	parts := strings.SplitN(arguments, "=", 2)
+	if len(parts) != 2 {
+		return replyError(1)
+	}
+
+	number, err := parseHex(parts[0])
+	if err != nil || number >= uint64(len(registerLayout)) {
+		return replyError(1)
+	}
+
+	value, err := hex.DecodeString(parts[1])
+	layout := registerLayout[number]
+	if err != nil || len(value) < layout[1] {
+		return replyError(1)
+	}
+
+	data := s.registers()
+	copy(data[layout[0]:layout[0]+layout[1]], value)
+	s.setRegisters(data)
+
+	return replyOK
+}
+
+func (s *session) readMemory(arguments string) string {
+	address, length, err := parseRange(arguments)
+	if err != nil {
+		return replyError(1)
+	}
+
+	var reply strings.Builder
+	for index := 0; index < length && int(address)+index <= 0xFFFF; index++ {
+		reply.WriteString(encodeByte(s.server.Debugger.Peek(address + uint16(index))))
+	}
+
+	return reply.String()
+}
+
+func (s *session) writeMemory(arguments string) string {
+	parts := strings.SplitN(arguments, ":", 2)
+	if len(parts) != 2 {
+		return replyError(1)
+	}
+
+	address, length, err := parseRange(parts[0])
+	if err != nil {
+		return replyError(1)
+	}
+
+	data, err := hex.DecodeString(parts[1])
+	if err != nil || len(data) != length {
+		return replyError(1)
+	}
+
+	return s.poke(address, data)
+}
+
+func (s *session) writeBinaryMemory(arguments string) string {
+	parts := strings.SplitN(arguments, ":", 2)
+	if len(parts) != 2 {
+		return replyError(1)
+	}
+
+	address, length, err := parseRange(parts[0])
+	if err != nil {
+		return replyError(1)
+	}
+
+	data := unescape(parts[1])
+	if len(data) != length {
+		return replyError(1)
+	}
+
+	return s.poke(address, data)
+}
+
+func (s *session) poke(address uint16, data []byte) string {
+	if int(address)+len(data) > 0x10000 {
+		return replyError(2)
+	}
+
+	for index, value := range data {
+		s.server.Debugger.Poke(address+uint16(index), value)
+	}
+
+	return replyOK
+}
+
+// Handles Z/z packets: "type,address,kind"
+func (s *session) breakpoint(insert bool, arguments string) string {
+	dbg := s.server.Debugger
+
+	parts := strings.Split(arguments, ",")
+	if len(parts) < 3 {
+		return replyError(1)
+	}
+
+	kind, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return replyError(1)
+	}
+
+	address, length, err := parseRange(parts[1] + "," + parts[2])
+	if err != nil {
+		return replyError(1)
+	}
+
+	switch kind {
+	case 0, 1:
+		// software and hardware breakpoints are the same for the emulator
+		if !insert {
+			if id, found := s.server.breakpoints[address]; found {
+				dbg.Breakpoints.Remove(id)
+				delete(s.server.breakpoints, address)
+			}
+
+			return replyOK
+		}
+
+		if _, found := s.server.breakpoints[address]; !found {
+			s.server.breakpoints[address] = dbg.Breakpoints.Add(debugger.Breakpoint{Address: address}).Id
+		}
+
+		return replyOK
+	case 2, 3, 4:
+		if dbg.Watch == nil {
+			return replyEmpty
+		}
+
+		access := map[int]debugger.Access{
+			2: debugger.ACCESS_WRITE,
+			3: debugger.ACCESS_READ,
+			4: debugger.ACCESS_READ | debugger.ACCESS_WRITE,
+		}[kind]
+
+		key := fmt.Sprintf("%d,%04x,%d", kind, address, length)
+
+		if !insert {
+			if id, found := s.server.watchpoints[key]; found {
+				dbg.Watch.Remove(id)
+				delete(s.server.watchpoints, key)
+			}
+
+			return replyOK
+		}
+
+		if length < 1 {
+			length = 1
+		}
+
+		// the range can't wrap past $FFFF
+		if int(address)+length-1 > 0xFFFF {
+			return replyError(1)
+		}
+
+		if _, found := s.server.watchpoints[key]; !found {
+			watchpoint := dbg.Watch.Add(debugger.Watchpoint{
+				Start:  address,
+				End:    address + uint16(length-1),
+				Access: access,
+			})
+
+			s.server.watchpoints[key] = watchpoint.Id
+		}
+
+		return replyOK
+	}
+
+	return replyEmpty
+}
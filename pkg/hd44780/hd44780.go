@@ -0,0 +1,389 @@
+// Hitachi HD44780 character LCD controller
+//
+// The display RAM, the custom characters, the cursor and the display shift of the usual 16x2 and
+// 20x4 modules, with the 8 and 4 bit interfaces. It's driven through its pins, so it can hang on
+// the ports of a VIA like on the breadboard computers:
+//
+//	lcd := hd44780.New(16, 2)
+//	via.OnPortA = func(pins byte) {
+//		data, driven := lcd.Interface(pins&0x20 > 0, pins&0x40 > 0, pins&0x80 > 0, via.PinsB())
+//		if driven {
+//			via.SetInputB(data)
+//		}
+//	}
+//
+// The instructions complete at once, so the busy flag always reads clear.
+package hd44780
+
+import (
+	"strings"
+)
+
+// Instructions, the highest bit set selects the instruction
+const (
+	INS_CLEAR        byte = 0x01
+	INS_HOME         byte = 0x02
+	INS_ENTRY_MODE   byte = 0x04 // I/D bit 1 increments, S bit 0 shifts the display
+	INS_DISPLAY      byte = 0x08 // D bit 2 display on, C bit 1 cursor, B bit 0 blink
+	INS_SHIFT        byte = 0x10 // S/C bit 3 shifts the display instead of the cursor, R/L bit 2 right
+	INS_FUNCTION_SET byte = 0x20 // DL bit 4 8-bit interface, N bit 3 two lines, F bit 2 5x10 font
+	INS_CGRAM        byte = 0x40 // Sets the custom characters address
+	INS_DDRAM        byte = 0x80 // Sets the display address
+)
+
+// Status bits read with RS low
+const (
+	STATUS_BUSY    byte = 0x80
+	STATUS_ADDRESS byte = 0x7F
+)
+
+const (
+	DDRAM_SIZE   = 80
+	LINE_LENGTH  = 40   // Characters of each line in the two line mode
+	SECOND_LINE  = 0x40 // Display address of the second line
+	CGRAM_SIZE   = 64
+	CUSTOM_CHARS = 8
+)
+
+type LCD struct {
+	Columns  int
+	Lines    int
+	OnChange func() // Optional, called after the display contents may have changed
+
+	ddram [DDRAM_SIZE]byte
+	cgram [CGRAM_SIZE]byte
+
+	address    byte // Address counter
+	cgMode     bool // The address counter points to the custom characters
+	increment  bool
+	shiftEntry bool // Shifts the display on the writes
+	displayOn  bool
+	cursor     bool
+	blink      bool
+	eightBit   bool
+	twoLines   bool
+	shift      int // Display shift, in characters
+
+	enable  bool // E level
+	pending bool // Transferred the high nibble in the 4 bit mode
+	high    byte // High nibble transferred
+}
+
+// The columns and lines of the module, e.g. 16x2
+func New(columns int, lines int) *LCD {
+	lcd := &LCD{Columns: columns, Lines: lines}
+	lcd.Reset()
+
+	return lcd
+}
+
+// Internal reset of the power up: 8 bit interface, one line, display off and incrementing
+func (l *LCD) Reset() {
+	for index := range l.ddram {
+		l.ddram[index] = ' '
+	}
+
+	l.address, l.cgMode, l.shift = 0, false, 0
+	l.increment, l.shiftEntry = true, false
+	l.displayOn, l.cursor, l.blink = false, false, false
+	l.eightBit, l.twoLines = true, false
+	l.pending = false
+}
+
+// Drives the RS, RW, E and D0-D7 pins, in the 4 bit mode only D4-D7 are used
+// Returns the data lines driven by the LCD while reading, when E and RW are high
+// The writes are taken on the falling edge of E
+func (l *LCD) Interface(rs bool, rw bool, enable bool, data byte) (byte, bool) {
+	falling := l.enable && !enable
+	l.enable = enable
+
+	if enable && rw {
+		return l.output(rs), true
+	}
+
+	if !falling {
+		return 0, false
+	}
+
+	if l.eightBit {
+		l.transfer(rs, rw, data)
+		return 0, false
+	}
+
+	if !l.pending {
+		l.pending, l.high = true, data&0xF0
+		return 0, false
+	}
+
+	l.pending = false
+	l.transfer(rs, rw, l.high|data>>4)
+
+	return 0, false
+}
+
+// Data lines while reading, the nibble pending in the 4 bit mode
+func (l *LCD) output(rs bool) byte {
+	value := l.Status()
+	if rs {
+		value = l.peek()
+	}
+
+	if l.eightBit {
+		return value
+	}
+
+	if l.pending {
+		return value << 4
+	}
+
+	return value & 0xF0
+}
+
+func (l *LCD) transfer(rs bool, rw bool, data byte) {
+	switch {
+	case rw && rs:
+		l.Read()
+	case rw:
+	case rs:
+		l.Write(data)
+	default:
+		l.Instruction(data)
+	}
+}
+
+// Busy flag and address counter
+func (l *LCD) Status() byte {
+	return l.address & STATUS_ADDRESS
+}
+
+func (l *LCD) Instruction(value byte) {
+	switch {
+	case value&INS_DDRAM > 0:
+		l.address, l.cgMode = value&^INS_DDRAM, false
+		l.wrapAddress()
+	case value&INS_CGRAM > 0:
+		l.address, l.cgMode = value&(CGRAM_SIZE-1), true
+	case value&INS_FUNCTION_SET > 0:
+		l.eightBit = value&0x10 > 0
+		l.twoLines = value&0x08 > 0
+		l.pending = false
+		l.wrapAddress()
+	case value&INS_SHIFT > 0:
+		step := -1
+		if value&0x04 > 0 {
+			step = 1
+		}
+
+		if value&0x08 > 0 {
+			l.shift -= step
+		} else {
+			l.move(step)
+		}
+	case value&INS_DISPLAY > 0:
+		l.displayOn, l.cursor, l.blink = value&0x04 > 0, value&0x02 > 0, value&0x01 > 0
+	case value&INS_ENTRY_MODE > 0:
+		l.increment, l.shiftEntry = value&0x02 > 0, value&0x01 > 0
+	case value&INS_HOME > 0:
+		l.address, l.cgMode, l.shift = 0, false, 0
+	case value&INS_CLEAR > 0:
+		for index := range l.ddram {
+			l.ddram[index] = ' '
+		}
+
+		l.address, l.cgMode, l.shift = 0, false, 0
+		l.increment = true
+	}
+
+	l.changed()
+}
+
+// Writes the data at the address counter and moves it
+func (l *LCD) Write(value byte) {
+	if l.cgMode {
+		l.cgram[l.address] = value & 0x1F
+		l.address = (l.address + 1) & (CGRAM_SIZE - 1)
+		l.changed()
+		return
+	}
+
+	l.ddram[l.index()] = value
+
+	step := -1
+	if l.increment {
+		step = 1
+	}
+
+	l.move(step)
+	if l.shiftEntry {
+		l.shift += step
+	}
+
+	l.changed()
+}
+
+// Reads the data at the address counter and moves it
+func (l *LCD) Read() byte {
+	value := l.peek()
+
+	if l.cgMode {
+		l.address = (l.address + 1) & (CGRAM_SIZE - 1)
+	} else if l.increment {
+		l.move(1)
+	} else {
+		l.move(-1)
+	}
+
+	return value
+}
+
+func (l *LCD) peek() byte {
+	if l.cgMode {
+		return l.cgram[l.address]
+	}
+
+	return l.ddram[l.index()]
+}
+
+// Display RAM index of the address counter
+func (l *LCD) index() int {
+	if l.twoLines && l.address >= SECOND_LINE {
+		return LINE_LENGTH + int(l.address-SECOND_LINE)
+	}
+
+	return int(l.address)
+}
+
+// Moves the address counter, wrapping around the lines
+func (l *LCD) move(step int) {
+	index := (l.index() + step + DDRAM_SIZE) % DDRAM_SIZE
+
+	if l.twoLines && index >= LINE_LENGTH {
+		l.address = byte(SECOND_LINE + index - LINE_LENGTH)
+		return
+	}
+
+	l.address = byte(index)
+}
+
+// Keeps the address counter in the display RAM of the lines mode
+func (l *LCD) wrapAddress() {
+	if l.cgMode {
+		return
+	}
+
+	if l.twoLines {
+		if l.address&^SECOND_LINE >= LINE_LENGTH {
+			l.address &= SECOND_LINE
+		}
+
+		return
+	}
+
+	if l.address >= DDRAM_SIZE {
+		l.address = 0
+	}
+}
+
+func (l *LCD) changed() {
+	if l.OnChange != nil {
+		l.OnChange()
+	}
+}
+
+// Character codes shown on each line, with the display shift
+// The lines are blank while the display is off
+func (l *LCD) Codes() [][]byte {
+	lines := make([][]byte, l.Lines)
+
+	for line := range lines {
+		lines[line] = make([]byte, l.Columns)
+
+		for column := range lines[line] {
+			lines[line][column] = ' '
+			if l.displayOn {
+				lines[line][column] = l.ddram[l.position(line, column)]
+			}
+		}
+	}
+
+	return lines
+}
+
+// Display RAM index of the position on the module
+// The 4 line modules continue the first and second lines on the third and fourth ones
+func (l *LCD) position(line int, column int) int {
+	length := DDRAM_SIZE
+	base := 0
+
+	if l.twoLines {
+		length = LINE_LENGTH
+		base = (line % 2) * LINE_LENGTH
+		column += (line / 2) * l.Columns
+	} else if line > 0 {
+		column += line * l.Columns
+	}
+
+	offset := ((column+l.shift)%length + length) % length
+
+	return base + offset
+}
+
+// Text shown on each line, the characters out of ASCII are approximated
+func (l *LCD) Text() []string {
+	codes := l.Codes()
+	lines := make([]string, len(codes))
+
+	for index, line := range codes {
+		var text strings.Builder
+		for _, code := range line {
+			text.WriteString(character(code))
+		}
+
+		lines[index] = text.String()
+	}
+
+	return lines
+}
+
+// Text of the A00 character ROM code
+func character(code byte) string {
+	switch {
+	case code < CUSTOM_CHARS*2:
+		return "▒"
+	case code == 0x5C:
+		return "¥"
+	case code == 0x7E:
+		return "→"
+	case code == 0x7F:
+		return "←"
+	case code == 0xDF:
+		return "°"
+	case code == 0xFF:
+		return "█"
+	case code >= 0x20 && code < 0x7E:
+		return string(rune(code))
+	}
+
+	return " "
+}
+
+// Text of the display in a frame
+func (l *LCD) String() string {
+	border := strings.Repeat("─", l.Columns)
+
+	var frame strings.Builder
+	frame.WriteString("┌" + border + "┐\n")
+	for _, line := range l.Text() {
+		frame.WriteString("│" + line + "│\n")
+	}
+	frame.WriteString("└" + border + "┘\n")
+
+	return frame.String()
+}
+
+// Custom character pattern, the 5 bit rows of the 5x8 font
+func (l *LCD) Custom(code byte) []byte {
+	start := int(code%CUSTOM_CHARS) * 8
+
+	return append([]byte(nil), l.cgram[start:start+8]...)
+}
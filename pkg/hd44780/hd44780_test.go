@@ -0,0 +1,65 @@
+package hd44780
+
+import (
+	"testing"
+)
+
+// Transfers the byte in two nibbles on D4-D7
+func send4(lcd *LCD, rs bool, value byte) {
+	for _, nibble := range []byte{value & 0xF0, value << 4} {
+		lcd.Interface(rs, false, true, nibble)
+		lcd.Interface(rs, false, false, nibble)
+	}
+}
+
+func TestFourBitInterface(t *testing.T) {
+	lcd := New(16, 2)
+
+	// the function set to 4 bit takes a single transfer in the 8 bit mode
+	lcd.Interface(false, false, true, 0x20)
+	lcd.Interface(false, false, false, 0x20)
+
+	for _, instruction := range []byte{0x28, 0x0C, 0x06, 0x01} {
+		send4(lcd, false, instruction)
+	}
+
+	for _, value := range []byte("Hi") {
+		send4(lcd, true, value)
+	}
+
+	send4(lcd, false, INS_DDRAM|SECOND_LINE)
+	send4(lcd, true, '!')
+
+	if lines := lcd.Text(); lines[0] != "Hi              " || lines[1] != "!               " {
+		t.Errorf("expected the text on both lines, got %q", lines)
+	}
+
+	// the status reads the high nibble and then the low one
+	high, driven := lcd.Interface(false, true, true, 0)
+	lcd.Interface(false, true, false, 0)
+	low, _ := lcd.Interface(false, true, true, 0)
+	lcd.Interface(false, true, false, 0)
+
+	if !driven || high|low>>4 != SECOND_LINE+1 {
+		t.Errorf("expected the address $41 without busy, got $%02X", high|low>>4)
+	}
+}
+
+func TestDisplayShift(t *testing.T) {
+	lcd := New(16, 1)
+	lcd.Instruction(INS_DISPLAY | 0x04)
+
+	for _, value := range []byte("ABC") {
+		lcd.Write(value)
+	}
+
+	lcd.Instruction(INS_SHIFT | 0x08)
+	if text := lcd.Text()[0]; text != "BC              " {
+		t.Errorf("expected the display shifted left, got %q", text)
+	}
+
+	lcd.Instruction(INS_HOME)
+	if text := lcd.Text()[0]; text != "ABC             " || lcd.Status() != 0 {
+		t.Errorf("expected the shift and the address back home, got %q", text)
+	}
+}
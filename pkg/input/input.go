@@ -0,0 +1,243 @@
+// Memory-mapped input devices
+//
+// The keys and the game controllers of the host, or of a script, seen by the programs on the bus.
+// The keyboard latches the code of the last key with a strobe bit, like the Apple II one, and the
+// buttons are a bitfield of the pressed ones, like a joystick port:
+//
+//	keyboard := input.NewKeyboard()
+//	dataBus.Map(0xC000, 0xC001, keyboard)
+//	buttons := input.NewButtons(input.NES)
+//	dataBus.Map(0xC002, 0xC002, buttons)
+//
+// The host inputs are bound by their names, the SDL key names ("Up", "Return", "Z") and game
+// controller button names ("a", "start", "dpup"), so the layouts don't depend on SDL.
+package input
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Keyboard registers
+const (
+	REG_KEY    = 0x0 // Code of the last key with the strobe bit
+	REG_STROBE = 0x1 // Any access clears the strobe
+)
+
+// Set on the key code until the strobe is cleared
+const STROBE = 0x80
+
+// Codes of the named host keys that don't type text
+var KEYS = map[string]byte{
+	"Return":    0x0D,
+	"Backspace": 0x08,
+	"Tab":       0x09,
+	"Escape":    0x1B,
+	"Delete":    0x7F,
+	"Left":      0x08,
+	"Right":     0x15,
+	"Up":        0x0B,
+	"Down":      0x0A,
+}
+
+type Keyboard struct {
+	Strobe    byte            // Strobe bit, 0 keeps a plain latch like the easy6502 one at $FF
+	Uppercase bool            // Types the letters in upper case
+	Keys      map[string]byte // Codes of the named keys, KEYS by default
+
+	code    byte
+	strobed bool
+}
+
+func NewKeyboard() *Keyboard {
+	return &Keyboard{Strobe: STROBE, Keys: KEYS}
+}
+
+// Latches the key code and sets the strobe
+func (k *Keyboard) Press(code byte) {
+	if k.Uppercase && code >= 'a' && code <= 'z' {
+		code -= 'a' - 'A'
+	}
+
+	k.code, k.strobed = code&^k.Strobe, true
+}
+
+// Presses the keys of the typed text, the last one stays latched
+func (k *Keyboard) Type(text string) {
+	for index := 0; index < len(text); index++ {
+		k.Press(text[index])
+	}
+}
+
+// Presses the named host key, returning whether it has a code
+func (k *Keyboard) PressKey(name string) bool {
+	code, found := k.Keys[name]
+	if found {
+		k.Press(code)
+	}
+
+	return found
+}
+
+// Whether a key was pressed since the strobe was cleared
+func (k *Keyboard) Strobed() bool {
+	return k.strobed
+}
+
+func (k *Keyboard) Read(register uint16) byte {
+	value := k.Peek(register)
+
+	if register == REG_STROBE {
+		k.strobed = false
+	}
+
+	return value
+}
+
+func (k *Keyboard) Peek(register uint16) byte {
+	if register != REG_KEY {
+		return 0
+	}
+
+	if k.strobed {
+		return k.code | k.Strobe
+	}
+
+	return k.code
+}
+
+func (k *Keyboard) Write(register uint16, data byte) {
+	if register == REG_STROBE {
+		k.strobed = false
+	}
+}
+
+// Host input names bound to the button bits
+type Layout map[string]byte
+
+// Bits of the NES controller report: A, B, Select, Start, Up, Down, Left and Right
+var NES = Layout{
+	"X": 0x01, "Z": 0x02, "Right Shift": 0x04, "Return": 0x08,
+	"Up": 0x10, "Down": 0x20, "Left": 0x40, "Right": 0x80,
+	"a": 0x01, "b": 0x02, "back": 0x04, "start": 0x08,
+	"dpup": 0x10, "dpdown": 0x20, "dpleft": 0x40, "dpright": 0x80,
+}
+
+// Joystick with the directions on the low bits and the fire button on bit 4
+var JOYSTICK = Layout{
+	"Up": 0x01, "Down": 0x02, "Left": 0x04, "Right": 0x08, "Space": 0x10,
+	"dpup": 0x01, "dpdown": 0x02, "dpleft": 0x04, "dpright": 0x08, "a": 0x10,
+}
+
+// Layouts by name
+var LAYOUTS = map[string]Layout{"nes": NES, "joystick": JOYSTICK}
+
+// Layout of the name on LAYOUTS, or of the bindings
+func LayoutOf(layout string) (Layout, error) {
+	if named, found := LAYOUTS[layout]; found {
+		return named, nil
+	}
+
+	return ParseLayout(layout)
+}
+
+// Layout of the comma separated name=bit bindings, e.g. "Up=0,Down=1,a=4", the bits go from 0 to 7
+func ParseLayout(bindings string) (Layout, error) {
+	layout := Layout{}
+
+	for _, binding := range strings.Split(bindings, ",") {
+		if binding = strings.TrimSpace(binding); binding == "" {
+			continue
+		}
+
+		name, bit, found := strings.Cut(binding, "=")
+		parsed, err := strconv.ParseUint(strings.TrimSpace(bit), 10, 3)
+
+		if !found || err != nil || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("invalid binding %q, it's name=bit", binding)
+		}
+
+		layout[strings.TrimSpace(name)] |= 1 << parsed
+	}
+
+	return layout, nil
+}
+
+// Button bitfield, a bit set for each pressed button
+type Buttons struct {
+	Layout    Layout
+	ActiveLow bool // The pressed buttons read 0, like the joystick switches to ground
+
+	pressed byte
+}
+
+func NewButtons(layout Layout) *Buttons {
+	return &Buttons{Layout: layout}
+}
+
+// Presses or releases the named host input, returning whether it's bound
+func (b *Buttons) Set(name string, pressed bool) bool {
+	bits, found := b.With(name, pressed)
+	if found {
+		b.pressed = bits
+	}
+
+	return found
+}
+
+// Bits that would be pressed after pressing or releasing the named host input, and whether it's bound
+func (b *Buttons) With(name string, pressed bool) (byte, bool) {
+	bits, found := b.Layout[name]
+	if !found {
+		return b.pressed, false
+	}
+
+	if pressed {
+		return b.pressed | bits, true
+	}
+
+	return b.pressed &^ bits, true
+}
+
+// Presses or releases the buttons of the bits
+func (b *Buttons) SetBits(bits byte, pressed bool) {
+	if pressed {
+		b.pressed |= bits
+	} else {
+		b.pressed &^= bits
+	}
+}
+
+// Bits of the pressed buttons
+func (b *Buttons) Pressed() byte {
+	return b.pressed
+}
+
+func (b *Buttons) Read(register uint16) byte {
+	if b.ActiveLow {
+		return ^b.pressed
+	}
+
+	return b.pressed
+}
+
+func (b *Buttons) Write(register uint16, data byte) {}
+
+// Devices of the inputs logged by a replay.Recorder
+const (
+	DEVICE_KEYBOARD = 0x0 // The value is the key code
+	DEVICE_BUTTONS  = 0x1 // The value is the pressed bits
+)
+
+// Applies the logged inputs to the devices, it's the Input of a replay.Target
+func Apply(keyboard *Keyboard, buttons *Buttons) func(device byte, value byte) {
+	return func(device byte, value byte) {
+		switch {
+		case device == DEVICE_KEYBOARD && keyboard != nil:
+			keyboard.Press(value)
+		case device == DEVICE_BUTTONS && buttons != nil:
+			buttons.pressed = value
+		}
+	}
+}
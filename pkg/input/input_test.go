@@ -0,0 +1,207 @@
+package input
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/costamauricio/6502-emulator/pkg/bus"
+	"github.com/costamauricio/6502-emulator/pkg/cpu6502"
+	"github.com/costamauricio/6502-emulator/pkg/system"
+)
+
+func TestKeyboard(t *testing.T) {
+	keyboard := NewKeyboard()
+
+	if value := keyboard.Read(REG_KEY); value != 0 {
+		t.Errorf("expected no key, got $%02X", value)
+	}
+
+	keyboard.Type("ab")
+	if value := keyboard.Read(REG_KEY); value != 'b'|STROBE {
+		t.Errorf("expected the last key with the strobe, got $%02X", value)
+	}
+
+	keyboard.Read(REG_STROBE)
+	if value := keyboard.Read(REG_KEY); value != 'b' {
+		t.Errorf("expected the strobe cleared, got $%02X", value)
+	}
+
+	keyboard.Uppercase = true
+	keyboard.Press('q')
+	if !keyboard.PressKey("Return") || keyboard.PressKey("F1") {
+		t.Error("expected only the named keys with a code")
+	}
+
+	if value := keyboard.Peek(REG_KEY); value != 0x0D|STROBE {
+		t.Errorf("expected the return key, got $%02X", value)
+	}
+
+	// the easy6502 latch
+	latch := NewKeyboard()
+	latch.Strobe = 0
+	latch.Press('w')
+
+	if value := latch.Read(REG_KEY); value != 'w' {
+		t.Errorf("expected the plain key code, got $%02X", value)
+	}
+}
+
+func TestButtons(t *testing.T) {
+	buttons := NewButtons(NES)
+
+	buttons.Set("Up", true)
+	buttons.Set("a", true)
+	if buttons.Set("F1", true) {
+		t.Error("expected F1 unbound")
+	}
+
+	if value := buttons.Read(0); value != 0x11 {
+		t.Errorf("expected A and Up, got $%02X", value)
+	}
+
+	buttons.Set("dpup", false)
+	buttons.ActiveLow = true
+	if value := buttons.Read(0); value != 0xFE {
+		t.Errorf("expected only A pressed active low, got $%02X", value)
+	}
+
+	layout, err := ParseLayout("Up=0, Down=1, w=0, Space=7")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if layout["Up"] != 0x01 || layout["w"] != 0x01 || layout["Space"] != 0x80 || len(layout) != 4 {
+		t.Errorf("unexpected layout %v", layout)
+	}
+
+	if layout, err := LayoutOf("joystick"); err != nil || layout["Space"] != 0x10 {
+		t.Errorf("expected the joystick layout, got %v", layout)
+	}
+
+	for _, invalid := range []string{"Up", "Up=8", "=1"} {
+		if _, err := ParseLayout(invalid); err == nil {
+			t.Errorf("expected %q invalid", invalid)
+		}
+	}
+}
+
+func TestScript(t *testing.T) {
+	keyboard, buttons := NewKeyboard(), NewButtons(NES)
+
+	script, err := ParseScript(strings.NewReader(`
+		# waits the program
+		5000 type HI\r
+		100 press Right
+		300 release Right   # short tap
+		200 key $1B
+	`), keyboard, buttons)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(script.Events) != 6 || script.Events[0].Cycle != 100 || script.Events[5].Cycle != 5000+2*TYPE_CICLES {
+		t.Fatalf("unexpected events %+v", script.Events)
+	}
+
+	// the program waits the keys and stores them from $0200, until the return
+	dataBus := &bus.Bus{}
+	dataBus.Map(0xC000, 0xC001, keyboard)
+	dataBus.LoadRam([]byte{
+		0xA2, 0x00, //       $8000 LDX #$00
+		0xAD, 0x00, 0xC0, // $8002 LDA $C000
+		0x10, 0xFB, //       $8005 BPL $8002
+		0x8D, 0x01, 0xC0, // $8007 STA $C001   clears the strobe
+		0x29, 0x7F, //       $800A AND #$7F
+		0x9D, 0x00, 0x02, // $800C STA $0200,X
+		0xE8,       //       $800F INX
+		0xC9, 0x0D, //       $8010 CMP #$0D
+		0xD0, 0xEE, //       $8012 BNE $8002
+		0x00, //             $8014 BRK
+	}, 0x8000)
+	dataBus.LoadRam([]byte{0x00, 0x80}, 0xFFFC)
+
+	cpu := cpu6502.New(dataBus)
+	machine := system.New(cpu, dataBus)
+	machine.Add(script, 1)
+	cpu.Reset()
+
+	sawRight := false
+	for machine.Now() < 100000 && !(cpu.InstructionCompleted() && cpu.PC == 0x8014) {
+		machine.Tick()
+		sawRight = sawRight || buttons.Pressed() == 0x80
+	}
+
+	if !sawRight || buttons.Pressed() != 0 {
+		t.Error("expected Right pressed and released")
+	}
+
+	if stored := string([]byte{dataBus.Read(0x0200), dataBus.Read(0x0201), dataBus.Read(0x0202), dataBus.Read(0x0203)}); stored != "\x1bHI\r" {
+		t.Errorf("expected the scripted keys stored, got %q", stored)
+	}
+
+	if !script.Done() {
+		t.Error("expected the script done")
+	}
+
+	if _, err := ParseScript(strings.NewReader("10 jump Up"), keyboard, buttons); err == nil {
+		t.Error("expected the unknown action refused")
+	}
+}
+
+func TestScriptSpaces(t *testing.T) {
+	buttons := NewButtons(Layout{"Left Ctrl": 0x01})
+
+	script, err := ParseScript(strings.NewReader(`
+		10 type RUN 10\r   # the spaces inside are typed
+		20 press  Left Ctrl
+		200000 type A#1
+		300000 key #   # the first character isn't a comment
+	`), nil, buttons)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var typed []byte
+	for _, event := range script.Events {
+		if event.Action == ACTION_KEY {
+			typed = append(typed, event.Key)
+		}
+	}
+
+	if string(typed) != "RUN 10\rA#1#" {
+		t.Errorf("expected the text typed with its space, got %q", typed)
+	}
+
+	script.Run(20)
+	if buttons.Pressed() != 0x01 {
+		t.Errorf("expected the named input with a space pressed, got $%02X", buttons.Pressed())
+	}
+
+	if _, err := ParseScript(strings.NewReader("10 press"), nil, buttons); err == nil {
+		t.Error("expected the missing argument refused")
+	}
+
+	if _, err := ParseScript(strings.NewReader("10 release Up"), nil, buttons); err == nil {
+		t.Error("expected the name out of the layout refused")
+	}
+}
+
+func TestApply(t *testing.T) {
+	keyboard, buttons := NewKeyboard(), NewButtons(NES)
+	apply := Apply(keyboard, buttons)
+
+	buttons.Set("Up", true)
+	if bits, bound := buttons.With("Right", true); !bound || bits != 0x90 || buttons.Pressed() != 0x10 {
+		t.Errorf("expected Up and Right without pressing them, got $%02X", bits)
+	}
+
+	apply(DEVICE_KEYBOARD, 'a')
+	apply(DEVICE_BUTTONS, 0x81)
+
+	if keyboard.Peek(REG_KEY) != 'a'|STROBE || buttons.Pressed() != 0x81 {
+		t.Errorf("expected the inputs applied, got $%02X and $%02X", keyboard.Peek(REG_KEY), buttons.Pressed())
+	}
+
+	// the devices not given are skipped
+	Apply(nil, nil)(DEVICE_KEYBOARD, 'b')
+}
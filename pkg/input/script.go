@@ -0,0 +1,183 @@
+package input
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/costamauricio/6502-emulator/pkg/system"
+)
+
+// Cicles between the keys of a typed text
+const TYPE_CICLES = 20000
+
+// Script actions
+const (
+	ACTION_KEY     = iota // Presses the key code on the keyboard
+	ACTION_PRESS          // Presses the named input on the buttons
+	ACTION_RELEASE        // Releases the named input on the buttons
+)
+
+type Event struct {
+	Cycle  uint64
+	Action int
+	Key    byte
+	Name   string
+}
+
+// Inputs applied at their cicles, a device clocked by the system or run before each tick:
+//
+//	script, _ := input.ParseScript(file, keyboard, buttons)
+//	machine.Add(script, 1)
+//
+// The script lines are the cycle, the action and its argument. The argument is the rest of the
+// line, so the names and the typed text may have spaces. # starts a comment at the start of a line
+// or after a space, though not as the first character of the argument:
+//
+//	1000 key A           presses the character, or the code like $0D
+//	2000 type RUN 10\r   presses the characters TYPE_CICLES apart, with the \r and \n escapes
+//	3000 press Up        presses the named input of the buttons layout
+//	4000 release Up
+//	5000 press Left Ctrl
+//	6000 key #           presses #, like $23
+type Script struct {
+	Keyboard *Keyboard // Optional, takes the keys
+	Buttons  *Buttons  // Optional, takes the presses and releases
+
+	Events []Event // Sorted by cycle
+	next   int
+}
+
+func NewScript(keyboard *Keyboard, buttons *Buttons, events []Event) *Script {
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Cycle < events[j].Cycle })
+
+	return &Script{Keyboard: keyboard, Buttons: buttons, Events: events}
+}
+
+func ParseScript(reader io.Reader, keyboard *Keyboard, buttons *Buttons) (*Script, error) {
+	var events []Event
+	scanner := bufio.NewScanner(reader)
+
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || text[0] == '#' {
+			continue
+		}
+
+		// the argument is the rest of the line, the key names and the typed text take spaces
+		cycleField, rest := cutField(text)
+		action, rest := cutField(rest)
+		argument := uncomment(rest)
+
+		if argument == "" {
+			return nil, fmt.Errorf("line %d: expected the cycle, the action and its argument", line)
+		}
+
+		cycle, err := strconv.ParseUint(cycleField, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid cycle %q", line, cycleField)
+		}
+
+		// the names out of the layout would be ignored
+		if (action == "press" || action == "release") && buttons != nil {
+			if _, found := buttons.Layout[argument]; !found {
+				return nil, fmt.Errorf("line %d: %q isn't bound on the buttons layout", line, argument)
+			}
+		}
+
+		switch action {
+		case "key":
+			code, err := parseKey(argument)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+
+			events = append(events, Event{Cycle: cycle, Action: ACTION_KEY, Key: code})
+		case "type":
+			text := strings.NewReplacer(`\r`, "\r", `\n`, "\n").Replace(argument)
+
+			for index := 0; index < len(text); index++ {
+				events = append(events, Event{Cycle: cycle + uint64(index)*TYPE_CICLES, Action: ACTION_KEY, Key: text[index]})
+			}
+		case "press":
+			events = append(events, Event{Cycle: cycle, Action: ACTION_PRESS, Name: argument})
+		case "release":
+			events = append(events, Event{Cycle: cycle, Action: ACTION_RELEASE, Name: argument})
+		default:
+			return nil, fmt.Errorf("line %d: unknown action %q", line, action)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return NewScript(keyboard, buttons, events), nil
+}
+
+// Splits the first field from the rest of the text
+func cutField(text string) (string, string) {
+	index := strings.IndexFunc(text, unicode.IsSpace)
+	if index < 0 {
+		return text, ""
+	}
+
+	return text[:index], strings.TrimLeftFunc(text[index:], unicode.IsSpace)
+}
+
+// Drops the comment from the argument, the # starting it isn't a comment
+func uncomment(argument string) string {
+	for index := 1; index < len(argument); index++ {
+		if argument[index] == '#' && unicode.IsSpace(rune(argument[index-1])) {
+			argument = argument[:index]
+			break
+		}
+	}
+
+	return strings.TrimSpace(argument)
+}
+
+// A single character or a hexadecimal code like $0D
+func parseKey(key string) (byte, error) {
+	if len(key) == 1 {
+		return key[0], nil
+	}
+
+	code, err := strconv.ParseUint(strings.TrimPrefix(key, "$"), 16, 8)
+	if err != nil || !strings.HasPrefix(key, "$") {
+		return 0, fmt.Errorf("invalid key %q, it's a character or a code like $0D", key)
+	}
+
+	return byte(code), nil
+}
+
+// Applies the events up to now, returning the cycle of the next one
+func (s *Script) Run(now uint64) uint64 {
+	for ; s.next < len(s.Events) && s.Events[s.next].Cycle <= now; s.next++ {
+		event := s.Events[s.next]
+
+		switch {
+		case event.Action == ACTION_KEY && s.Keyboard != nil:
+			s.Keyboard.Press(event.Key)
+		case event.Action == ACTION_PRESS && s.Buttons != nil:
+			s.Buttons.Set(event.Name, true)
+		case event.Action == ACTION_RELEASE && s.Buttons != nil:
+			s.Buttons.Set(event.Name, false)
+		}
+	}
+
+	if s.Done() {
+		return system.NEVER
+	}
+
+	return s.Events[s.next].Cycle
+}
+
+// Whether all the events were applied
+func (s *Script) Done() bool {
+	return s.next >= len(s.Events)
+}
@@ -0,0 +1,349 @@
+// MOS KIM-1 single board computer
+//
+// 1K of RAM, the 6530-002 and 6530-003 RRIOTs with the monitor ROM, the hex keypad and the six
+// digit LED display. The monitor isn't part of the emulator, it takes the 2K image of $1800-$1FFF
+// (the 6530-003 ROM followed by the 6530-002 one):
+//
+//	computer := kim1.New(rom, acia6551.Stdio(), false)
+//	clock.New(clock.FREQUENCY_1MHZ, computer.Tick).Run(stop)
+//	fmt.Print(computer.Display())
+//
+// The keypad and the display are scanned by the monitor through the 6530-002 ports, the key rows
+// and the digits are selected on PB1-PB4 and read or lit on PA0-PA6. In TTY mode the monitor
+// OUTCH and GETCH routines are served from the terminal instead of the bit-banged serial line.
+package kim1
+
+import (
+	"strings"
+
+	"github.com/costamauricio/6502-emulator/pkg/acia6551"
+	"github.com/costamauricio/6502-emulator/pkg/bus"
+	"github.com/costamauricio/6502-emulator/pkg/cpu6502"
+	"github.com/costamauricio/6502-emulator/pkg/rriot6530"
+	"github.com/costamauricio/6502-emulator/pkg/system"
+	"github.com/costamauricio/6502-emulator/pkg/trap"
+)
+
+// Memory map
+const (
+	U3_IO       = 0x1700 // 6530-003 ports and timer
+	U2_IO       = 0x1740 // 6530-002 ports and timer, the keypad and the display
+	U2_RAM      = 0x1780
+	U3_RAM      = 0x17C0
+	U3_ROM      = 0x1800
+	U2_ROM      = 0x1C00 // The monitor, single step doesn't stop in it
+	ROM_SIZE    = 0x800
+	OUTCH       = 0x1EA0 // Prints the character in A on the TTY
+	GETCH       = 0x1E5A // Reads a character from the TTY into A
+	DIGITS      = 6
+	FIRST_DIGIT = 4 // Select of the leftmost digit, the keypad rows are 0 to 2
+)
+
+// Keypad codes, the hex keys are their values
+const (
+	KEY_AD   = 0x10
+	KEY_DA   = 0x11
+	KEY_PLUS = 0x12
+	KEY_GO   = 0x13
+	KEY_PC   = 0x14
+	KEY_NONE = -1
+)
+
+// Terminal bytes of the keys out of the hex ones and +
+const (
+	CTRL_AD    = 0x01 // Ctrl+A
+	CTRL_DA    = 0x04 // Ctrl+D
+	CTRL_GO    = 0x07 // Ctrl+G
+	CTRL_PC    = 0x10 // Ctrl+P
+	CTRL_RESET = 0x12 // Ctrl+R, the RS key
+	CTRL_STOP  = 0x13 // Ctrl+S, the ST key
+	CTRL_STEP  = 0x14 // Ctrl+T, toggles the SST switch
+)
+
+// Timing in cicles
+const (
+	KEY_CICLES    = 50000 // A key stays pressed, and released before the next one
+	PERSISTENCE   = 50000 // A digit stays lit after it was scanned
+	RUBOUT_DELAY  = 20000 // After the reset in TTY mode, the start bit the monitor measures
+	RUBOUT_CICLES = 9091  // Start bit at 110 baud
+	KEYBOARD_POLL = 1000
+)
+
+type KIM1 struct {
+	Cpu      *cpu6502.CPU
+	Bus      *bus.Bus
+	System   *system.System
+	U2       *rriot6530.RRIOT // 6530-002
+	U3       *rriot6530.RRIOT // 6530-003
+	Traps    *trap.Traps
+	Terminal acia6551.Endpoint
+	TTY      bool // The TTY/KB jumper, the monitor talks to the terminal
+	SST      bool // Single step switch, stops after each instruction out of the monitor
+
+	nmi func(asserted bool)
+
+	keys     []int  // Pressed on the keypad, waiting their turn
+	held     int    // Key down
+	released uint64 // Cycle the held key is released, or the next one can be pressed
+	rubout   uint64 // Cycle of the RUBOUT start bit in TTY mode
+
+	segments [DIGITS]byte
+	lit      [DIGITS]uint64 // Cycle each digit was scanned last
+}
+
+// The ROM is the 2K image of $1800-$1FFF, the terminal is the TTY or the keypad
+func New(rom []byte, terminal acia6551.Endpoint, tty bool) *KIM1 {
+	image := make([]byte, ROM_SIZE)
+	copy(image, rom)
+
+	computer := &KIM1{
+		Bus:      &bus.Bus{},
+		U3:       rriot6530.New(image[:rriot6530.ROM_SIZE]),
+		U2:       rriot6530.New(image[rriot6530.ROM_SIZE:]),
+		Terminal: terminal,
+		TTY:      tty,
+		held:     KEY_NONE,
+	}
+
+	computer.Cpu = cpu6502.New(computer.Bus)
+	computer.System = system.New(computer.Cpu, computer.Bus)
+	computer.nmi = computer.System.NMISource()
+
+	computer.Bus.Map(U3_IO, U3_IO+0x3F, computer.System.Add(computer.U3, 1))
+	computer.Bus.Map(U2_IO, U2_IO+0x3F, computer.System.Add(computer.U2, 1))
+	computer.Bus.Map(U2_RAM, U2_RAM+rriot6530.RAM_SIZE-1, computer.U2.RAM())
+	computer.Bus.Map(U3_RAM, U3_RAM+rriot6530.RAM_SIZE-1, computer.U3.RAM())
+	computer.Bus.Map(U3_ROM, U3_ROM+rriot6530.ROM_SIZE-1, computer.U3.ROM)
+	computer.Bus.Map(U2_ROM, U2_ROM+rriot6530.ROM_SIZE-1, computer.U2.ROM)
+
+	// only A0-A12 are decoded, the vectors at the top are the ones of the monitor
+	computer.Bus.Map(0xFC00, 0xFFFF, computer.U2.ROM)
+
+	computer.U2.OnPortA = func(byte) { computer.scan() }
+	computer.U2.OnPortB = func(byte) { computer.scan() }
+
+	computer.Traps = trap.New(computer.Cpu, computer.Bus)
+	computer.Traps.Set(OUTCH, computer.outch)
+	computer.Traps.Set(GETCH, computer.getch)
+
+	computer.System.Add((*keypad)(computer), 1)
+	computer.Reset()
+
+	return computer
+}
+
+// Presses the RS key
+func (k *KIM1) Reset() {
+	k.U2.Reset()
+	k.U3.Reset()
+	k.Cpu.Reset()
+
+	k.rubout = k.System.Now() + RUBOUT_DELAY
+	k.scan()
+}
+
+// Presses the ST key, the monitor stops the program
+func (k *KIM1) Stop() {
+	k.nmi(true)
+	k.nmi(false)
+}
+
+// Queues the key press on the keypad
+func (k *KIM1) Press(key int) {
+	k.keys = append(k.keys, key)
+}
+
+func (k *KIM1) Tick() {
+	step := k.SST && k.Cpu.InstructionCompleted() && k.Cpu.PC < U2_ROM
+
+	if k.TTY {
+		k.Traps.Check()
+	}
+
+	k.System.Tick()
+
+	// the NMI is taken once the instruction completes
+	if step {
+		k.Stop()
+	}
+}
+
+// Ticks the cicles
+func (k *KIM1) Run(cycles uint64) {
+	for index := uint64(0); index < cycles; index++ {
+		k.Tick()
+	}
+}
+
+// Row or digit selected on the 74145 decoder
+func (k *KIM1) selected() int {
+	return int(k.U2.PinsB() >> 1 & 0x0F)
+}
+
+// Drives the keypad row and the TTY lines on port A and lights the selected digit
+func (k *KIM1) scan() {
+	selected := k.selected()
+	now := k.System.Now()
+	levels := byte(0xFF)
+
+	if k.held != KEY_NONE && k.held/7 == selected {
+		levels &^= 0x40 >> (k.held % 7)
+	}
+
+	if k.TTY {
+		// the TTY/KB jumper
+		levels &^= 0x01
+
+		if now >= k.rubout && now < k.rubout+RUBOUT_CICLES {
+			levels &^= 0x80
+		}
+	}
+
+	k.U2.SetInputA(levels)
+
+	if selected >= FIRST_DIGIT && selected < FIRST_DIGIT+DIGITS {
+		segments := k.U2.PinsA() & k.U2.Peek(rriot6530.REG_PADD) & 0x7F
+		if segments != 0 {
+			k.segments[selected-FIRST_DIGIT] = segments
+			k.lit[selected-FIRST_DIGIT] = now
+		}
+	}
+}
+
+// Segments lit on each digit, a to g on the bits 0 to 6
+func (k *KIM1) Segments() [DIGITS]byte {
+	var segments [DIGITS]byte
+	now := k.System.Now()
+
+	for digit := range segments {
+		if now-k.lit[digit] < PERSISTENCE && k.lit[digit] > 0 {
+			segments[digit] = k.segments[digit]
+		}
+	}
+
+	return segments
+}
+
+// Display drawn in three lines, the address and the data digits apart
+func (k *KIM1) Display() string {
+	var lines [3]strings.Builder
+
+	for digit, segments := range k.Segments() {
+		if digit == 4 {
+			for line := range lines {
+				lines[line].WriteString(" ")
+			}
+		}
+
+		lit := func(segment int, on string) string {
+			if segments&(1<<segment) > 0 {
+				return on
+			}
+
+			return " "
+		}
+
+		lines[0].WriteString(" " + lit(0, "_") + "  ")
+		lines[1].WriteString(lit(5, "|") + lit(6, "_") + lit(1, "|") + " ")
+		lines[2].WriteString(lit(4, "|") + lit(3, "_") + lit(2, "|") + " ")
+	}
+
+	return lines[0].String() + "\n" + lines[1].String() + "\n" + lines[2].String() + "\n"
+}
+
+// Prints A on the terminal
+func (k *KIM1) outch(cpu *cpu6502.CPU) bool {
+	if character := cpu.A & 0x7F; character != 0 && k.Terminal != nil {
+		k.Terminal.Write([]byte{character})
+	}
+
+	return true
+}
+
+// Reads the next terminal byte into A, echoed like by the TTY line
+func (k *KIM1) getch(cpu *cpu6502.CPU) bool {
+	if k.Terminal == nil {
+		return false
+	}
+
+	value, found := k.Terminal.Receive()
+	if !found {
+		return false
+	}
+
+	switch {
+	case value == '\n':
+		value = '\r'
+	case value >= 'a' && value <= 'z':
+		value -= 'a' - 'A'
+	}
+
+	k.Terminal.Write([]byte{value})
+
+	cpu.A = value & 0x7F
+	cpu.SetFlag(cpu6502.FLAG_Z, cpu.A == 0)
+	cpu.SetFlag(cpu6502.FLAG_N, false)
+
+	return true
+}
+
+// Holds the pressed keys and takes the keypad keys from the terminal
+type keypad KIM1
+
+func (k *keypad) Run(now uint64) uint64 {
+	computer := (*KIM1)(k)
+
+	if !k.TTY && k.Terminal != nil {
+		if value, found := k.Terminal.Receive(); found {
+			computer.key(value)
+		}
+	}
+
+	if now >= k.released {
+		switch {
+		case k.held != KEY_NONE:
+			k.held = KEY_NONE
+			k.released = now + KEY_CICLES
+		case len(k.keys) > 0:
+			k.held, k.keys = k.keys[0], k.keys[1:]
+			k.released = now + KEY_CICLES
+		}
+
+		computer.scan()
+	}
+
+	// the RUBOUT start bit edges
+	if k.TTY && now >= k.rubout && now <= k.rubout+RUBOUT_CICLES {
+		computer.scan()
+	}
+
+	return now + KEYBOARD_POLL
+}
+
+// Presses the key of the terminal byte
+func (k *KIM1) key(value byte) {
+	switch {
+	case value >= '0' && value <= '9':
+		k.Press(int(value - '0'))
+	case value >= 'a' && value <= 'f':
+		k.Press(int(value-'a') + 10)
+	case value >= 'A' && value <= 'F':
+		k.Press(int(value-'A') + 10)
+	case value == '+':
+		k.Press(KEY_PLUS)
+	case value == CTRL_AD:
+		k.Press(KEY_AD)
+	case value == CTRL_DA:
+		k.Press(KEY_DA)
+	case value == CTRL_GO:
+		k.Press(KEY_GO)
+	case value == CTRL_PC:
+		k.Press(KEY_PC)
+	case value == CTRL_RESET:
+		k.Reset()
+	case value == CTRL_STOP:
+		k.Stop()
+	case value == CTRL_STEP:
+		k.SST = !k.SST
+	}
+}
@@ -0,0 +1,77 @@
+package kim1
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/costamauricio/6502-emulator/pkg/acia6551"
+)
+
+// ROM with the reset vector to the program in RAM at $0200
+func testROM() []byte {
+	rom := make([]byte, ROM_SIZE)
+	rom[0x7FC], rom[0x7FD] = 0x00, 0x02
+
+	return rom
+}
+
+func TestKeypadAndDisplay(t *testing.T) {
+	computer := New(testROM(), nil, false)
+	computer.Bus.LoadRam([]byte{
+		0xA9, 0x1E, // 0200 LDA #$1E
+		0x8D, 0x43, 0x17, // 0202 STA PBDD
+		0xA9, 0x7F, // 0205 LDA #$7F
+		0x8D, 0x41, 0x17, // 0207 STA PADD
+		0xA9, 0x09, // 020A LDA #$09, first digit
+		0x8D, 0x42, 0x17, // 020C STA SBD
+		0xA9, 0x06, // 020F LDA #$06, segments of 1
+		0x8D, 0x40, 0x17, // 0211 STA SAD
+		0xA9, 0x00, // 0214 LDA #$00
+		0x8D, 0x41, 0x17, // 0216 STA PADD
+		0x8D, 0x42, 0x17, // 0219 STA SBD, first key row
+		0xAD, 0x40, 0x17, // 021C loop: LDA SAD
+		0x85, 0x00, // 021F STA $00
+		0x4C, 0x1C, 0x02, // 0221 JMP loop
+	}, 0x0200)
+	computer.Reset()
+
+	computer.Run(1000)
+	if lines := strings.Split(computer.Display(), "\n"); lines[0][:4] != "    " || lines[1][:4] != "  | " || lines[2][:4] != "  | " {
+		t.Errorf("expected 1 on the first digit, got\n%s", computer.Display())
+	}
+
+	computer.Press(3)
+	computer.Run(2000)
+	if value := computer.Bus.Read(0x0000); value != 0xF7 {
+		t.Errorf("expected the key 3 on PA3, got $%02X", value)
+	}
+
+	computer.Run(KEY_CICLES)
+	if value := computer.Bus.Read(0x0000); value != 0xFF {
+		t.Errorf("expected the key released, got $%02X", value)
+	}
+
+	if segments := computer.Segments(); segments[0] != 0x00 {
+		t.Errorf("expected the first digit off without the scan, got $%02X", segments[0])
+	}
+}
+
+func TestTTY(t *testing.T) {
+	terminal := acia6551.NewPipe()
+	computer := New(testROM(), terminal, true)
+	computer.Bus.LoadRam([]byte{
+		0xA9, 'K', // 0200 LDA #'K'
+		0x20, 0xA0, 0x1E, // 0202 JSR OUTCH
+		0x20, 0x5A, 0x1E, // 0205 JSR GETCH
+		0x85, 0x01, // 0208 STA $01
+		0x4C, 0x0A, 0x02, // 020A JMP $020A
+	}, 0x0200)
+
+	computer.Run(1000)
+	terminal.Send([]byte("x"))
+	computer.Run(1000)
+
+	if output := string(terminal.Output()); output != "KX" || computer.Bus.Read(0x0001) != 'X' {
+		t.Errorf("expected K and the X echoed, got %q", output)
+	}
+}
@@ -0,0 +1,950 @@
+package monitor
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/costamauricio/6502-emulator/pkg/cpu6502"
+	"github.com/costamauricio/6502-emulator/pkg/debugger"
+	"github.com/costamauricio/6502-emulator/pkg/debuginfo"
+	"github.com/costamauricio/6502-emulator/pkg/symbols"
+)
+
+func commandList() []*command {
+	return []*command{
+		{
+			names: []string{"help", "h", "?"},
+			usage: "help [command]",
+			help:  "Shows the commands or the help of a command",
+			execute: func(m *Monitor, args []string, line string) error {
+				return m.printHelp(line)
+			},
+		},
+		{
+			names:   []string{"registers", "r"},
+			usage:   "r [register=value ...]",
+			help:    "Shows or sets the registers A, X, Y, S, PC and P, or a flag C, Z, I, D, B, V, N",
+			execute: registers,
+		},
+		{
+			names:   []string{"step", "s", "z"},
+			usage:   "step [count]",
+			help:    "Executes count instructions, stepping into subroutines",
+			execute: step,
+		},
+		{
+			names:   []string{"next", "n"},
+			usage:   "next [count]",
+			help:    "Executes count instructions, stepping over subroutines",
+			execute: next,
+		},
+		{
+			names:   []string{"continue", "c", "g"},
+			usage:   "continue [address]",
+			help:    "Runs until a breakpoint or watchpoint, optionally from the address",
+			execute: cont,
+		},
+		{
+			names:   []string{"until", "u"},
+			usage:   "until address",
+			help:    "Runs until the PC reaches the address",
+			execute: until,
+		},
+		{
+			names:   []string{"back", "bs"},
+			usage:   "back [count]",
+			help:    "Steps back count instructions, needs the history",
+			execute: back,
+		},
+		{
+			names:   []string{"reverse", "rc"},
+			usage:   "reverse [count]",
+			help:    "Runs backwards until a breakpoint, a write or change watchpoint or the start of the history",
+			execute: reverse,
+		},
+		{
+			names:   []string{"history", "hist"},
+			usage:   "history [depth|off]",
+			help:    "Records the executed instructions to step back, shows the recorded count without arguments",
+			execute: history,
+		},
+		{
+			names: []string{"break", "b"},
+			usage: "break address|file:line [if condition]",
+			help:  "Adds a breakpoint",
+			execute: func(m *Monitor, args []string, line string) error {
+				return m.addBreakpoint(line, false)
+			},
+		},
+		{
+			names: []string{"tbreak", "tb"},
+			usage: "tbreak address|file:line [if condition]",
+			help:  "Adds a temporary breakpoint, removed after the first hit",
+			execute: func(m *Monitor, args []string, line string) error {
+				return m.addBreakpoint(line, true)
+			},
+		},
+		{
+			names: []string{"watch", "w"},
+			usage: "watch [r|w|c] start [end] [if condition]",
+			help:  "Halts when the range is read, written or changed (VALUE, PREVIOUS and ADDRESS in conditions)",
+			execute: func(m *Monitor, args []string, line string) error {
+				return m.addWatchpoint(line, false)
+			},
+		},
+		{
+			names: []string{"trace", "tr"},
+			usage: "trace [r|w|c] start [end] [if condition]",
+			help:  "Logs when the range is read, written or changed without halting",
+			execute: func(m *Monitor, args []string, line string) error {
+				return m.addWatchpoint(line, true)
+			},
+		},
+		{
+			names:   []string{"list", "bl"},
+			usage:   "list",
+			help:    "Lists the breakpoints and watchpoints",
+			execute: list,
+		},
+		{
+			names:   []string{"delete", "del"},
+			usage:   "delete id",
+			help:    "Removes a breakpoint",
+			execute: deleteBreakpoint,
+		},
+		{
+			names:   []string{"unwatch"},
+			usage:   "unwatch id",
+			help:    "Removes a watchpoint",
+			execute: unwatch,
+		},
+		{
+			names: []string{"enable"},
+			usage: "enable id",
+			help:  "Enables a breakpoint",
+			execute: func(m *Monitor, args []string, line string) error {
+				return m.setDisabled(args, false)
+			},
+		},
+		{
+			names: []string{"disable"},
+			usage: "disable id",
+			help:  "Disables a breakpoint without removing it",
+			execute: func(m *Monitor, args []string, line string) error {
+				return m.setDisabled(args, true)
+			},
+		},
+		{
+			names:   []string{"ignore"},
+			usage:   "ignore id count",
+			help:    "Skips the next count hits of a breakpoint",
+			execute: ignore,
+		},
+		{
+			names:   []string{"condition", "cond"},
+			usage:   "condition id [condition]",
+			help:    "Sets or removes the condition of a breakpoint",
+			execute: condition,
+		},
+		{
+			names:   []string{"mem", "m"},
+			usage:   "mem [start] [end]",
+			help:    "Displays the memory",
+			execute: mem,
+		},
+		{
+			names:   []string{"fill", "f"},
+			usage:   "fill start end byte [byte ...]",
+			help:    "Fills the memory range repeating the bytes",
+			execute: fill,
+		},
+		{
+			names:   []string{"edit", "e", ">"},
+			usage:   "edit address byte [byte ...]",
+			help:    "Writes the bytes to memory",
+			execute: edit,
+		},
+		{
+			names:   []string{"disasm", "d"},
+			usage:   "disasm [address] [count]",
+			help:    "Disassembles count instructions from the address",
+			execute: disasm,
+		},
+		{
+			names:   []string{"load", "l"},
+			usage:   "load file address",
+			help:    "Loads a binary file to memory",
+			execute: load,
+		},
+		{
+			names:   []string{"debuginfo", "di"},
+			usage:   "debuginfo file",
+			help:    "Loads the debug information (ca65 --dbgfile or JSON) to show labels and source lines",
+			execute: loadDebugInfo,
+		},
+		{
+			names:   []string{"symbols", "sym"},
+			usage:   "symbols [file|" + strings.Join(symbols.Machines(), "|") + "]",
+			help:    "Loads a label file (VICE, ld65 -Ln or name = $addr) or the hardware registers of a memory map, lists the symbols without arguments",
+			execute: loadSymbols,
+		},
+		{
+			names:   []string{"save"},
+			usage:   "save file start end",
+			help:    "Saves the memory range to a binary file",
+			execute: save,
+		},
+		{
+			names:   []string{"stopwatch", "sw"},
+			usage:   "stopwatch [reset]",
+			help:    "Shows the cycles executed since the stopwatch reset, to measure the cost of a routine",
+			execute: stopwatch,
+		},
+		{
+			names: []string{"reset"},
+			usage: "reset",
+			help:  "Resets the CPU",
+			execute: func(m *Monitor, args []string, line string) error {
+				m.Debugger.Reset()
+				if m.Debugger.History != nil {
+					m.Debugger.History.Clear()
+				}
+
+				m.Debugger.Step()
+				m.printStop(debugger.Stop{Reason: debugger.STOP_STEP})
+				return nil
+			},
+		},
+		{
+			names: []string{"irq"},
+			usage: "irq",
+			help:  "Requests an interrupt and steps into the handler, refused when the I flag is set",
+			execute: func(m *Monitor, args []string, line string) error {
+				if m.Debugger.Cpu.GetFlag(cpu6502.FLAG_I) > 0 {
+					return fmt.Errorf("the interrupt is ignored, the I flag is set")
+				}
+
+				m.Debugger.InterruptRequest()
+				m.Debugger.Step()
+				m.printStop(debugger.Stop{Reason: debugger.STOP_STEP})
+				return nil
+			},
+		},
+		{
+			names: []string{"nmi"},
+			usage: "nmi",
+			help:  "Triggers a non maskable interrupt",
+			execute: func(m *Monitor, args []string, line string) error {
+				m.Debugger.NonMaskableInterrupt()
+				m.Debugger.Step()
+				m.printStop(debugger.Stop{Reason: debugger.STOP_STEP})
+				return nil
+			},
+		},
+		{
+			names: []string{"quit", "q", "x"},
+			usage: "quit",
+			help:  "Exits the monitor",
+			execute: func(m *Monitor, args []string, line string) error {
+				m.quit = true
+				return nil
+			},
+		},
+	}
+}
+
+// Parses an optional count argument
+func (m *Monitor) count(args []string) (int, error) {
+	if len(args) == 0 {
+		return 1, nil
+	}
+
+	count, err := m.eval(args[0])
+	if err != nil {
+		return 0, err
+	}
+
+	if count < 1 {
+		return 0, fmt.Errorf("invalid count: %d", count)
+	}
+
+	return count, nil
+}
+
+// Splits "arguments if condition"
+func splitCondition(line string) ([]string, string) {
+	fields := strings.Fields(line)
+
+	for index, field := range fields {
+		if strings.ToLower(field) == "if" {
+			return fields[:index], strings.Join(fields[index+1:], " ")
+		}
+	}
+
+	return fields, ""
+}
+
+func registers(m *Monitor, args []string, line string) error {
+	cpu := m.Debugger.Cpu
+
+	for _, arg := range args {
+		parts := strings.SplitN(arg, "=", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("expected register=value, got %q", arg)
+		}
+
+		value, err := m.eval(parts[1])
+		if err != nil {
+			return err
+		}
+
+		name := strings.ToUpper(parts[0])
+
+		switch name {
+		case "A":
+			cpu.A = byte(value)
+		case "X":
+			cpu.X = byte(value)
+		case "Y":
+			cpu.Y = byte(value)
+		case "S", "SP":
+			cpu.S = byte(value)
+		case "P":
+			cpu.Status = byte(value)
+		case "PC":
+			cpu.PC = uint16(value)
+		default:
+			flag, found := flags[name]
+			if !found {
+				return fmt.Errorf("unknown register %q", parts[0])
+			}
+
+			cpu.SetFlag(flag, value != 0)
+		}
+	}
+
+	m.printRegisters()
+	return nil
+}
+
+var flags = map[string]cpu6502.Flag{
+	"C": cpu6502.FLAG_C,
+	"Z": cpu6502.FLAG_Z,
+	"I": cpu6502.FLAG_I,
+	"D": cpu6502.FLAG_D,
+	"B": cpu6502.FLAG_B,
+	"V": cpu6502.FLAG_V,
+	"N": cpu6502.FLAG_N,
+}
+
+func step(m *Monitor, args []string, line string) error {
+	count, err := m.count(args)
+	if err != nil {
+		return err
+	}
+
+	m.printStop(m.Debugger.Continue(count))
+	return nil
+}
+
+func next(m *Monitor, args []string, line string) error {
+	count, err := m.count(args)
+	if err != nil {
+		return err
+	}
+
+	var stop debugger.Stop
+
+	for executed := 0; executed < count; executed++ {
+		operation, _ := cpu6502.Decode(m.Debugger.Peek, m.Debugger.Cpu.PC)
+
+		if operation.Instruction == cpu6502.INS_JSR {
+			stop = m.Debugger.RunTo(operation.Address + operation.Size)
+		} else {
+			stop = m.Debugger.Continue(1)
+		}
+
+		// a temporary breakpoint after the JSR also stops as a breakpoint
+		if stop.Reason != debugger.STOP_STEP && (stop.Breakpoint == nil || !stop.Breakpoint.Temporary) {
+			break
+		}
+
+		stop = debugger.Stop{Reason: debugger.STOP_STEP}
+	}
+
+	m.printStop(stop)
+	return nil
+}
+
+func cont(m *Monitor, args []string, line string) error {
+	if len(args) > 0 {
+		address, err := m.evalAddress(args[0])
+		if err != nil {
+			return err
+		}
+
+		m.Debugger.Cpu.PC = address
+	}
+
+	m.printStop(m.Debugger.Continue(0))
+	return nil
+}
+
+func back(m *Monitor, args []string, line string) error {
+	if m.Debugger.History == nil {
+		return fmt.Errorf("the history is off, enable it with history")
+	}
+
+	count, err := m.count(args)
+	if err != nil {
+		return err
+	}
+
+	m.printStop(m.Debugger.ReverseContinue(count))
+	return nil
+}
+
+func reverse(m *Monitor, args []string, line string) error {
+	if m.Debugger.History == nil {
+		return fmt.Errorf("the history is off, enable it with history")
+	}
+
+	count := 0
+	if len(args) > 0 {
+		var err error
+		if count, err = m.count(args); err != nil {
+			return err
+		}
+	}
+
+	m.printStop(m.Debugger.ReverseContinue(count))
+	return nil
+}
+
+func history(m *Monitor, args []string, line string) error {
+	if len(args) == 0 {
+		if m.Debugger.History == nil {
+			m.printf("history off\n")
+		} else {
+			m.printf("%d of %d instructions recorded\n", m.Debugger.History.Len(), m.Debugger.History.Depth())
+		}
+
+		return nil
+	}
+
+	if strings.ToLower(args[0]) == "off" {
+		m.Debugger.DisableHistory()
+		return nil
+	}
+
+	depth, err := m.count(args)
+	if err != nil {
+		return err
+	}
+
+	return m.Debugger.EnableHistory(depth)
+}
+
+func stopwatch(m *Monitor, args []string, line string) error {
+	cycles := m.Debugger.Cpu.Cycles()
+
+	if len(args) > 0 {
+		if strings.ToLower(args[0]) != "reset" {
+			return fmt.Errorf("usage: stopwatch [reset]")
+		}
+
+		m.stopwatch = cycles
+	}
+
+	m.printf("%d cycles, %d in total\n", cycles-m.stopwatch, cycles)
+	return nil
+}
+
+func until(m *Monitor, args []string, line string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: until address")
+	}
+
+	address, err := m.evalAddress(args[0])
+	if err != nil {
+		return err
+	}
+
+	stop := m.Debugger.RunTo(address)
+	if stop.Breakpoint != nil && stop.Breakpoint.Temporary && stop.Breakpoint.Address == address {
+		stop = debugger.Stop{Reason: debugger.STOP_STEP}
+	}
+
+	m.printStop(stop)
+	return nil
+}
+
+func (m *Monitor) addBreakpoint(line string, temporary bool) error {
+	args, source := splitCondition(line)
+	if len(args) != 1 {
+		return fmt.Errorf("usage: break address [if condition]")
+	}
+
+	address, err := m.evalAddress(args[0])
+	if err != nil {
+		return err
+	}
+
+	var breakpoint *debugger.Breakpoint
+
+	if source != "" {
+		if breakpoint, err = m.Debugger.AddConditionalBreakpoint(address, source, m.symbols()); err != nil {
+			return err
+		}
+	} else {
+		breakpoint = m.Debugger.Breakpoints.Add(debugger.Breakpoint{Address: address})
+	}
+
+	breakpoint.Temporary = temporary
+
+	m.printf("%s\n", breakpoint)
+	return nil
+}
+
+func (m *Monitor) addWatchpoint(line string, log bool) error {
+	if m.Debugger.Watch == nil {
+		return fmt.Errorf("watchpoints aren't available, the CPU bus isn't watched")
+	}
+
+	args, source := splitCondition(line)
+
+	access := debugger.ACCESS_WRITE
+	if len(args) > 0 {
+		if parsed, ok := parseAccess(args[0]); ok {
+			access = parsed
+			args = args[1:]
+		}
+	}
+
+	if len(args) < 1 || len(args) > 2 {
+		return fmt.Errorf("usage: watch [r|w|c] start [end] [if condition]")
+	}
+
+	start, err := m.evalAddress(args[0])
+	if err != nil {
+		return err
+	}
+
+	end := start
+	if len(args) > 1 {
+		if end, err = m.evalAddress(args[1]); err != nil {
+			return err
+		}
+	}
+
+	watchpoint := debugger.Watchpoint{Start: start, End: end, Access: access, Log: log, Expression: source}
+
+	if source != "" {
+		watchpoint.Condition, err = debugger.CompileWatchCondition(source, m.Debugger.Cpu, m.Debugger.Bus, m.symbols())
+		if err != nil {
+			return err
+		}
+	}
+
+	m.printf("%s\n", m.Debugger.Watch.Add(watchpoint))
+	return nil
+}
+
+// Parses accesses like "r", "w", "c" or combinations like "rw"
+func parseAccess(text string) (debugger.Access, bool) {
+	var access debugger.Access
+
+	for _, char := range strings.ToLower(text) {
+		switch char {
+		case 'r':
+			access |= debugger.ACCESS_READ
+		case 'w':
+			access |= debugger.ACCESS_WRITE
+		case 'c':
+			access |= debugger.ACCESS_CHANGE
+		default:
+			return 0, false
+		}
+	}
+
+	return access, access != 0
+}
+
+func list(m *Monitor, args []string, line string) error {
+	breakpoints := m.Debugger.Breakpoints.List()
+
+	for _, breakpoint := range breakpoints {
+		m.printf("%s, hit %d times\n", breakpoint, breakpoint.Hits)
+	}
+
+	var watchpoints []*debugger.Watchpoint
+	if m.Debugger.Watch != nil {
+		watchpoints = m.Debugger.Watch.Watchpoints()
+	}
+
+	for _, watchpoint := range watchpoints {
+		m.printf("%s\n", watchpoint)
+	}
+
+	if len(breakpoints) == 0 && len(watchpoints) == 0 {
+		m.printf("no breakpoints or watchpoints\n")
+	}
+
+	return nil
+}
+
+func (m *Monitor) breakpoint(args []string) (*debugger.Breakpoint, error) {
+	if len(args) < 1 {
+		return nil, fmt.Errorf("missing breakpoint id")
+	}
+
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid breakpoint id %q", args[0])
+	}
+
+	breakpoint := m.Debugger.Breakpoints.Get(id)
+	if breakpoint == nil {
+		return nil, fmt.Errorf("no breakpoint %d", id)
+	}
+
+	return breakpoint, nil
+}
+
+func deleteBreakpoint(m *Monitor, args []string, line string) error {
+	breakpoint, err := m.breakpoint(args)
+	if err != nil {
+		return err
+	}
+
+	m.Debugger.Breakpoints.Remove(breakpoint.Id)
+	return nil
+}
+
+func unwatch(m *Monitor, args []string, line string) error {
+	if len(args) != 1 || m.Debugger.Watch == nil {
+		return fmt.Errorf("usage: unwatch id")
+	}
+
+	id, err := strconv.Atoi(args[0])
+	if err != nil || !m.Debugger.Watch.Remove(id) {
+		return fmt.Errorf("no watchpoint %s", args[0])
+	}
+
+	return nil
+}
+
+func (m *Monitor) setDisabled(args []string, disabled bool) error {
+	breakpoint, err := m.breakpoint(args)
+	if err != nil {
+		return err
+	}
+
+	breakpoint.Disabled = disabled
+	return nil
+}
+
+func ignore(m *Monitor, args []string, line string) error {
+	breakpoint, err := m.breakpoint(args)
+	if err != nil {
+		return err
+	}
+
+	if len(args) != 2 {
+		return fmt.Errorf("usage: ignore id count")
+	}
+
+	count, err := m.eval(args[1])
+	if err != nil {
+		return err
+	}
+
+	if count < 0 {
+		return fmt.Errorf("invalid count: %d", count)
+	}
+
+	breakpoint.IgnoreCount = count
+
+	m.printf("%s\n", breakpoint)
+	return nil
+}
+
+func condition(m *Monitor, args []string, line string) error {
+	breakpoint, err := m.breakpoint(args)
+	if err != nil {
+		return err
+	}
+
+	source := strings.TrimSpace(strings.TrimPrefix(line, args[0]))
+	if source == "" {
+		breakpoint.Condition, breakpoint.Expression = nil, ""
+	} else {
+		compiled, err := debugger.CompileCondition(source, m.symbols())
+		if err != nil {
+			return err
+		}
+
+		breakpoint.Condition, breakpoint.Expression = compiled, source
+	}
+
+	m.printf("%s\n", breakpoint)
+	return nil
+}
+
+func mem(m *Monitor, args []string, line string) error {
+	start := m.nextMemory
+	end := uint(start) + 0x7F
+
+	if len(args) > 0 {
+		address, err := m.evalAddress(args[0])
+		if err != nil {
+			return err
+		}
+
+		start, end = address, uint(address)+0x7F
+	}
+
+	if len(args) > 1 {
+		address, err := m.evalAddress(args[1])
+		if err != nil {
+			return err
+		}
+
+		if address < start {
+			return fmt.Errorf("end $%04X is before start $%04X", address, start)
+		}
+
+		end = uint(address)
+	}
+
+	if end > 0xFFFF {
+		end = 0xFFFF
+	}
+
+	for row := uint(start); row <= end; row += 16 {
+		var hex, text string
+
+		for column := row; column < row+16 && column <= end; column++ {
+			data := m.Debugger.Peek(uint16(column))
+			hex += fmt.Sprintf("%02X ", data)
+
+			if data >= 0x20 && data < 0x7F {
+				text += string(rune(data))
+			} else {
+				text += "."
+			}
+		}
+
+		m.printf("$%04X  %-48s %s\n", row, hex, text)
+	}
+
+	m.nextMemory = uint16(end + 1)
+	return nil
+}
+
+func (m *Monitor) bytes(args []string) ([]byte, error) {
+	var data []byte
+
+	for _, arg := range args {
+		value, err := m.evalByte(arg)
+		if err != nil {
+			return nil, err
+		}
+
+		data = append(data, value)
+	}
+
+	return data, nil
+}
+
+func fill(m *Monitor, args []string, line string) error {
+	if len(args) < 3 {
+		return fmt.Errorf("usage: fill start end byte [byte ...]")
+	}
+
+	start, err := m.evalAddress(args[0])
+	if err != nil {
+		return err
+	}
+
+	end, err := m.evalAddress(args[1])
+	if err != nil {
+		return err
+	}
+
+	if end < start {
+		return fmt.Errorf("end $%04X is before start $%04X", end, start)
+	}
+
+	data, err := m.bytes(args[2:])
+	if err != nil {
+		return err
+	}
+
+	for address := uint(start); address <= uint(end); address++ {
+		m.Debugger.Poke(uint16(address), data[(address-uint(start))%uint(len(data))])
+	}
+
+	return nil
+}
+
+func edit(m *Monitor, args []string, line string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("usage: edit address byte [byte ...]")
+	}
+
+	address, err := m.evalAddress(args[0])
+	if err != nil {
+		return err
+	}
+
+	data, err := m.bytes(args[1:])
+	if err != nil {
+		return err
+	}
+
+	for index, value := range data {
+		m.Debugger.Poke(address+uint16(index), value)
+	}
+
+	return nil
+}
+
+func disasm(m *Monitor, args []string, line string) error {
+	address := m.nextDisasm
+	count := 16
+
+	if len(args) > 0 {
+		parsed, err := m.evalAddress(args[0])
+		if err != nil {
+			return err
+		}
+
+		address = parsed
+	}
+
+	if len(args) > 1 {
+		parsed, err := m.count(args[1:])
+		if err != nil {
+			return err
+		}
+
+		count = parsed
+	}
+
+	for index := 0; index < count; index++ {
+		address = m.printInstruction(address)
+	}
+
+	m.nextDisasm = address
+	return nil
+}
+
+func loadDebugInfo(m *Monitor, args []string, line string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: debuginfo file")
+	}
+
+	info, err := debuginfo.Load(args[0])
+	if err != nil {
+		return err
+	}
+
+	m.Debugger.DebugInfo = info
+	m.printf("loaded %d lines and %d symbols\n", len(info.Lines), len(info.Symbols))
+
+	return nil
+}
+
+func loadSymbols(m *Monitor, args []string, line string) error {
+	if len(args) == 0 {
+		if m.Debugger.Symbols == nil {
+			return nil
+		}
+
+		for _, symbol := range m.Debugger.Symbols.Symbols() {
+			m.printf("$%04X  %s\n", symbol.Address, symbol.Name)
+		}
+
+		return nil
+	}
+
+	if len(args) != 1 {
+		return fmt.Errorf("usage: symbols [file|machine]")
+	}
+
+	table, err := symbols.Open(args[0])
+	if err != nil {
+		return err
+	}
+
+	if m.Debugger.Symbols == nil {
+		m.Debugger.Symbols = symbols.New()
+	}
+
+	m.Debugger.Symbols.Merge(table)
+	m.printf("loaded %d symbols\n", table.Len())
+
+	return nil
+}
+
+func load(m *Monitor, args []string, line string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("usage: load file address")
+	}
+
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+
+	address, err := m.evalAddress(args[1])
+	if err != nil {
+		return err
+	}
+
+	if int(address)+len(data) > 0x10000 {
+		return fmt.Errorf("%d bytes don't fit at $%04X", len(data), address)
+	}
+
+	for index, value := range data {
+		m.Debugger.Poke(address+uint16(index), value)
+	}
+
+	m.printf("loaded $%04X-$%04X\n", address, int(address)+len(data)-1)
+	return nil
+}
+
+func save(m *Monitor, args []string, line string) error {
+	if len(args) != 3 {
+		return fmt.Errorf("usage: save file start end")
+	}
+
+	start, err := m.evalAddress(args[1])
+	if err != nil {
+		return err
+	}
+
+	end, err := m.evalAddress(args[2])
+	if err != nil {
+		return err
+	}
+
+	if end < start {
+		return fmt.Errorf("end $%04X is before start $%04X", end, start)
+	}
+
+	var data []byte
+	for address := uint(start); address <= uint(end); address++ {
+		data = append(data, m.Debugger.Peek(uint16(address)))
+	}
+
+	if err := os.WriteFile(args[0], data, 0644); err != nil {
+		return err
+	}
+
+	m.printf("saved $%04X-$%04X\n", start, end)
+	return nil
+}
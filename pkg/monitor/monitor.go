@@ -0,0 +1,275 @@
+// Terminal monitor to debug programs over plain text input and output
+// in the spirit of the VICE monitor and WozMon
+package monitor
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/costamauricio/6502-emulator/pkg/cpu6502"
+	"github.com/costamauricio/6502-emulator/pkg/debugger"
+	"github.com/costamauricio/6502-emulator/pkg/expr"
+)
+
+const prompt = "(6502) "
+
+type command struct {
+	names   []string
+	usage   string
+	help    string
+	execute func(m *Monitor, args []string, line string) error
+}
+
+type Monitor struct {
+	Debugger *debugger.Debugger
+	Symbols  expr.Symbols // Optional, resolves the names in the expressions instead of the debugger symbols
+
+	in  *bufio.Scanner
+	out io.Writer
+
+	list        []*command
+	commands    map[string]*command
+	quit        bool
+	lastCommand string
+	nextMemory  uint16 // Where a memory display without address continues
+	nextDisasm  uint16 // Where a disassembly without address continues
+	stopwatch   uint64 // CPU cycles when the stopwatch was reset
+}
+
+func New(dbg *debugger.Debugger, in io.Reader, out io.Writer) *Monitor {
+	m := &Monitor{
+		Debugger: dbg,
+		in:       bufio.NewScanner(in),
+		out:      out,
+		list:     commandList(),
+		commands: make(map[string]*command),
+	}
+
+	for _, command := range m.list {
+		for _, name := range command.names {
+			m.commands[name] = command
+		}
+	}
+
+	if dbg.Watch != nil && dbg.Watch.OnHit == nil {
+		dbg.Watch.OnHit = func(hit debugger.Hit) {
+			if hit.Watchpoint.Log {
+				m.printf("%s\n", hit.Format(m.Debugger.Name))
+			}
+		}
+	}
+
+	m.nextMemory = dbg.Cpu.PC
+	m.nextDisasm = dbg.Cpu.PC
+
+	return m
+}
+
+// Reads and executes the commands until the input ends or the quit command
+func (m *Monitor) Run() error {
+	m.printRegisters()
+
+	for !m.quit {
+		m.printf("%s", prompt)
+
+		if !m.in.Scan() {
+			m.printf("\n")
+			return m.in.Err()
+		}
+
+		line := strings.TrimSpace(m.in.Text())
+
+		// an empty line repeats the steps, like the usual debuggers
+		if line == "" {
+			if !m.repeatable() {
+				continue
+			}
+
+			line = m.lastCommand
+		}
+
+		if err := m.Execute(line); err != nil {
+			m.printf("error: %s\n", err)
+		}
+	}
+
+	return nil
+}
+
+// Executes a single command line
+func (m *Monitor) Execute(line string) error {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return nil
+	}
+
+	command, found := m.commands[strings.ToLower(fields[0])]
+	if !found {
+		return fmt.Errorf("unknown command %q, try help", fields[0])
+	}
+
+	m.lastCommand = line
+
+	rest := strings.TrimSpace(line[len(fields[0]):])
+	return command.execute(m, fields[1:], rest)
+}
+
+func (m *Monitor) repeatable() bool {
+	fields := strings.Fields(m.lastCommand)
+	if len(fields) == 0 {
+		return false
+	}
+
+	switch strings.ToLower(fields[0]) {
+	case "s", "step", "z", "n", "next", "bs", "back", "m", "mem", "d", "disasm":
+		return true
+	}
+
+	return false
+}
+
+func (m *Monitor) printf(format string, args ...interface{}) {
+	fmt.Fprintf(m.out, format, args...)
+}
+
+// Evaluates an expression used as a command argument
+func (m *Monitor) eval(source string) (int, error) {
+	return expr.Eval(source, m.env())
+}
+
+// Evaluates an address argument, with debug information it also accepts source locations as "file:line"
+func (m *Monitor) evalAddress(source string) (uint16, error) {
+	if info := m.Debugger.DebugInfo; info != nil {
+		if file, line, found := strings.Cut(source, ":"); found && file != "" {
+			if number, err := strconv.Atoi(line); err == nil {
+				address, _, found := info.NearestLine(file, number)
+				if !found {
+					return 0, fmt.Errorf("no code at %s", source)
+				}
+
+				return address, nil
+			}
+		}
+	}
+
+	expression, err := expr.Parse(source)
+	if err != nil {
+		return 0, err
+	}
+
+	return expr.Address(expression, m.env())
+}
+
+func (m *Monitor) evalByte(source string) (byte, error) {
+	value, err := m.eval(source)
+	if err != nil {
+		return 0, err
+	}
+
+	if value < -128 || value > 0xFF {
+		return 0, fmt.Errorf("value out of byte range: %s = %d", source, value)
+	}
+
+	return byte(value), nil
+}
+
+func (m *Monitor) env() *expr.Env {
+	return &expr.Env{Cpu: m.Debugger.Cpu, Bus: m.Debugger.Bus, Symbols: m.symbols()}
+}
+
+// Symbols used in the expressions, defaults to the debugger ones
+func (m *Monitor) symbols() expr.Symbols {
+	if m.Symbols == nil {
+		return m.Debugger
+	}
+
+	return m.Symbols
+}
+
+func (m *Monitor) printRegisters() {
+	cpu := m.Debugger.Cpu
+
+	flags := []byte("NV-BDIZC")
+	for index := range flags {
+		if cpu.Status&(0x80>>index) == 0 {
+			flags[index] = byte(strings.ToLower(string(flags[index]))[0])
+		}
+	}
+
+	m.printf("PC=$%04X A=$%02X X=$%02X Y=$%02X S=$%02X P=$%02X %s\n", cpu.PC, cpu.A, cpu.X, cpu.Y, cpu.S, cpu.Status, flags)
+}
+
+// Prints the instruction at the address, returning the next instruction address
+func (m *Monitor) printInstruction(address uint16) uint16 {
+	operation, _ := cpu6502.Decode(m.Debugger.Peek, address)
+
+	var raw string
+	for index := uint16(0); index < operation.Size; index++ {
+		raw += fmt.Sprintf("%02X ", m.Debugger.Peek(address+index))
+	}
+
+	marker := " "
+	if len(m.Debugger.Breakpoints.At(address)) > 0 {
+		marker = "*"
+	}
+
+	if label, found := m.Debugger.Label(address); found {
+		m.printf("%s:\n", label)
+	}
+
+	instruction := operation.Format(m.Debugger.Name)
+
+	if source, found := m.Debugger.SourceLine(address); found {
+		m.printf("%s$%04X  %-9s %-16s ; %s\n", marker, address, raw, instruction, source)
+	} else {
+		m.printf("%s$%04X  %-9s %s\n", marker, address, raw, instruction)
+	}
+
+	return address + operation.Size
+}
+
+func (m *Monitor) printStop(stop debugger.Stop) {
+	switch stop.Reason {
+	case debugger.STOP_BREAKPOINT:
+		m.printf("%s\n", stop.Breakpoint)
+	case debugger.STOP_WATCHPOINT:
+		for _, hit := range stop.Hits {
+			m.printf("%s\n", hit.Format(m.Debugger.Name))
+		}
+	case debugger.STOP_INTERRUPTED:
+		m.printf("interrupted\n")
+	case debugger.STOP_HISTORY:
+		m.printf("start of the history\n")
+	}
+
+	m.printRegisters()
+	m.nextDisasm = m.printInstruction(m.Debugger.Cpu.PC)
+}
+
+func (m *Monitor) printHelp(name string) error {
+	if name != "" {
+		command, found := m.commands[strings.ToLower(name)]
+		if !found {
+			return fmt.Errorf("unknown command %q", name)
+		}
+
+		m.printf("%s\n    %s\n", command.usage, command.help)
+		return nil
+	}
+
+	var lines []string
+	for _, command := range m.list {
+		lines = append(lines, fmt.Sprintf("  %-40s %s", command.usage, command.help))
+	}
+
+	sort.Strings(lines)
+	m.printf("%s\n", strings.Join(lines, "\n"))
+	m.printf("Arguments are expressions: $hex, %%binary, decimal, registers, [byte], {word} and symbols\n")
+	m.printf("With debug information addresses can also be source locations as file:line\n")
+
+	return nil
+}
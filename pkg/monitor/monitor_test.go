@@ -0,0 +1,142 @@
+package monitor
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/costamauricio/6502-emulator/pkg/bus"
+	"github.com/costamauricio/6502-emulator/pkg/cpu6502"
+	"github.com/costamauricio/6502-emulator/pkg/debugger"
+	"github.com/costamauricio/6502-emulator/pkg/debuginfo"
+	"github.com/costamauricio/6502-emulator/pkg/symbols"
+)
+
+func TestSession(t *testing.T) {
+	dataBus := &bus.Bus{}
+	// JSR sub; LDX #$02; BRK; sub: LDA #$10; STA $0200; RTS
+	dataBus.LoadRamFromString("20 06 80 A2 02 00 A9 10 8D 00 02 60", 0x8000)
+	dataBus.LoadRamFromString("00 80", 0xFFFC)
+
+	watchBus := debugger.NewWatchBus(dataBus)
+	dbg := debugger.New(cpu6502.New(watchBus), watchBus)
+	dbg.Step()
+
+	script := strings.Join([]string{
+		"sw reset",
+		"next",
+		"sw",
+		"r",
+		"r pc=$8000 a=0",
+		"watch w $0200",
+		"c",
+		"edit $0300 1 2 3",
+		"fill $0303 $0305 $FF",
+		"m $0300 $0305",
+		"fill $0305 $0303 0",
+		"m $0305 $0300",
+		"d $8006 1",
+		"break $8003 if A == $10",
+		"ignore 2 -1",
+		"c",
+		"bogus",
+		"quit",
+	}, "\n")
+
+	var out bytes.Buffer
+	if err := New(dbg, strings.NewReader(script), &out).Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"PC=$8003 A=$10",
+		"18 cycles, 24 in total",
+		"PC=$8000 A=$00",
+		"watchpoint 1: write $10 to $0200 by $8008: STA $0200",
+		"$0300  01 02 03 FF FF FF",
+		" $8006  A9 10     LDA #$10",
+		"error: end $0303 is before start $0305",
+		"error: end $0300 is before start $0305",
+		"breakpoint 2 at $8003 if A == $10",
+		"error: invalid count: -1",
+		"*$8003  A2 02     LDX #$02",
+		"error: unknown command \"bogus\"",
+	}
+
+	for _, line := range expected {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("expected %q in the output:\n%s", line, out.String())
+		}
+	}
+}
+
+func TestDebugInfo(t *testing.T) {
+	dataBus := &bus.Bus{}
+	// JSR sub; LDX #$02; BRK; sub: LDA #$10; STA $0200; RTS
+	dataBus.LoadRamFromString("20 06 80 A2 02 00 A9 10 8D 00 02 60", 0x8000)
+	dataBus.LoadRamFromString("00 80", 0xFFFC)
+
+	dbg := debugger.New(cpu6502.New(dataBus), dataBus)
+	dbg.Step()
+
+	dbg.DebugInfo = &debuginfo.Info{
+		Lines: []debuginfo.LineRange{
+			{File: "main.s", Line: 1, Start: 0x8000, Size: 3},
+			{File: "main.s", Line: 2, Start: 0x8003, Size: 2},
+			{File: "main.s", Line: 6, Start: 0x8006, Size: 2},
+		},
+		Symbols: []debuginfo.Symbol{{Name: "reset", Address: 0x8000}, {Name: "sub", Address: 0x8006}},
+	}
+
+	dbg.Symbols = symbols.New()
+	dbg.Symbols.Set("RESULT", 0x01FF)
+
+	script := strings.Join([]string{
+		"d reset 1",
+		"d $8008 1",
+		"break main.s:4",
+		"c",
+	}, "\n")
+
+	var out bytes.Buffer
+	if err := New(dbg, strings.NewReader(script), &out).Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"reset:\n $8000  20 06 80  JSR sub          ; main.s:1",
+		"$8008  8D 00 02  STA RESULT+1",
+		"breakpoint 1 at $8006",
+		"sub:\n*$8006  A9 10     LDA #$10         ; main.s:6",
+	}
+
+	for _, line := range expected {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("expected %q in the output:\n%s", line, out.String())
+		}
+	}
+}
+
+func TestInterruptIgnored(t *testing.T) {
+	dataBus := &bus.Bus{}
+	// SEI; NOP; NOP
+	dataBus.LoadRamFromString("78 EA EA", 0x8000)
+	dataBus.LoadRamFromString("00 80", 0xFFFC)
+	dataBus.LoadRamFromString("00 90", 0xFFFE)
+
+	dbg := debugger.New(cpu6502.New(dataBus), dataBus)
+	dbg.Step()
+
+	var out bytes.Buffer
+	if err := New(dbg, strings.NewReader("step\nirq\nquit"), &out).Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(out.String(), "error: the interrupt is ignored, the I flag is set") {
+		t.Errorf("expected the interrupt refused:\n%s", out.String())
+	}
+
+	if dbg.Cpu.PC != 0x8001 {
+		t.Errorf("expected no instruction executed, PC at $%04X", dbg.Cpu.PC)
+	}
+}
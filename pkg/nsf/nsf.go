@@ -0,0 +1,317 @@
+// Player of NES Sound Format files
+//
+// An NSF is the music code and data ripped from a NES game, with the INIT and PLAY routines to
+// call. The player runs them on the CPU with just what they need of the NES: the 2K of RAM, the
+// 8K of WRAM at $6000, the APU and the 4K banks of the ROM switched on $5FF8-$5FFF:
+//
+//	file, _ := nsf.Parse(data)
+//	player := nsf.NewPlayer(file, 44100)
+//	player.Init(file.Start)
+//	wav.Write(output, player.Render(time.Minute), 44100)
+//
+// INIT is called once for the song and PLAY at the rate of the header. The expansion sound chips
+// aren't emulated, only the APU channels are heard.
+package nsf
+
+import (
+	"bytes"
+	"errors"
+	"time"
+
+	"github.com/costamauricio/6502-emulator/pkg/apu2a03"
+	"github.com/costamauricio/6502-emulator/pkg/bus"
+	"github.com/costamauricio/6502-emulator/pkg/cpu6502"
+	"github.com/costamauricio/6502-emulator/pkg/system"
+)
+
+const (
+	HEADER_SIZE = 0x80
+	MAGIC       = "NESM\x1A"
+	BANK_SIZE   = 0x1000
+)
+
+// Memory map
+const (
+	RAM_END    = 0x07FF
+	APU        = 0x4000
+	APU_END    = 0x4017
+	IDLE       = 0x4100 // Return address of the calls, the CPU waits in a loop there
+	BANKS      = 0x5FF8 // 8 registers selecting the bank of each 4K from $8000
+	WRAM       = 0x6000
+	WRAM_END   = 0x7FFF
+	ROM        = 0x8000
+	INIT_LIMIT = 10 * apu2a03.FREQUENCY_NTSC // Cicles INIT can take to return
+)
+
+// Region byte
+const (
+	REGION_PAL  = 0x01
+	REGION_DUAL = 0x02
+)
+
+// Play periods in microseconds used when the header doesn't have them
+const (
+	SPEED_NTSC = 16639
+	SPEED_PAL  = 19997
+)
+
+var (
+	ErrInvalidNSF  = errors.New("invalid NSF, the header is missing")
+	ErrLoadAddress = errors.New("NSF without bankswitching loaded below $8000")
+	ErrSong        = errors.New("song out of range")
+	ErrInitLimit   = errors.New("INIT didn't return")
+)
+
+type NSF struct {
+	Version   byte
+	Songs     byte
+	Start     byte // First song played, from 1
+	Load      uint16
+	Init      uint16
+	Play      uint16
+	Name      string
+	Artist    string
+	Copyright string
+	SpeedNTSC uint16 // Microseconds between the PLAY calls
+	SpeedPAL  uint16
+	Banks     [8]byte // Initial banks, all 0 without bankswitching
+	Region    byte
+	Chips     byte // Expansion sound chips
+	Data      []byte
+}
+
+func Parse(data []byte) (*NSF, error) {
+	if len(data) < HEADER_SIZE || string(data[:len(MAGIC)]) != MAGIC {
+		return nil, ErrInvalidNSF
+	}
+
+	word := func(offset int) uint16 {
+		return uint16(data[offset]) | uint16(data[offset+1])<<8
+	}
+
+	text := func(offset int) string {
+		field := data[offset : offset+32]
+		if end := bytes.IndexByte(field, 0); end >= 0 {
+			field = field[:end]
+		}
+
+		return string(field)
+	}
+
+	file := &NSF{
+		Version:   data[0x05],
+		Songs:     data[0x06],
+		Start:     data[0x07],
+		Load:      word(0x08),
+		Init:      word(0x0A),
+		Play:      word(0x0C),
+		Name:      text(0x0E),
+		Artist:    text(0x2E),
+		Copyright: text(0x4E),
+		SpeedNTSC: word(0x6E),
+		SpeedPAL:  word(0x78),
+		Region:    data[0x7A],
+		Chips:     data[0x7B],
+		Data:      data[HEADER_SIZE:],
+	}
+	copy(file.Banks[:], data[0x70:0x78])
+
+	return file, nil
+}
+
+// Whether the data is switched in banks, otherwise it's at the load address
+func (n *NSF) Bankswitched() bool {
+	return n.Banks != [8]byte{}
+}
+
+// Whether it only plays on the PAL clock
+func (n *NSF) PAL() bool {
+	return n.Region&REGION_PAL > 0 && n.Region&REGION_DUAL == 0
+}
+
+type Player struct {
+	Cpu    *cpu6502.CPU
+	Bus    *bus.Bus
+	System *system.System
+	Apu    *apu2a03.APU
+	File   *NSF
+	PAL    bool // Plays on the PAL clock and speed, set before Init
+
+	device *system.Device
+	rom    []byte  // Banks of the data, padded to the load address
+	banks  [8]byte // Bank selected on each 4K from $8000
+	frame  float64 // CPU cycle of the next PLAY call
+}
+
+func NewPlayer(file *NSF, sampleRate int) *Player {
+	player := &Player{Bus: &bus.Bus{}, File: file, PAL: file.PAL()}
+	player.Cpu = cpu6502.New(player.Bus)
+	player.System = system.New(player.Cpu, player.Bus)
+
+	player.Apu = apu2a03.New(apu2a03.FREQUENCY_NTSC, sampleRate)
+	player.Apu.Memory = player.Bus.Read
+	player.Apu.IRQ = player.System.IRQSource()
+	player.device = player.System.Add(player.Apu, 1)
+
+	player.Bus.Map(APU, APU_END, player.device)
+	player.Bus.Map(BANKS, BANKS+7, (*bankswitch)(player))
+	player.Bus.Map(ROM, 0xFFFF, (*rom)(player))
+
+	// the CPU waits in place between the calls
+	player.Bus.LoadRam([]byte{0x4C, IDLE & 0xFF, IDLE >> 8}, IDLE)
+
+	return player
+}
+
+// Resets the memory and the APU and runs INIT for the song, from 1
+func (p *Player) Init(song byte) error {
+	if song < 1 || song > p.File.Songs {
+		return ErrSong
+	}
+
+	padding := int(p.File.Load & (BANK_SIZE - 1))
+	p.banks = p.File.Banks
+
+	if !p.File.Bankswitched() {
+		if p.File.Load < ROM {
+			return ErrLoadAddress
+		}
+
+		padding = int(p.File.Load - ROM)
+		p.banks = [8]byte{0, 1, 2, 3, 4, 5, 6, 7}
+	}
+
+	p.rom = make([]byte, padding+len(p.File.Data))
+	copy(p.rom[padding:], p.File.Data)
+
+	for address := 0; address <= RAM_END; address++ {
+		p.Bus.Write(uint16(address), 0)
+	}
+
+	for address := WRAM; address <= WRAM_END; address++ {
+		p.Bus.Write(uint16(address), 0)
+	}
+
+	p.Apu.Frequency = apu2a03.FREQUENCY_NTSC
+	if p.PAL {
+		p.Apu.Frequency = apu2a03.FREQUENCY_PAL
+	}
+
+	p.Apu.Reset()
+	for register := uint16(APU); register <= APU+0x13; register++ {
+		p.Bus.Write(register, 0)
+	}
+
+	p.Bus.Write(APU+apu2a03.REG_STATUS, 0x00)
+	p.Bus.Write(APU+apu2a03.REG_STATUS, 0x0F)
+	p.Bus.Write(APU+apu2a03.REG_FRAME, 0x40)
+
+	state := p.Cpu.State()
+	state.A, state.X, state.S = song-1, 0, 0xFF
+	if p.PAL {
+		state.X = 1
+	}
+
+	state.Status = cpu6502.FLAG_U | cpu6502.FLAG_I
+	p.Cpu.Restore(state)
+
+	p.call(p.File.Init)
+
+	limit := p.System.Now() + INIT_LIMIT
+	for !p.idle() {
+		if p.System.Now() >= limit {
+			return ErrInitLimit
+		}
+
+		p.System.Tick()
+	}
+
+	p.frame = float64(p.System.Now())
+
+	return nil
+}
+
+// Calls the routine, it returns to the idle loop
+func (p *Player) call(address uint16) {
+	state := p.Cpu.State()
+	state.PC, state.Cicles, state.Address = address, 0, address
+
+	stack := 0x0100 | uint16(state.S)
+	p.Bus.Write(stack, (IDLE-1)>>8)
+	p.Bus.Write(stack-1, (IDLE-1)&0xFF)
+	state.S -= 2
+
+	p.Cpu.Restore(state)
+}
+
+// Whether the called routine returned
+func (p *Player) idle() bool {
+	return p.Cpu.InstructionCompleted() && p.Cpu.PC == IDLE
+}
+
+// CPU cicles between the PLAY calls
+func (p *Player) period() float64 {
+	speed, fallback := p.File.SpeedNTSC, SPEED_NTSC
+	if p.PAL {
+		speed, fallback = p.File.SpeedPAL, SPEED_PAL
+	}
+
+	if speed == 0 {
+		speed = uint16(fallback)
+	}
+
+	return float64(speed) * float64(p.Apu.Frequency) / 1e6
+}
+
+// Calls PLAY for the frame and runs the CPU up to the next one
+// A PLAY that is still running at the next frame isn't called again until it returns
+func (p *Player) Frame() {
+	if p.idle() {
+		p.call(p.File.Play)
+	}
+
+	p.frame += p.period()
+	for float64(p.System.Now()) < p.frame || !p.Cpu.InstructionCompleted() {
+		p.System.Tick()
+	}
+
+	p.device.Sync()
+}
+
+// Plays for the duration after Init, returning the samples of the APU
+func (p *Player) Render(duration time.Duration) []float32 {
+	frames := int(duration.Seconds() * float64(p.Apu.Frequency) / p.period())
+
+	var samples []float32
+	for frame := 0; frame < frames; frame++ {
+		p.Frame()
+		samples = append(samples, p.Apu.Samples()...)
+	}
+
+	return samples
+}
+
+// Bank registers at $5FF8-$5FFF
+type bankswitch Player
+
+func (b *bankswitch) Read(register uint16) byte {
+	return 0
+}
+
+func (b *bankswitch) Write(register uint16, data byte) {
+	b.banks[register&0x07] = data
+}
+
+// Banks of the data switched in $8000-$FFFF
+type rom Player
+
+func (r *rom) Read(register uint16) byte {
+	offset := int(r.banks[register/BANK_SIZE])*BANK_SIZE + int(register%BANK_SIZE)
+	if offset >= len(r.rom) {
+		return 0
+	}
+
+	return r.rom[offset]
+}
+
+func (r *rom) Write(register uint16, data byte) {}
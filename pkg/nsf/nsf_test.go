@@ -0,0 +1,121 @@
+package nsf
+
+import (
+	"testing"
+	"time"
+)
+
+// Header and data of the file
+func build(load uint16, banks [8]byte, data []byte) []byte {
+	header := make([]byte, HEADER_SIZE)
+	copy(header, MAGIC)
+	header[0x05], header[0x06], header[0x07] = 1, 2, 1
+	header[0x08], header[0x09] = byte(load), byte(load>>8)
+	header[0x0A], header[0x0B] = 0x00, 0x80
+	header[0x0C], header[0x0D] = 0x10, 0x80
+	copy(header[0x0E:], "Tone")
+	copy(header[0x2E:], "Nobody")
+	header[0x6E], header[0x6F] = SPEED_NTSC&0xFF, SPEED_NTSC>>8
+	copy(header[0x70:], banks[:])
+
+	return append(header, data...)
+}
+
+// INIT plays 440 Hz on the first pulse channel, PLAY does nothing
+var tone = []byte{
+	0xA9, 0xBF, // LDA #$BF    duty 50%, halt, constant volume 15
+	0x8D, 0x00, 0x40, // STA $4000
+	0xA9, 0xFD, // LDA #$FD    timer 253
+	0x8D, 0x02, 0x40, // STA $4002
+	0xA9, 0x00, // LDA #$00
+	0x8D, 0x03, 0x40, // STA $4003
+	0x60, // RTS
+	0x60, // RTS         PLAY at $8010
+}
+
+func TestParse(t *testing.T) {
+	if _, err := Parse([]byte("NESM")); err != ErrInvalidNSF {
+		t.Errorf("short file parsed, %v", err)
+	}
+
+	file, err := Parse(build(0x8000, [8]byte{}, tone))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if file.Songs != 2 || file.Start != 1 || file.Init != 0x8000 || file.Play != 0x8010 {
+		t.Errorf("unexpected header %+v", file)
+	}
+
+	if file.Name != "Tone" || file.Artist != "Nobody" || file.Copyright != "" {
+		t.Errorf("unexpected texts %q %q %q", file.Name, file.Artist, file.Copyright)
+	}
+
+	if file.Bankswitched() || file.PAL() || len(file.Data) != len(tone) {
+		t.Errorf("unexpected mapping %+v", file)
+	}
+}
+
+func TestTone(t *testing.T) {
+	file, _ := Parse(build(0x8000, [8]byte{}, tone))
+	player := NewPlayer(file, 44100)
+
+	if err := player.Init(3); err != ErrSong {
+		t.Errorf("song 3 of 2 initialized, %v", err)
+	}
+
+	if err := player.Init(1); err != nil {
+		t.Fatal(err)
+	}
+
+	samples := player.Render(time.Second)
+	if len(samples) < 44000 || len(samples) > 44200 {
+		t.Fatalf("expected a second of samples, got %d", len(samples))
+	}
+
+	// rising zero crossings after the filter settles
+	crossings := 0
+	for index := 4410; index < len(samples); index++ {
+		if samples[index-1] < 0 && samples[index] >= 0 {
+			crossings++
+		}
+	}
+
+	// 1789773 / (16 * 254) = 440.4 Hz over 0.9 seconds
+	if crossings < 390 || crossings > 403 {
+		t.Errorf("expected about 396 cycles of the tone, got %d", crossings)
+	}
+}
+
+func TestBankswitching(t *testing.T) {
+	// loaded at $8100, the first bank is padded
+	data := make([]byte, 3*BANK_SIZE)
+	data[0] = 0xAA
+	data[BANK_SIZE-0x100] = 0xBB
+	data[2*BANK_SIZE-0x100+0x20] = 0xCC
+	copy(data[0x1F00:], tone) // INIT at $8000 of bank 2
+
+	file, _ := Parse(build(0x8100, [8]byte{2, 1}, data))
+	if !file.Bankswitched() {
+		t.Fatal("expected bankswitching")
+	}
+
+	player := NewPlayer(file, 44100)
+	if err := player.Init(1); err != nil {
+		t.Fatal(err)
+	}
+
+	if value := player.Bus.Read(0x9000); value != 0xBB {
+		t.Errorf("expected bank 1 at $9000, got $%02X", value)
+	}
+
+	player.Bus.Write(BANKS+1, 0)
+	if value := player.Bus.Read(0x9100); value != 0xAA {
+		t.Errorf("expected bank 0 at $9000, got $%02X at $9100", value)
+	}
+
+	player.Bus.Write(BANKS+7, 2)
+	if value := player.Bus.Read(0xF020); value != 0xCC {
+		t.Errorf("expected bank 2 at $F000, got $%02X at $F020", value)
+	}
+}
@@ -0,0 +1,289 @@
+// Motorola 6821 Peripheral Interface Adapter
+//
+// Two 8-bit ports with data direction registers and the CA1/CA2/CB1/CB2 control lines. Each side
+// has a control register selecting between the port and its direction register, the active edge
+// of the control lines and their interrupts. It has no timers, so it's mapped on the bus directly:
+//
+//	pia := pia6821.New()
+//	dataBus.Map(0xD010, 0xD013, pia)
+//
+// The pins are driven from Go with SetInputA, SetInputB and the Set methods of the control lines.
+package pia6821
+
+// Registers, mirrored every 4 bytes
+const (
+	REG_PORT_A    = 0x0 // Port A or its data direction, by CONTROL_PORT
+	REG_CONTROL_A = 0x1
+	REG_PORT_B    = 0x2 // Port B or its data direction, by CONTROL_PORT
+	REG_CONTROL_B = 0x3
+)
+
+// Control register bits
+const (
+	CONTROL_C1_IRQ      byte = 0x01 // Enables the C1 interrupt
+	CONTROL_C1_POSITIVE byte = 0x02 // C1 active on the rising edge instead of the falling one
+	CONTROL_PORT        byte = 0x04 // Selects the port register, otherwise the data direction
+	CONTROL_C2          byte = 0x38 // C2 mode, see C2_*
+	FLAG_C2             byte = 0x40 // C2 active transition, read only
+	FLAG_C1             byte = 0x80 // C1 active transition, read only
+)
+
+// C2 modes, bits 3-5 of the control register
+const (
+	C2_IN_NEGATIVE     = 0x0
+	C2_IN_NEGATIVE_IRQ = 0x1
+	C2_IN_POSITIVE     = 0x2
+	C2_IN_POSITIVE_IRQ = 0x3
+	C2_HANDSHAKE       = 0x4 // Goes low on the port access until the C1 active edge
+	C2_PULSE           = 0x5 // Pulses low on the port access
+	C2_LOW             = 0x6
+	C2_HIGH            = 0x7
+)
+
+type side struct {
+	output    byte
+	direction byte
+	input     byte // Levels driven from outside
+	control   byte
+
+	c1, c2 bool // Input levels
+	c2Out  bool // Output level
+	irq    bool
+}
+
+func (s *side) pins() byte {
+	return s.output&s.direction | s.input&^s.direction
+}
+
+func (s *side) c2Mode() byte {
+	return s.control & CONTROL_C2 >> 3
+}
+
+type PIA struct {
+	IRQA    func(asserted bool) // Optional, the IRQA output
+	IRQB    func(asserted bool) // Optional, the IRQB output
+	OnPortA func(pins byte)     // Optional, called when the port A pins driven by the PIA change
+	OnPortB func(pins byte)     // Optional, called when the port B pins driven by the PIA change
+	OnCA2   func(level bool)    // Optional, called when CA2 changes as output
+	OnCB2   func(level bool)    // Optional, called when CB2 changes as output
+
+	a, b side
+}
+
+func New() *PIA {
+	pia := &PIA{}
+	pia.Reset()
+
+	return pia
+}
+
+// Clears the registers, the inputs are kept
+func (p *PIA) Reset() {
+	for _, s := range []*side{&p.a, &p.b} {
+		s.output, s.direction, s.control = 0, 0, 0
+		s.c2Out = true
+	}
+
+	p.updateIRQ()
+}
+
+func (p *PIA) PinsA() byte {
+	return p.a.pins()
+}
+
+func (p *PIA) PinsB() byte {
+	return p.b.pins()
+}
+
+// Levels driven from outside on port A, used by the input bits
+func (p *PIA) SetInputA(levels byte) {
+	p.a.input = levels
+}
+
+// Levels driven from outside on port B, used by the input bits
+func (p *PIA) SetInputB(levels byte) {
+	p.b.input = levels
+}
+
+// Output level of CA2, high when it's an input
+func (p *PIA) CA2() bool {
+	return p.a.c2Mode() < C2_HANDSHAKE || p.a.c2Out
+}
+
+// Output level of CB2, high when it's an input
+func (p *PIA) CB2() bool {
+	return p.b.c2Mode() < C2_HANDSHAKE || p.b.c2Out
+}
+
+func (p *PIA) SetCA1(level bool) {
+	p.setC1(&p.a, level, p.OnCA2)
+}
+
+func (p *PIA) SetCA2(level bool) {
+	p.setC2(&p.a, level)
+}
+
+func (p *PIA) SetCB1(level bool) {
+	p.setC1(&p.b, level, p.OnCB2)
+}
+
+func (p *PIA) SetCB2(level bool) {
+	p.setC2(&p.b, level)
+}
+
+func (p *PIA) setC1(s *side, level bool, onC2 func(bool)) {
+	if level == s.c1 {
+		return
+	}
+
+	s.c1 = level
+	if level != (s.control&CONTROL_C1_POSITIVE > 0) {
+		return
+	}
+
+	s.control |= FLAG_C1
+	p.updateIRQ()
+
+	// the active edge completes the handshake
+	if s.c2Mode() == C2_HANDSHAKE {
+		p.setC2Out(s, true, onC2)
+	}
+}
+
+func (p *PIA) setC2(s *side, level bool) {
+	if level == s.c2 {
+		return
+	}
+
+	s.c2 = level
+
+	// the input modes have the positive edge on bit 1 and the interrupt enable on bit 0
+	mode := s.c2Mode()
+	if mode >= C2_HANDSHAKE || level != (mode&C2_IN_POSITIVE > 0) {
+		return
+	}
+
+	s.control |= FLAG_C2
+	p.updateIRQ()
+}
+
+func (p *PIA) setC2Out(s *side, level bool, onC2 func(bool)) {
+	if level == s.c2Out {
+		return
+	}
+
+	s.c2Out = level
+	if onC2 != nil {
+		onC2(level)
+	}
+}
+
+// Handshake of C2 on the port access, the reads of A and the writes of B
+func (p *PIA) access(s *side, onC2 func(bool)) {
+	switch s.c2Mode() {
+	case C2_HANDSHAKE:
+		p.setC2Out(s, false, onC2)
+	case C2_PULSE:
+		p.setC2Out(s, false, onC2)
+		p.setC2Out(s, true, onC2)
+	}
+}
+
+func (p *PIA) updateIRQ() {
+	for _, s := range []*side{&p.a, &p.b} {
+		callback := p.IRQA
+		if s == &p.b {
+			callback = p.IRQB
+		}
+
+		mode := s.c2Mode()
+		asserted := s.control&FLAG_C1 > 0 && s.control&CONTROL_C1_IRQ > 0 ||
+			s.control&FLAG_C2 > 0 && mode < C2_HANDSHAKE && mode&C2_IN_NEGATIVE_IRQ > 0
+
+		if asserted != s.irq {
+			s.irq = asserted
+			if callback != nil {
+				callback(asserted)
+			}
+		}
+	}
+}
+
+func (p *PIA) Read(register uint16) byte {
+	value := p.Peek(register)
+
+	s, onC2 := &p.a, p.OnCA2
+	if register&0x02 > 0 {
+		s, onC2 = &p.b, p.OnCB2
+	}
+
+	// reading the port clears the flags
+	if register&0x01 == 0 && s.control&CONTROL_PORT > 0 {
+		s.control &^= FLAG_C1 | FLAG_C2
+		p.updateIRQ()
+
+		if s == &p.a {
+			p.access(s, onC2)
+		}
+	}
+
+	return value
+}
+
+// Reads the register without clearing the flags
+func (p *PIA) Peek(register uint16) byte {
+	s := &p.a
+	if register&0x02 > 0 {
+		s = &p.b
+	}
+
+	if register&0x01 > 0 {
+		return s.control
+	}
+
+	if s.control&CONTROL_PORT == 0 {
+		return s.direction
+	}
+
+	return s.pins()
+}
+
+func (p *PIA) Write(register uint16, data byte) {
+	s, onPort, onC2 := &p.a, p.OnPortA, p.OnCA2
+	if register&0x02 > 0 {
+		s, onPort, onC2 = &p.b, p.OnPortB, p.OnCB2
+	}
+
+	if register&0x01 > 0 {
+		s.control = s.control&(FLAG_C1|FLAG_C2) | data&^(FLAG_C1|FLAG_C2)
+
+		// the output modes drive C2 at once, the handshake and pulse ones rest high
+		switch mode := s.c2Mode(); {
+		case mode == C2_LOW:
+			p.setC2Out(s, false, onC2)
+		case mode >= C2_HANDSHAKE:
+			p.setC2Out(s, true, onC2)
+		default:
+			s.c2Out = true
+		}
+
+		p.updateIRQ()
+		return
+	}
+
+	before := s.pins()
+
+	if s.control&CONTROL_PORT == 0 {
+		s.direction = data
+	} else {
+		s.output = data
+	}
+
+	if onPort != nil && before != s.pins() {
+		onPort(s.pins())
+	}
+
+	if s == &p.b && s.control&CONTROL_PORT > 0 {
+		p.access(s, onC2)
+	}
+}
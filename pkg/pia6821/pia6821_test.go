@@ -0,0 +1,159 @@
+package pia6821
+
+import "testing"
+
+func TestDirection(t *testing.T) {
+	pia := New()
+
+	// the data direction is selected until CONTROL_PORT is set
+	pia.Write(REG_PORT_A, 0x0F)
+	pia.Write(REG_CONTROL_A, CONTROL_PORT)
+	pia.Write(REG_PORT_A, 0xFF)
+	pia.SetInputA(0xA0)
+
+	if value := pia.Read(REG_PORT_A); value != 0xAF {
+		t.Errorf("expected the outputs on the low bits and the inputs on the high ones, got $%02X", value)
+	}
+
+	pia.Write(REG_CONTROL_A, 0)
+	if value := pia.Read(REG_PORT_A); value != 0x0F {
+		t.Errorf("expected the data direction, got $%02X", value)
+	}
+}
+
+func TestC1Edges(t *testing.T) {
+	pia := New()
+	var irqA, irqB bool
+	pia.IRQA = func(asserted bool) { irqA = asserted }
+	pia.IRQB = func(asserted bool) { irqB = asserted }
+
+	// CA1 active on the falling edge with the interrupt enabled
+	pia.Write(REG_CONTROL_A, CONTROL_PORT|CONTROL_C1_IRQ)
+
+	pia.SetCA1(true)
+	if pia.Peek(REG_CONTROL_A)&FLAG_C1 > 0 || irqA {
+		t.Error("expected the rising edge ignored")
+	}
+
+	pia.SetCA1(false)
+	if pia.Peek(REG_CONTROL_A)&FLAG_C1 == 0 || !irqA {
+		t.Error("expected the falling edge flagged and interrupting")
+	}
+
+	// reading the control doesn't clear the flag, reading the port does
+	pia.Read(REG_CONTROL_A)
+	if pia.Peek(REG_CONTROL_A)&FLAG_C1 == 0 {
+		t.Error("expected the flag kept reading the control")
+	}
+
+	pia.Read(REG_PORT_A)
+	if pia.Peek(REG_CONTROL_A)&FLAG_C1 > 0 || irqA {
+		t.Error("expected the flag cleared reading the port")
+	}
+
+	// CB1 active on the rising edge without the interrupt
+	pia.Write(REG_CONTROL_B, CONTROL_PORT|CONTROL_C1_POSITIVE)
+	pia.SetCB1(true)
+
+	if pia.Peek(REG_CONTROL_B)&FLAG_C1 == 0 || irqB {
+		t.Error("expected the rising edge flagged without interrupt")
+	}
+
+	// the flag isn't written and enabling the interrupt takes it
+	pia.Write(REG_CONTROL_B, CONTROL_PORT|CONTROL_C1_POSITIVE|CONTROL_C1_IRQ)
+	if pia.Peek(REG_CONTROL_B)&FLAG_C1 == 0 || !irqB {
+		t.Error("expected the pending flag interrupting once enabled")
+	}
+
+	pia.Read(REG_PORT_B)
+	if irqB {
+		t.Error("expected the interrupt released reading the port")
+	}
+}
+
+func TestC2Input(t *testing.T) {
+	pia := New()
+	var irq bool
+	pia.IRQA = func(asserted bool) { irq = asserted }
+
+	pia.Write(REG_CONTROL_A, CONTROL_PORT|C2_IN_POSITIVE_IRQ<<3)
+
+	pia.SetCA2(true)
+	if pia.Peek(REG_CONTROL_A)&FLAG_C2 == 0 || !irq {
+		t.Error("expected the rising edge of CA2 flagged and interrupting")
+	}
+
+	if !pia.CA2() {
+		t.Error("expected CA2 high as an input")
+	}
+
+	pia.Read(REG_PORT_A)
+	if pia.Peek(REG_CONTROL_A)&FLAG_C2 > 0 || irq {
+		t.Error("expected the flag cleared reading the port")
+	}
+}
+
+func TestHandshake(t *testing.T) {
+	pia := New()
+
+	// CA2 goes low reading port A until the active edge of CA1
+	pia.Write(REG_CONTROL_A, CONTROL_PORT|C2_HANDSHAKE<<3)
+	pia.SetCA1(true)
+
+	if !pia.CA2() {
+		t.Fatal("expected CA2 resting high")
+	}
+
+	pia.Read(REG_PORT_A)
+	if pia.CA2() {
+		t.Error("expected CA2 low after reading the port")
+	}
+
+	pia.SetCA1(false)
+	if !pia.CA2() {
+		t.Error("expected CA2 high after the CA1 edge")
+	}
+
+	// CB2 goes low writing port B, reading it doesn't
+	pia.Write(REG_CONTROL_B, CONTROL_PORT|C2_HANDSHAKE<<3)
+	pia.SetCB1(true)
+
+	pia.Read(REG_PORT_B)
+	if !pia.CB2() {
+		t.Error("expected CB2 high after reading the port")
+	}
+
+	pia.Write(REG_PORT_B, 0x55)
+	if pia.CB2() {
+		t.Error("expected CB2 low after writing the port")
+	}
+
+	pia.SetCB1(false)
+	if !pia.CB2() {
+		t.Error("expected CB2 high after the CB1 edge")
+	}
+}
+
+func TestPulse(t *testing.T) {
+	pia := New()
+	var levels []bool
+	pia.OnCA2 = func(level bool) { levels = append(levels, level) }
+
+	pia.Write(REG_CONTROL_A, CONTROL_PORT|C2_PULSE<<3)
+	pia.Read(REG_PORT_A)
+
+	if len(levels) != 2 || levels[0] || !levels[1] || !pia.CA2() {
+		t.Errorf("expected CA2 pulsed low and back high, got %v", levels)
+	}
+
+	// the output modes drive the level at once
+	pia.Write(REG_CONTROL_A, CONTROL_PORT|C2_LOW<<3)
+	if pia.CA2() {
+		t.Error("expected CA2 low")
+	}
+
+	pia.Write(REG_CONTROL_A, CONTROL_PORT|C2_HIGH<<3)
+	if !pia.CA2() {
+		t.Error("expected CA2 high")
+	}
+}
@@ -0,0 +1,137 @@
+package replay
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Replay files start with the magic and the format version, then each event is
+// the cicles since the previous one as an uvarint, the kind and its payload
+const (
+	MAGIC   = "6502RPL"
+	VERSION = 1
+)
+
+var ErrInvalidFile = errors.New("not a replay file")
+
+// Writes the events in the replay format, they must be in cycle order
+func Write(writer io.Writer, events []Event) error {
+	output := bufio.NewWriter(writer)
+	output.WriteString(MAGIC)
+	output.WriteByte(VERSION)
+
+	var previous uint64
+	buffer := make([]byte, binary.MaxVarintLen64)
+
+	for _, event := range events {
+		if event.Cycle < previous {
+			return fmt.Errorf("event out of order: %s", event)
+		}
+
+		output.Write(buffer[:binary.PutUvarint(buffer, event.Cycle-previous)])
+		output.WriteByte(byte(event.Kind))
+		previous = event.Cycle
+
+		switch event.Kind {
+		case EVENT_IRQ, EVENT_NMI, EVENT_RESET:
+		case EVENT_INPUT:
+			output.Write([]byte{event.Device, event.Value})
+		case EVENT_WRITE:
+			output.Write([]byte{byte(event.Address & 0x00FF), byte(event.Address >> 8), event.Value})
+		default:
+			return fmt.Errorf("unknown event: %s", event)
+		}
+	}
+
+	return output.Flush()
+}
+
+// Reads the events of a replay file
+func Read(reader io.Reader) ([]Event, error) {
+	input := bufio.NewReader(reader)
+
+	header := make([]byte, len(MAGIC)+1)
+	if _, err := io.ReadFull(input, header); err != nil || string(header[:len(MAGIC)]) != MAGIC {
+		return nil, ErrInvalidFile
+	}
+
+	if header[len(MAGIC)] != VERSION {
+		return nil, fmt.Errorf("unsupported replay version %d", header[len(MAGIC)])
+	}
+
+	var events []Event
+	var cycle uint64
+
+	for {
+		delta, err := binary.ReadUvarint(input)
+		if err == io.EOF {
+			return events, nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		kind, err := input.ReadByte()
+		if err != nil {
+			return nil, io.ErrUnexpectedEOF
+		}
+
+		cycle += delta
+		event := Event{Cycle: cycle, Kind: Kind(kind)}
+
+		var payload []byte
+
+		switch event.Kind {
+		case EVENT_IRQ, EVENT_NMI, EVENT_RESET:
+		case EVENT_INPUT:
+			payload = make([]byte, 2)
+		case EVENT_WRITE:
+			payload = make([]byte, 3)
+		default:
+			return nil, fmt.Errorf("unknown event kind %d at cycle %d", kind, cycle)
+		}
+
+		if _, err := io.ReadFull(input, payload); err != nil {
+			return nil, io.ErrUnexpectedEOF
+		}
+
+		switch event.Kind {
+		case EVENT_INPUT:
+			event.Device, event.Value = payload[0], payload[1]
+		case EVENT_WRITE:
+			event.Address = uint16(payload[1])<<8 | uint16(payload[0])
+			event.Value = payload[2]
+		}
+
+		events = append(events, event)
+	}
+}
+
+func Save(path string, events []Event) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := Write(file, events); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+func Load(path string) ([]Event, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return Read(file)
+}
@@ -0,0 +1,174 @@
+// Deterministic record and replay of the external events
+//
+// The interrupts, the device inputs (e.g. keypresses) and the memory written from outside the CPU
+// are the only things that make two runs of the same program differ. The Recorder logs them keyed
+// by the absolute cycle of the CPU (cpu.Cycles) they happened at and the Replayer injects them back
+// at exactly the same cycles, so a run can be reproduced bit by bit, e.g. to replay a bug report in
+// a test. The debugger logs the interrupts and resets of the front ends through its Recorder:
+//
+//	dbg.Recorder = replay.NewRecorder(replay.Target{Cpu: cpu, Bus: dataBus})
+//	...
+//	dbg.Recorder.Save("bug.rpl")
+//
+// and the Replayer runs with the CPU, ticking it, or as a device of a system.System:
+//
+//	replayer := replay.NewReplayer(replay.Target{Cpu: cpu, Bus: dataBus}, events)
+//	dbg.Tick = replayer.Tick
+package replay
+
+import (
+	"fmt"
+
+	"github.com/costamauricio/6502-emulator/pkg/cpu6502"
+	"github.com/costamauricio/6502-emulator/pkg/system"
+)
+
+type Kind byte
+
+const (
+	EVENT_IRQ   Kind = iota + 1 // Interrupt request
+	EVENT_NMI                   // Non maskable interrupt
+	EVENT_INPUT                 // Device input, e.g. a keypress, Device identifies who receives it
+	EVENT_WRITE                 // Memory written from outside the CPU, e.g. by a keyboard latch
+	EVENT_RESET                 // CPU reset
+)
+
+var kindNames = map[Kind]string{
+	EVENT_IRQ:   "irq",
+	EVENT_NMI:   "nmi",
+	EVENT_INPUT: "input",
+	EVENT_WRITE: "write",
+	EVENT_RESET: "reset",
+}
+
+func (k Kind) String() string {
+	if name, found := kindNames[k]; found {
+		return name
+	}
+
+	return fmt.Sprintf("kind(%d)", byte(k))
+}
+
+// External event injected between two CPU cicles
+type Event struct {
+	Cycle   uint64 // CPU cicles ticked before the event, cpu.Cycles
+	Kind    Kind
+	Device  byte   // EVENT_INPUT only
+	Address uint16 // EVENT_WRITE only
+	Value   byte   // EVENT_INPUT and EVENT_WRITE
+}
+
+func (e Event) String() string {
+	switch e.Kind {
+	case EVENT_INPUT:
+		return fmt.Sprintf("%d: input device %d $%02X", e.Cycle, e.Device, e.Value)
+	case EVENT_WRITE:
+		return fmt.Sprintf("%d: write $%04X = $%02X", e.Cycle, e.Address, e.Value)
+	}
+
+	return fmt.Sprintf("%d: %s", e.Cycle, e.Kind)
+}
+
+// Machine receiving the events
+type Target struct {
+	Cpu   *cpu6502.CPU
+	Bus   cpu6502.Bus                   // Receives the EVENT_WRITE events
+	Input func(device byte, value byte) // Optional, receives the EVENT_INPUT events
+}
+
+func (t *Target) apply(event Event) {
+	switch event.Kind {
+	case EVENT_IRQ:
+		t.Cpu.InterruptRequest()
+	case EVENT_NMI:
+		t.Cpu.NonMaskableInterrupt()
+	case EVENT_INPUT:
+		if t.Input != nil {
+			t.Input(event.Device, event.Value)
+		}
+	case EVENT_WRITE:
+		t.Bus.Write(event.Address, event.Value)
+	case EVENT_RESET:
+		t.Cpu.Reset()
+	}
+}
+
+// Logs the external events injected through it at the current CPU cycle
+type Recorder struct {
+	Target
+	Events []Event
+}
+
+func NewRecorder(target Target) *Recorder {
+	return &Recorder{Target: target}
+}
+
+func (r *Recorder) InterruptRequest() {
+	r.record(Event{Kind: EVENT_IRQ})
+}
+
+func (r *Recorder) NonMaskableInterrupt() {
+	r.record(Event{Kind: EVENT_NMI})
+}
+
+// Sends the value to the device through the target Input
+func (r *Recorder) Input(device byte, value byte) {
+	r.record(Event{Kind: EVENT_INPUT, Device: device, Value: value})
+}
+
+// Writes the memory from outside the CPU
+func (r *Recorder) Write(address uint16, value byte) {
+	r.record(Event{Kind: EVENT_WRITE, Address: address, Value: value})
+}
+
+func (r *Recorder) Reset() {
+	r.record(Event{Kind: EVENT_RESET})
+}
+
+func (r *Recorder) record(event Event) {
+	event.Cycle = r.Cpu.Cycles()
+	r.Events = append(r.Events, event)
+	r.apply(event)
+}
+
+// Saves the recorded events to a replay file
+func (r *Recorder) Save(path string) error {
+	return Save(path, r.Events)
+}
+
+// Injects the recorded events at their CPU cicles
+type Replayer struct {
+	Target
+	Events []Event
+
+	next int
+}
+
+func NewReplayer(target Target, events []Event) *Replayer {
+	return &Replayer{Target: target, Events: events}
+}
+
+// Injects the events due at the current CPU cycle and ticks the CPU
+func (r *Replayer) Tick() {
+	r.Run(r.Cpu.Cycles())
+	r.Cpu.Tick()
+}
+
+// Injects the events due up to the cycle, returning the cycle of the next one
+// Added to a system.System with the divider 1, it injects them between the ticks of the system
+func (r *Replayer) Run(now uint64) uint64 {
+	for ; r.next < len(r.Events) && r.Events[r.next].Cycle <= now; r.next++ {
+		r.apply(r.Events[r.next])
+	}
+
+	if r.Done() {
+		return system.NEVER
+	}
+
+	return r.Events[r.next].Cycle
+}
+
+// Whether all the events were injected
+func (r *Replayer) Done() bool {
+	return r.next >= len(r.Events)
+}
@@ -0,0 +1,115 @@
+package replay
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/costamauricio/6502-emulator/pkg/bus"
+	"github.com/costamauricio/6502-emulator/pkg/cpu6502"
+	"github.com/costamauricio/6502-emulator/pkg/system"
+)
+
+func newTarget() (Target, *bus.Bus) {
+	dataBus := &bus.Bus{}
+	// CLI; loop: INX; LDA $0300; STA $0200,X; JMP loop
+	dataBus.LoadRamFromString("58 E8 AD 00 03 9D 00 02 4C 01 80", 0x8000)
+	// interrupts: INC $10; RTI
+	dataBus.LoadRamFromString("E6 10 40", 0x9000)
+	dataBus.LoadRamFromString("00 90 00 80 00 90", 0xFFFA)
+
+	cpu := cpu6502.New(dataBus)
+
+	return Target{
+		Cpu: cpu,
+		Bus: dataBus,
+		Input: func(device byte, value byte) {
+			dataBus.Write(0x0300, value+device)
+		},
+	}, dataBus
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	target, recorded := newTarget()
+	recorder := NewRecorder(target)
+
+	inject := map[uint64]func(){
+		40:  recorder.InterruptRequest,
+		97:  func() { recorder.Input(1, 0x41) },
+		150: recorder.NonMaskableInterrupt,
+		151: func() { recorder.Write(0x0300, 0x7F) },
+		233: func() { recorder.Input(2, 0x10) },
+		300: recorder.Reset,
+	}
+
+	for recorder.Cpu.Cycles() < 400 {
+		if event, found := inject[recorder.Cpu.Cycles()]; found {
+			event()
+		}
+
+		recorder.Cpu.Tick()
+	}
+
+	var file bytes.Buffer
+	if err := Write(&file, recorder.Events); err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := Read(&file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(events) != 6 || events[3] != (Event{Cycle: 151, Kind: EVENT_WRITE, Address: 0x0300, Value: 0x7F}) {
+		t.Fatalf("unexpected events read %v", events)
+	}
+
+	target, replayed := newTarget()
+	replayer := NewReplayer(target, events)
+	for replayer.Cpu.Cycles() < 400 {
+		replayer.Tick()
+	}
+
+	if !replayer.Done() || replayer.Cpu.State() != recorder.Cpu.State() {
+		t.Errorf("expected the recorded state %+v, got %+v", recorder.Cpu.State(), replayer.Cpu.State())
+	}
+
+	for address := 0; address <= 0xFFFF; address++ {
+		if recorded.Read(uint16(address)) != replayed.Read(uint16(address)) {
+			t.Fatalf("memory differs at $%04X", address)
+		}
+	}
+
+	if replayed.Read(0x0010) != 2 {
+		t.Errorf("expected both interrupts to run, counted %d", replayed.Read(0x0010))
+	}
+}
+
+func TestReplaySystem(t *testing.T) {
+	target, _ := newTarget()
+	recorder := NewRecorder(target)
+
+	for recorder.Cpu.Cycles() < 200 {
+		if recorder.Cpu.Cycles() == 57 {
+			recorder.NonMaskableInterrupt()
+		}
+
+		recorder.Cpu.Tick()
+	}
+
+	// the events are injected between the ticks of the system, at the same cicles
+	target, replayed := newTarget()
+	machine := system.New(target.Cpu, replayed)
+	replayer := NewReplayer(target, recorder.Events)
+	machine.Add(replayer, 1)
+	machine.Run(200)
+
+	if !replayer.Done() || target.Cpu.State() != recorder.Cpu.State() {
+		t.Errorf("expected the recorded state %+v, got %+v", recorder.Cpu.State(), target.Cpu.State())
+	}
+}
+
+func TestInvalidFile(t *testing.T) {
+	if _, err := Read(bytes.NewBufferString("not a replay")); err != ErrInvalidFile {
+		t.Errorf("expected invalid file error, got %v", err)
+	}
+}
@@ -0,0 +1,272 @@
+// MOS 6532 RAM-I/O-Timer
+//
+// 128 bytes of RAM, two 8-bit ports with data direction registers, an interval timer with the
+// 1, 8, 64 and 1024 prescalers and the PA7 edge detection. The RAM and the I/O registers are
+// selected by the RS pin, so they are mapped as two devices:
+//
+//	riot := riot6532.New()
+//	riot.IRQ = machine.IRQSource()
+//	dataBus.Map(0x0080, 0x00FF, riot.RAM())
+//	dataBus.Map(0x0280, 0x029F, machine.Add(riot, 1))
+//
+// The I/O registers are decoded by the address lines A0-A4 like on the chip, e.g. writing the
+// offset $16 starts the timer with the 64 prescaler and $1E also enables its interrupt.
+package riot6532
+
+import (
+	"github.com/costamauricio/6502-emulator/pkg/system"
+)
+
+// I/O registers by the address lines
+const (
+	REG_ORA        = 0x00 // Port A, reads the pins
+	REG_DDRA       = 0x01 // Data direction A, 1 is output
+	REG_ORB        = 0x02 // Port B
+	REG_DDRB       = 0x03 // Data direction B, 1 is output
+	REG_TIMER      = 0x04 // Reads the timer
+	REG_FLAGS      = 0x05 // Reads the interrupt flags, clears the PA7 flag
+	REG_EDGE       = 0x04 // Writes the PA7 edge detect control, see EDGE_*
+	REG_TIMER_1    = 0x14 // Starts the timer decrementing every cycle
+	REG_TIMER_8    = 0x15 // Starts the timer decrementing every 8 cicles
+	REG_TIMER_64   = 0x16 // Starts the timer decrementing every 64 cicles
+	REG_TIMER_1024 = 0x17 // Starts the timer decrementing every 1024 cicles
+	TIMER_IRQ      = 0x08 // Address line enabling the timer interrupt when reading or writing the timer
+)
+
+// PA7 edge detect control, written to REG_EDGE address lines
+const (
+	EDGE_POSITIVE = 0x01 // Detects the rising edges instead of the falling ones
+	EDGE_IRQ      = 0x02 // Enables the PA7 interrupt
+)
+
+// Interrupt flags
+const (
+	FLAG_PA7   byte = 0x40
+	FLAG_TIMER byte = 0x80
+)
+
+var prescalers = [4]uint64{1, 8, 64, 1024}
+
+type port struct {
+	output    byte
+	direction byte
+	input     byte // Levels driven from outside
+}
+
+func (p *port) pins() byte {
+	return p.output&p.direction | p.input&^p.direction
+}
+
+type RIOT struct {
+	IRQ     func(asserted bool) // Optional, the IRQ output, e.g. System.IRQSource
+	OnPortA func(pins byte)     // Optional, called when the port A pins driven by the RIOT change
+	OnPortB func(pins byte)     // Optional, called when the port B pins driven by the RIOT change
+
+	ram  [128]byte
+	a, b port
+
+	flags      byte
+	timerIRQ   bool
+	edgeIRQ    bool
+	edgeRising bool
+	pa7        bool
+	irq        bool
+
+	timer     byte   // Value written
+	prescaler uint64 // Cicles per decrement until the underflow
+	started   uint64 // Cycle the timer was written
+	expired   bool   // The timer passed through zero since it was written
+	now       uint64 // Cycle the RIOT ran last
+}
+
+func New() *RIOT {
+	riot := &RIOT{}
+	riot.Reset()
+
+	return riot
+}
+
+// Clears the ports and the interrupts, the RAM and the timer are kept
+func (r *RIOT) Reset() {
+	r.a, r.b = port{input: 0xFF}, port{input: 0xFF}
+	r.flags, r.timerIRQ, r.edgeIRQ, r.edgeRising = 0, false, false, false
+	r.pa7 = true
+
+	if r.prescaler == 0 {
+		r.prescaler = 1024
+	}
+
+	r.updateIRQ()
+}
+
+// RAM selected by the RS pin, to be mapped on the bus
+func (r *RIOT) RAM() *RAM {
+	return (*RAM)(r)
+}
+
+type RAM RIOT
+
+func (m *RAM) Read(register uint16) byte {
+	return m.ram[register&0x7F]
+}
+
+func (m *RAM) Write(register uint16, data byte) {
+	m.ram[register&0x7F] = data
+}
+
+func (r *RIOT) PinsA() byte {
+	return r.a.pins()
+}
+
+func (r *RIOT) PinsB() byte {
+	return r.b.pins()
+}
+
+// Levels driven from outside on port A, used by the input bits and the PA7 edge detection
+func (r *RIOT) SetInputA(levels byte) {
+	r.a.input = levels
+	r.detectEdge()
+}
+
+// Levels driven from outside on port B, used by the input bits
+func (r *RIOT) SetInputB(levels byte) {
+	r.b.input = levels
+}
+
+func (r *RIOT) detectEdge() {
+	level := r.PinsA()&0x80 > 0
+	if level == r.pa7 {
+		return
+	}
+
+	r.pa7 = level
+	if level == r.edgeRising {
+		r.flags |= FLAG_PA7
+		r.updateIRQ()
+	}
+}
+
+func (r *RIOT) updateIRQ() {
+	asserted := r.flags&FLAG_TIMER > 0 && r.timerIRQ || r.flags&FLAG_PA7 > 0 && r.edgeIRQ
+	if asserted != r.irq {
+		r.irq = asserted
+		if r.IRQ != nil {
+			r.IRQ(asserted)
+		}
+	}
+}
+
+// Cycle the timer passes through zero
+func (r *RIOT) underflow() uint64 {
+	return r.started + (uint64(r.timer)+1)*r.prescaler
+}
+
+// Timer value at the cycle, after passing through zero it decrements every cycle
+func (r *RIOT) timerAt(cycle uint64) byte {
+	if underflow := r.underflow(); cycle >= underflow {
+		return byte(0xFF - (cycle-underflow)%0x100)
+	}
+
+	return r.timer - byte((cycle-r.started)/r.prescaler)
+}
+
+// Sets the timer flag when it passes through zero
+func (r *RIOT) Run(now uint64) uint64 {
+	r.now = now
+
+	underflow := r.underflow()
+	if now < underflow {
+		return underflow
+	}
+
+	if !r.expired {
+		r.expired = true
+		r.flags |= FLAG_TIMER
+		r.updateIRQ()
+	}
+
+	return system.NEVER
+}
+
+func (r *RIOT) Read(register uint16) byte {
+	value := r.Peek(register)
+
+	if register&0x04 > 0 {
+		if register&0x01 > 0 {
+			r.flags &^= FLAG_PA7
+		} else {
+			r.timerIRQ = register&TIMER_IRQ > 0
+
+			// reading the timer after the underflow clears its flag, it keeps decrementing every cycle
+			if r.expired {
+				r.flags &^= FLAG_TIMER
+			}
+		}
+
+		r.updateIRQ()
+	}
+
+	return value
+}
+
+// Reads the register without clearing the flags
+func (r *RIOT) Peek(register uint16) byte {
+	if register&0x04 == 0 {
+		switch register & 0x03 {
+		case REG_ORA:
+			return r.PinsA()
+		case REG_DDRA:
+			return r.a.direction
+		case REG_ORB:
+			return r.b.output&r.b.direction | r.b.input&^r.b.direction
+		}
+
+		return r.b.direction
+	}
+
+	if register&0x01 > 0 {
+		return r.flags
+	}
+
+	return r.timerAt(r.now)
+}
+
+func (r *RIOT) Write(register uint16, data byte) {
+	pinsA, pinsB := r.PinsA(), r.PinsB()
+
+	switch {
+	case register&0x04 == 0:
+		switch register & 0x03 {
+		case REG_ORA:
+			r.a.output = data
+		case REG_DDRA:
+			r.a.direction = data
+		case REG_ORB:
+			r.b.output = data
+		case REG_DDRB:
+			r.b.direction = data
+		}
+
+		r.detectEdge()
+	case register&0x10 > 0:
+		r.timer = data
+		r.prescaler = prescalers[register&0x03]
+		r.started = r.now
+		r.timerIRQ = register&TIMER_IRQ > 0
+		r.expired = false
+		r.flags &^= FLAG_TIMER
+	default:
+		r.edgeRising = register&EDGE_POSITIVE > 0
+		r.edgeIRQ = register&EDGE_IRQ > 0
+	}
+
+	r.updateIRQ()
+
+	if r.OnPortA != nil && pinsA != r.PinsA() {
+		r.OnPortA(r.PinsA())
+	}
+
+	if r.OnPortB != nil && pinsB != r.PinsB() {
+		r.OnPortB(r.PinsB())
+	}
+}
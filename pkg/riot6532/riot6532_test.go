@@ -0,0 +1,90 @@
+package riot6532
+
+import (
+	"testing"
+
+	"github.com/costamauricio/6502-emulator/pkg/system"
+)
+
+func TestTimer(t *testing.T) {
+	riot := New()
+	var irq bool
+	riot.IRQ = func(asserted bool) { irq = asserted }
+
+	riot.Run(100)
+	riot.Write(REG_TIMER_8|TIMER_IRQ, 0x02)
+
+	if next := riot.Run(100); next != 124 {
+		t.Errorf("expected the underflow at cycle 124, got %d", next)
+	}
+
+	riot.Run(115)
+	if irq || riot.Read(REG_TIMER|TIMER_IRQ) != 0x01 {
+		t.Errorf("expected the timer at 1 without interrupt, got $%02X", riot.Peek(REG_TIMER))
+	}
+
+	riot.Run(124)
+	if !irq || riot.Peek(REG_FLAGS) != FLAG_TIMER || riot.Peek(REG_TIMER) != 0xFF {
+		t.Errorf("expected the timer interrupt at $FF, flags = $%02X", riot.Peek(REG_FLAGS))
+	}
+
+	// after the underflow it decrements every cycle, reading clears the flag
+	riot.Run(130)
+	if value := riot.Read(REG_TIMER | TIMER_IRQ); value != 0xF9 || irq || riot.Peek(REG_FLAGS) != 0 {
+		t.Errorf("expected the timer at $F9 with the flag cleared, got $%02X", value)
+	}
+
+	if riot.Run(1000) != system.NEVER || irq {
+		t.Errorf("expected a single interrupt until the timer is written")
+	}
+}
+
+func TestEdgeDetect(t *testing.T) {
+	riot := New()
+	var irq bool
+	riot.IRQ = func(asserted bool) { irq = asserted }
+
+	riot.Write(REG_EDGE|EDGE_IRQ, 0x00)
+	riot.SetInputA(0x7F)
+	if !irq || riot.Peek(REG_FLAGS) != FLAG_PA7 {
+		t.Errorf("expected the PA7 interrupt on the falling edge")
+	}
+
+	riot.Read(REG_FLAGS)
+	if irq {
+		t.Errorf("expected reading the flags to clear the PA7 interrupt")
+	}
+
+	riot.Write(REG_EDGE|EDGE_IRQ|EDGE_POSITIVE, 0x00)
+	riot.SetInputA(0x00)
+	if irq {
+		t.Errorf("expected no interrupt on the falling edge")
+	}
+
+	// PA7 driven as output also triggers it
+	riot.Write(REG_DDRA, 0x80)
+	riot.Write(REG_ORA, 0x80)
+	if !irq {
+		t.Errorf("expected the PA7 interrupt on the rising edge")
+	}
+}
+
+func TestPorts(t *testing.T) {
+	riot := New()
+	var pins byte
+	riot.OnPortB = func(value byte) { pins = value }
+
+	riot.SetInputB(0x0F)
+	riot.Write(REG_DDRB, 0xF0)
+	riot.Write(REG_ORB, 0xA5)
+
+	if pins != 0xAF || riot.Read(REG_ORB) != 0xAF || riot.Read(REG_DDRB) != 0xF0 {
+		t.Errorf("expected port B pins $AF, got $%02X", riot.Read(REG_ORB))
+	}
+
+	ram := riot.RAM()
+	ram.Write(0x80+0x12, 0x42)
+	if ram.Read(0x12) != 0x42 {
+		t.Errorf("expected the RAM mirrored on 128 bytes")
+	}
+}
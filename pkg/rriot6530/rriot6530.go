@@ -0,0 +1,89 @@
+// MOS 6530 ROM-RAM-I/O-Timer
+//
+// 1K of mask ROM, 64 bytes of RAM, two 8-bit ports and the interval timer of the KIM-1. The I/O
+// and the timer behave like the ones of the 6532, so it's a RIOT with the 6530 register decoding
+// and without the PA7 edge detection. The three parts are mapped on their own ranges:
+//
+//	rriot := rriot6530.New(rom)
+//	dataBus.Map(0x1740, 0x177F, machine.Add(rriot, 1))
+//	dataBus.Map(0x1780, 0x17BF, rriot.RAM())
+//	dataBus.Map(0x1C00, 0x1FFF, rriot.ROM)
+package rriot6530
+
+import (
+	"github.com/costamauricio/6502-emulator/pkg/bus"
+	"github.com/costamauricio/6502-emulator/pkg/riot6532"
+)
+
+// I/O registers by the address lines
+const (
+	REG_PAD        = 0x0 // Port A data
+	REG_PADD       = 0x1 // Port A data direction, 1 is output
+	REG_PBD        = 0x2 // Port B data
+	REG_PBDD       = 0x3 // Port B data direction, 1 is output
+	REG_TIMER_1    = 0x4 // Writes start the timer decrementing every cycle
+	REG_TIMER_8    = 0x5
+	REG_TIMER_64   = 0x6
+	REG_TIMER_1024 = 0x7
+	REG_TIMER      = 0x6 // Reads the timer
+	REG_FLAGS      = 0x7 // Reads the timer interrupt flag on bit 7
+	TIMER_IRQ      = 0x8 // Address line enabling the timer interrupt when reading or writing the timer
+	ROM_SIZE       = 0x400
+	RAM_SIZE       = 0x40
+)
+
+type RRIOT struct {
+	*riot6532.RIOT
+	ROM bus.ROM
+}
+
+// The ROM image is cut or padded to 1K
+func New(rom []byte) *RRIOT {
+	image := make(bus.ROM, ROM_SIZE)
+	copy(image, rom)
+
+	return &RRIOT{RIOT: riot6532.New(), ROM: image}
+}
+
+// RIOT register of the 6530 one
+func register(register uint16, write bool) uint16 {
+	if register&0x04 == 0 {
+		return register & 0x03
+	}
+
+	irq := register & TIMER_IRQ
+
+	if write {
+		return riot6532.REG_TIMER_1 + register&0x03 | irq
+	}
+
+	if register&0x01 == 0 {
+		return riot6532.REG_TIMER | irq
+	}
+
+	return riot6532.REG_FLAGS
+}
+
+func (r *RRIOT) Read(address uint16) byte {
+	value := r.RIOT.Read(register(address, false))
+
+	if address&0x05 == 0x05 {
+		return value & riot6532.FLAG_TIMER
+	}
+
+	return value
+}
+
+func (r *RRIOT) Peek(address uint16) byte {
+	value := r.RIOT.Peek(register(address, false))
+
+	if address&0x05 == 0x05 {
+		return value & riot6532.FLAG_TIMER
+	}
+
+	return value
+}
+
+func (r *RRIOT) Write(address uint16, data byte) {
+	r.RIOT.Write(register(address, true), data)
+}
@@ -0,0 +1,71 @@
+package rriot6530
+
+import (
+	"testing"
+
+	"github.com/costamauricio/6502-emulator/pkg/riot6532"
+)
+
+func TestRegisters(t *testing.T) {
+	decoded := []struct {
+		address  uint16
+		write    bool
+		register uint16
+	}{
+		{REG_PAD, false, riot6532.REG_ORA},
+		{REG_PBDD, true, riot6532.REG_DDRB},
+		{REG_TIMER_1, true, riot6532.REG_TIMER_1},
+		{REG_TIMER_1024 | TIMER_IRQ, true, riot6532.REG_TIMER_1024 | riot6532.TIMER_IRQ},
+		{REG_TIMER, false, riot6532.REG_TIMER},
+		{REG_TIMER_1 | TIMER_IRQ, false, riot6532.REG_TIMER | riot6532.TIMER_IRQ},
+		{REG_FLAGS | TIMER_IRQ, false, riot6532.REG_FLAGS},
+		{0x05, false, riot6532.REG_FLAGS},
+	}
+
+	for _, expected := range decoded {
+		if register := register(expected.address, expected.write); register != expected.register {
+			t.Errorf("expected $%02X decoded to $%02X, got $%02X", expected.address, expected.register, register)
+		}
+	}
+}
+
+func TestTimerFlag(t *testing.T) {
+	rriot := New([]byte{0xEA})
+	var irq bool
+	rriot.IRQ = func(asserted bool) { irq = asserted }
+
+	if rriot.ROM[0] != 0xEA || len(rriot.ROM) != ROM_SIZE {
+		t.Errorf("expected the ROM padded to 1K")
+	}
+
+	rriot.Run(100)
+	rriot.Write(REG_TIMER_8|TIMER_IRQ, 0x02)
+
+	rriot.Run(115)
+	if rriot.Read(REG_FLAGS) != 0 || rriot.Read(REG_TIMER|TIMER_IRQ) != 0x01 {
+		t.Errorf("expected the timer at 1 without the flag, got $%02X", rriot.Peek(REG_TIMER))
+	}
+
+	// a falling edge on PA7 sets the 6532 flag, the 6530 only has the timer one
+	rriot.SetInputA(0x80)
+	rriot.SetInputA(0x00)
+	rriot.Run(124)
+
+	if rriot.RIOT.Peek(riot6532.REG_FLAGS)&riot6532.FLAG_PA7 == 0 {
+		t.Fatal("expected the PA7 flag set on the 6532")
+	}
+
+	if value := rriot.Peek(REG_FLAGS); value != riot6532.FLAG_TIMER || !irq {
+		t.Errorf("expected only the timer flag with the interrupt, got $%02X", value)
+	}
+
+	if value := rriot.Read(REG_FLAGS); value != riot6532.FLAG_TIMER {
+		t.Errorf("expected only the timer flag, got $%02X", value)
+	}
+
+	// reading the timer clears the flag
+	rriot.Read(REG_TIMER | TIMER_IRQ)
+	if rriot.Read(REG_FLAGS) != 0 || irq {
+		t.Error("expected the flag cleared reading the timer")
+	}
+}
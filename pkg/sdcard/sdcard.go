@@ -0,0 +1,310 @@
+// SD card in SPI mode
+//
+// The card of the homebrew boards, bit-banged by the program on the pins of a VIA port. It answers
+// the commands of the usual initialization (CMD0, CMD8, ACMD41, CMD58) as an SDHC card, addressed
+// by blocks of 512 bytes, and reads and writes single blocks of a block device image:
+//
+//	card := sdcard.New(blockdev.NewMemory(2048))
+//	sdcard.NewSPI(card, sdcard.WIRING).ConnectA(via)
+//
+// The CRCs aren't checked and the card is never busy longer than a few bytes.
+package sdcard
+
+import (
+	"github.com/costamauricio/6502-emulator/pkg/blockdev"
+	"github.com/costamauricio/6502-emulator/pkg/via6522"
+)
+
+// Commands, sent as 0x40 | index
+const (
+	CMD_GO_IDLE_STATE     = 0
+	CMD_SEND_OP_COND      = 1
+	CMD_SEND_IF_COND      = 8
+	CMD_STOP_TRANSMISSION = 12
+	CMD_SET_BLOCKLEN      = 16
+	CMD_READ_SINGLE_BLOCK = 17
+	CMD_WRITE_BLOCK       = 24
+	CMD_APP_CMD           = 55
+	CMD_READ_OCR          = 58
+	ACMD_SD_SEND_OP_COND  = 41
+)
+
+// R1 response bits
+const (
+	R1_READY           byte = 0x00
+	R1_IDLE            byte = 0x01
+	R1_ILLEGAL_COMMAND byte = 0x04
+	R1_ADDRESS_ERROR   byte = 0x20
+	R1_PARAMETER_ERROR byte = 0x40
+)
+
+// Data tokens
+const (
+	TOKEN_START    byte = 0xFE // Starts a block of data
+	TOKEN_ACCEPTED byte = 0x05 // Data response of a written block
+	TOKEN_ERROR    byte = 0x08 // Replaces the block that can't be read, out of range
+	TOKEN_WRITE    byte = 0xE5 // Data response of a block that can't be written
+	IDLE           byte = 0xFF // Sent with nothing to say
+)
+
+const (
+	COMMAND_SIZE = 6
+	OCR          = 0xC0FF8000 // Powered up SDHC card, 2.7 to 3.6 V
+	BUSY_BYTES   = 2          // Bytes the card holds MISO low after a write
+)
+
+type Card struct {
+	Image blockdev.Image
+
+	idle    bool // Not initialized yet, in the idle state
+	app     bool // The next command is an application one
+	command []byte
+	output  []byte // Bytes queued to the host
+
+	writing  bool   // Waiting the data of a write
+	block    uint32 // Block being written
+	received []byte // Data of the written block with its start token
+}
+
+func New(image blockdev.Image) *Card {
+	card := &Card{Image: image}
+	card.Reset()
+
+	return card
+}
+
+// Powers the card up, it needs the initialization again
+func (c *Card) Reset() {
+	c.idle, c.app, c.writing = true, false, false
+	c.command, c.output, c.received = nil, nil, nil
+}
+
+// Byte the card sends on the next transfer
+func (c *Card) Next() byte {
+	if len(c.output) == 0 {
+		return IDLE
+	}
+
+	return c.output[0]
+}
+
+// Exchanges a byte with the card selected, returning the one it sent
+func (c *Card) Transfer(in byte) byte {
+	out := c.Next()
+	if len(c.output) > 0 {
+		c.output = c.output[1:]
+	}
+
+	switch {
+	case c.writing:
+		c.receive(in)
+	case len(c.command) == 0 && in&0xC0 != 0x40:
+		// waits the start of a command
+	default:
+		// a command aborts what was being sent
+		if len(c.command) == 0 {
+			c.output = nil
+		}
+
+		c.command = append(c.command, in)
+		if len(c.command) == COMMAND_SIZE {
+			c.execute()
+			c.command = nil
+		}
+	}
+
+	return out
+}
+
+// Drops the partial command, when the card is deselected
+func (c *Card) Deselect() {
+	c.command = nil
+}
+
+func (c *Card) r1(flags byte) byte {
+	if c.idle {
+		flags |= R1_IDLE
+	}
+
+	return flags
+}
+
+// Queues the response after a byte of wait
+func (c *Card) respond(response ...byte) {
+	c.output = append([]byte{IDLE}, response...)
+}
+
+func (c *Card) execute() {
+	index := c.command[0] & 0x3F
+	argument := uint32(c.command[1])<<24 | uint32(c.command[2])<<16 | uint32(c.command[3])<<8 | uint32(c.command[4])
+
+	app := c.app
+	c.app = false
+
+	switch {
+	case app && index == ACMD_SD_SEND_OP_COND, index == CMD_SEND_OP_COND:
+		c.idle = false
+		c.respond(c.r1(R1_READY))
+	case index == CMD_GO_IDLE_STATE:
+		c.Reset()
+		c.respond(R1_IDLE)
+	case index == CMD_SEND_IF_COND:
+		// echoes the voltage and the check pattern
+		c.respond(c.r1(R1_READY), 0x00, 0x00, byte(argument>>8)&0x0F, byte(argument))
+	case index == CMD_APP_CMD:
+		c.app = true
+		c.respond(c.r1(R1_READY))
+	case index == CMD_READ_OCR:
+		c.respond(c.r1(R1_READY), OCR>>24, OCR>>16&0xFF, OCR>>8&0xFF, OCR&0xFF)
+	case index == CMD_SET_BLOCKLEN:
+		if argument != blockdev.SECTOR_SIZE {
+			c.respond(c.r1(R1_PARAMETER_ERROR))
+			return
+		}
+
+		c.respond(c.r1(R1_READY))
+	case index == CMD_STOP_TRANSMISSION:
+		c.respond(c.r1(R1_READY))
+	case index == CMD_READ_SINGLE_BLOCK && !c.idle:
+		c.read(argument)
+	case index == CMD_WRITE_BLOCK && !c.idle:
+		if c.Image == nil || argument >= c.Image.Sectors() {
+			c.respond(c.r1(R1_ADDRESS_ERROR))
+			return
+		}
+
+		c.respond(c.r1(R1_READY))
+		c.writing, c.block, c.received = true, argument, nil
+	default:
+		c.respond(c.r1(R1_ILLEGAL_COMMAND))
+	}
+}
+
+// Queues the block after the start token, with a dummy CRC
+func (c *Card) read(block uint32) {
+	if c.Image == nil || block >= c.Image.Sectors() {
+		c.respond(c.r1(R1_ADDRESS_ERROR))
+		return
+	}
+
+	data := make([]byte, blockdev.SECTOR_SIZE)
+	if err := blockdev.ReadSector(c.Image, block, data); err != nil {
+		c.respond(c.r1(R1_READY), IDLE, TOKEN_ERROR)
+		return
+	}
+
+	response := append([]byte{c.r1(R1_READY), IDLE, TOKEN_START}, data...)
+	c.respond(append(response, 0x00, 0x00)...)
+}
+
+// Takes the start token, the block and its CRC
+func (c *Card) receive(in byte) {
+	if len(c.received) == 0 && in != TOKEN_START {
+		return
+	}
+
+	c.received = append(c.received, in)
+	if len(c.received) < 1+blockdev.SECTOR_SIZE+2 {
+		return
+	}
+
+	c.writing = false
+
+	response := TOKEN_ACCEPTED
+	if err := blockdev.WriteSector(c.Image, c.block, c.received[1:]); err != nil {
+		response = TOKEN_WRITE
+	}
+
+	c.output = append([]byte{response}, make([]byte, BUSY_BYTES)...)
+	c.received = nil
+}
+
+// Pins of the card on the port
+type Wiring struct {
+	CS   byte // Chip select, active low
+	SCK  byte
+	MOSI byte
+	MISO byte // Input of the port
+}
+
+// Wiring of the bit-banged SPI on the low bits of the port and MISO on bit 7, read with BIT
+var WIRING = Wiring{SCK: 0x01, MOSI: 0x02, CS: 0x04, MISO: 0x80}
+
+// SPI mode 0 bus bit-banged on the port pins
+type SPI struct {
+	Card   *Card
+	Wiring Wiring
+
+	selected bool
+	sck      bool
+	in       byte // Bits received of the byte
+	out      byte // Bits left to send of the byte
+	bits     int
+	shift    bool // The sent byte shifts on the falling edge of SCK
+	reload   bool // The next byte is sent from the falling edge of SCK
+}
+
+func NewSPI(card *Card, wiring Wiring) *SPI {
+	return &SPI{Card: card, Wiring: wiring, out: IDLE}
+}
+
+// Updates the bus with the pins driven by the port, returning the port input levels
+func (s *SPI) Pins(pins byte) byte {
+	selected := pins&s.Wiring.CS == 0
+	sck := pins&s.Wiring.SCK > 0
+
+	switch {
+	case !selected:
+		if s.selected {
+			s.Card.Deselect()
+		}
+	case !s.selected:
+		// the first bit is on MISO before the first edge
+		s.in, s.bits, s.shift, s.reload = 0, 0, false, false
+		s.out = s.Card.Next()
+	case sck && !s.sck:
+		s.in = s.in<<1 | boolBit(pins&s.Wiring.MOSI > 0)
+		s.bits++
+
+		if s.bits < 8 {
+			s.shift = true
+			break
+		}
+
+		s.Card.Transfer(s.in)
+		s.in, s.bits, s.reload = 0, 0, true
+	case !sck && s.sck && s.reload:
+		s.out, s.reload = s.Card.Next(), false
+	case !sck && s.sck && s.shift:
+		s.out, s.shift = s.out<<1, false
+	}
+
+	s.selected, s.sck = selected, sck
+
+	// MISO floats high while the card isn't selected
+	if selected && s.out&0x80 == 0 {
+		return ^s.Wiring.MISO
+	}
+
+	return 0xFF
+}
+
+func boolBit(value bool) byte {
+	if value {
+		return 1
+	}
+
+	return 0
+}
+
+// Wires the card on port A of the VIA, CS, SCK and MOSI must be outputs
+func (s *SPI) ConnectA(via *via6522.VIA) {
+	via.OnPortA = func(pins byte) { via.SetInputA(s.Pins(pins)) }
+	via.SetInputA(s.Pins(via.PinsA()))
+}
+
+// Wires the card on port B of the VIA
+func (s *SPI) ConnectB(via *via6522.VIA) {
+	via.OnPortB = func(pins byte) { via.SetInputB(s.Pins(pins)) }
+	via.SetInputB(s.Pins(via.PinsB()))
+}
@@ -0,0 +1,161 @@
+package sdcard
+
+import (
+	"testing"
+
+	"github.com/costamauricio/6502-emulator/pkg/blockdev"
+	"github.com/costamauricio/6502-emulator/pkg/via6522"
+)
+
+// Sends the command and returns the R1 response, like the host waiting for it
+func command(transfer func(byte) byte, index byte, argument uint32) byte {
+	transfer(0x40 | index)
+	transfer(byte(argument >> 24))
+	transfer(byte(argument >> 16))
+	transfer(byte(argument >> 8))
+	transfer(byte(argument))
+	transfer(0x95)
+
+	for tries := 0; tries < 8; tries++ {
+		if response := transfer(IDLE); response != IDLE {
+			return response
+		}
+	}
+
+	return IDLE
+}
+
+// Initializes the card and reads and writes a block
+func exercise(t *testing.T, transfer func(byte) byte, image blockdev.Memory) {
+	if response := command(transfer, CMD_GO_IDLE_STATE, 0); response != R1_IDLE {
+		t.Fatalf("expected the card idle, got $%02X", response)
+	}
+
+	if response := command(transfer, CMD_SEND_IF_COND, 0x1AA); response != R1_IDLE {
+		t.Fatalf("expected CMD8 accepted, got $%02X", response)
+	}
+
+	if echo := []byte{transfer(IDLE), transfer(IDLE), transfer(IDLE), transfer(IDLE)}; echo[2] != 0x01 || echo[3] != 0xAA {
+		t.Errorf("expected the check pattern, got % X", echo)
+	}
+
+	if response := command(transfer, CMD_READ_SINGLE_BLOCK, 0); response != R1_IDLE|R1_ILLEGAL_COMMAND {
+		t.Errorf("expected the read refused before the initialization, got $%02X", response)
+	}
+
+	command(transfer, CMD_APP_CMD, 0)
+	if response := command(transfer, ACMD_SD_SEND_OP_COND, 0x40000000); response != R1_READY {
+		t.Fatalf("expected the card ready, got $%02X", response)
+	}
+
+	if response := command(transfer, CMD_READ_OCR, 0); response != R1_READY || transfer(IDLE)&0x40 == 0 {
+		t.Errorf("expected an SDHC card, got $%02X", response)
+	}
+
+	// reads block 1
+	if response := command(transfer, CMD_READ_SINGLE_BLOCK, 1); response != R1_READY {
+		t.Fatalf("expected the read accepted, got $%02X", response)
+	}
+
+	for token := transfer(IDLE); token != TOKEN_START; token = transfer(IDLE) {
+		if token != IDLE {
+			t.Fatalf("expected the start token, got $%02X", token)
+		}
+	}
+
+	for index := 0; index < blockdev.SECTOR_SIZE; index++ {
+		if value, expected := transfer(IDLE), image[blockdev.SECTOR_SIZE+index]; value != expected {
+			t.Fatalf("expected $%02X at %d, got $%02X", expected, index, value)
+		}
+	}
+
+	transfer(IDLE)
+	transfer(IDLE)
+
+	// writes block 2
+	if response := command(transfer, CMD_WRITE_BLOCK, 2); response != R1_READY {
+		t.Fatalf("expected the write accepted, got $%02X", response)
+	}
+
+	transfer(IDLE)
+	transfer(TOKEN_START)
+	for index := 0; index < blockdev.SECTOR_SIZE; index++ {
+		transfer(byte(index) ^ 0x5A)
+	}
+
+	transfer(0x00)
+	transfer(0x00)
+
+	if response := transfer(IDLE) & 0x1F; response != TOKEN_ACCEPTED {
+		t.Fatalf("expected the block accepted, got $%02X", response)
+	}
+
+	for busy := transfer(IDLE); busy == 0x00; busy = transfer(IDLE) {
+	}
+
+	if image[2*blockdev.SECTOR_SIZE] != 0x5A || image[3*blockdev.SECTOR_SIZE-1] != 0xA5 {
+		t.Error("expected the block written")
+	}
+
+	if response := command(transfer, CMD_READ_SINGLE_BLOCK, 3); response != R1_ADDRESS_ERROR {
+		t.Errorf("expected the block out of range, got $%02X", response)
+	}
+}
+
+func image() blockdev.Memory {
+	image := blockdev.NewMemory(3)
+	for index := range image {
+		image[index] = byte(index * 7)
+	}
+
+	return image
+}
+
+func TestCard(t *testing.T) {
+	image := image()
+	card := New(image)
+
+	exercise(t, card.Transfer, image)
+}
+
+func TestBitBanged(t *testing.T) {
+	image := image()
+	via := via6522.New()
+	NewSPI(New(image), WIRING).ConnectA(via)
+
+	// deselected with SCK low
+	via.Write(via6522.REG_ORA, WIRING.CS)
+	via.Write(via6522.REG_DDRA, WIRING.CS|WIRING.SCK|WIRING.MOSI)
+	via.Write(via6522.REG_ORA, 0)
+
+	// the program loop: MOSI, SCK up, MISO, SCK down
+	transfer := func(out byte) byte {
+		var in byte
+
+		for bit := 7; bit >= 0; bit-- {
+			mosi := byte(0)
+			if out&(1<<bit) > 0 {
+				mosi = WIRING.MOSI
+			}
+
+			via.Write(via6522.REG_ORA, mosi)
+			via.Write(via6522.REG_ORA, mosi|WIRING.SCK)
+
+			in <<= 1
+			if via.Read(via6522.REG_ORA)&WIRING.MISO > 0 {
+				in |= 1
+			}
+
+			via.Write(via6522.REG_ORA, mosi)
+		}
+
+		return in
+	}
+
+	exercise(t, transfer, image)
+
+	via.Write(via6522.REG_ORA, WIRING.CS)
+	if via.Read(via6522.REG_ORA)&WIRING.MISO == 0 {
+		t.Error("expected MISO high with the card deselected")
+	}
+}
@@ -0,0 +1,199 @@
+package symbols
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Hardware registers and ROM entry points of the known memory maps
+var hardware = map[string][]Symbol{
+	"apple1": {
+		{"KBD", 0xD010, 1},
+		{"KBDCR", 0xD011, 1},
+		{"DSP", 0xD012, 1},
+		{"DSPCR", 0xD013, 1},
+		{"WOZMON", 0xFF00, 0},
+		{"ECHO", 0xFFEF, 0},
+		{"PRBYTE", 0xFFDC, 0},
+		{"PRHEX", 0xFFE5, 0},
+	},
+	"beneater": {
+		{"ACIA_DATA", 0x5000, 1},
+		{"ACIA_STATUS", 0x5001, 1},
+		{"ACIA_CMD", 0x5002, 1},
+		{"ACIA_CTRL", 0x5003, 1},
+		{"PORTB", 0x6000, 1},
+		{"PORTA", 0x6001, 1},
+		{"DDRB", 0x6002, 1},
+		{"DDRA", 0x6003, 1},
+		{"T1CL", 0x6004, 1},
+		{"T1CH", 0x6005, 1},
+		{"T1LL", 0x6006, 1},
+		{"T1LH", 0x6007, 1},
+		{"T2CL", 0x6008, 1},
+		{"T2CH", 0x6009, 1},
+		{"SR", 0x600A, 1},
+		{"ACR", 0x600B, 1},
+		{"PCR", 0x600C, 1},
+		{"IFR", 0x600D, 1},
+		{"IER", 0x600E, 1},
+		{"PORTA_NH", 0x600F, 1},
+	},
+	"kim1": {
+		{"PAD", 0x1700, 1},
+		{"PADD", 0x1701, 1},
+		{"PBD", 0x1702, 1},
+		{"PBDD", 0x1703, 1},
+		{"SAD", 0x1740, 1},
+		{"PADD2", 0x1741, 1},
+		{"SBD", 0x1742, 1},
+		{"PBDD2", 0x1743, 1},
+		{"CLK1T", 0x1744, 1},
+		{"CLK8T", 0x1745, 1},
+		{"CLK64T", 0x1746, 1},
+		{"CLKKT", 0x1747, 1},
+		{"NMIV", 0x17FA, 2},
+		{"RSTV", 0x17FC, 2},
+		{"IRQV", 0x17FE, 2},
+		{"RESET", 0x1C22, 0},
+		{"START", 0x1C4F, 0},
+		{"SCANDS", 0x1F1F, 0},
+		{"GETKEY", 0x1F6A, 0},
+		{"OUTCH", 0x1EA0, 0},
+		{"GETCH", 0x1E5A, 0},
+	},
+	"c64": {
+		{"D6510", 0x0000, 1},
+		{"R6510", 0x0001, 1},
+		{"SCREEN", 0x0400, 1000},
+		{"VIC_SPRITES", 0xD000, 16},
+		{"VIC_SPR_HI_X", 0xD010, 1},
+		{"VIC_CTRL1", 0xD011, 1},
+		{"VIC_RASTER", 0xD012, 1},
+		{"VIC_LPEN_X", 0xD013, 1},
+		{"VIC_LPEN_Y", 0xD014, 1},
+		{"VIC_SPR_ENA", 0xD015, 1},
+		{"VIC_CTRL2", 0xD016, 1},
+		{"VIC_SPR_EXP_Y", 0xD017, 1},
+		{"VIC_MEMPTR", 0xD018, 1},
+		{"VIC_IRR", 0xD019, 1},
+		{"VIC_IMR", 0xD01A, 1},
+		{"VIC_SPR_BG_PRIO", 0xD01B, 1},
+		{"VIC_SPR_MCOLOR", 0xD01C, 1},
+		{"VIC_SPR_EXP_X", 0xD01D, 1},
+		{"VIC_SPR_COLL", 0xD01E, 1},
+		{"VIC_SPR_BG_COLL", 0xD01F, 1},
+		{"VIC_BORDERCOLOR", 0xD020, 1},
+		{"VIC_BG_COLOR0", 0xD021, 1},
+		{"VIC_BG_COLOR1", 0xD022, 1},
+		{"VIC_BG_COLOR2", 0xD023, 1},
+		{"VIC_BG_COLOR3", 0xD024, 1},
+		{"VIC_SPR_MCOLOR0", 0xD025, 1},
+		{"VIC_SPR_MCOLOR1", 0xD026, 1},
+		{"VIC_SPR_COLORS", 0xD027, 8},
+		{"SID_VOICE1", 0xD400, 7},
+		{"SID_VOICE2", 0xD407, 7},
+		{"SID_VOICE3", 0xD40E, 7},
+		{"SID_FILTER_LO", 0xD415, 1},
+		{"SID_FILTER_HI", 0xD416, 1},
+		{"SID_RESONANCE", 0xD417, 1},
+		{"SID_VOLUME", 0xD418, 1},
+		{"COLOR_RAM", 0xD800, 1000},
+		{"CIA1_PRA", 0xDC00, 1},
+		{"CIA1_PRB", 0xDC01, 1},
+		{"CIA1_DDRA", 0xDC02, 1},
+		{"CIA1_DDRB", 0xDC03, 1},
+		{"CIA1_TA_LO", 0xDC04, 1},
+		{"CIA1_TA_HI", 0xDC05, 1},
+		{"CIA1_TB_LO", 0xDC06, 1},
+		{"CIA1_TB_HI", 0xDC07, 1},
+		{"CIA1_ICR", 0xDC0D, 1},
+		{"CIA1_CRA", 0xDC0E, 1},
+		{"CIA1_CRB", 0xDC0F, 1},
+		{"CIA2_PRA", 0xDD00, 1},
+		{"CIA2_PRB", 0xDD01, 1},
+		{"CIA2_DDRA", 0xDD02, 1},
+		{"CIA2_DDRB", 0xDD03, 1},
+		{"CIA2_ICR", 0xDD0D, 1},
+		{"CIA2_CRA", 0xDD0E, 1},
+		{"CIA2_CRB", 0xDD0F, 1},
+		{"CINV", 0x0314, 2},
+		{"CBINV", 0x0316, 2},
+		{"NMINV", 0x0318, 2},
+		{"SCINIT", 0xFF81, 0},
+		{"IOINIT", 0xFF84, 0},
+		{"SETLFS", 0xFFBA, 0},
+		{"SETNAM", 0xFFBD, 0},
+		{"OPEN", 0xFFC0, 0},
+		{"CLOSE", 0xFFC3, 0},
+		{"CHKIN", 0xFFC6, 0},
+		{"CHKOUT", 0xFFC9, 0},
+		{"CLRCHN", 0xFFCC, 0},
+		{"CHRIN", 0xFFCF, 0},
+		{"CHROUT", 0xFFD2, 0},
+		{"LOAD", 0xFFD5, 0},
+		{"SAVE", 0xFFD8, 0},
+		{"STOP", 0xFFE1, 0},
+		{"GETIN", 0xFFE4, 0},
+		{"CLALL", 0xFFE7, 0},
+		{"PLOT", 0xFFF0, 0},
+	},
+	"nes": {
+		{"SQ1_VOL", 0x4000, 1},
+		{"SQ1_SWEEP", 0x4001, 1},
+		{"SQ1_LO", 0x4002, 1},
+		{"SQ1_HI", 0x4003, 1},
+		{"SQ2_VOL", 0x4004, 1},
+		{"SQ2_SWEEP", 0x4005, 1},
+		{"SQ2_LO", 0x4006, 1},
+		{"SQ2_HI", 0x4007, 1},
+		{"TRI_LINEAR", 0x4008, 1},
+		{"TRI_LO", 0x400A, 1},
+		{"TRI_HI", 0x400B, 1},
+		{"NOISE_VOL", 0x400C, 1},
+		{"NOISE_LO", 0x400E, 1},
+		{"NOISE_HI", 0x400F, 1},
+		{"DMC_FREQ", 0x4010, 1},
+		{"DMC_RAW", 0x4011, 1},
+		{"DMC_START", 0x4012, 1},
+		{"DMC_LEN", 0x4013, 1},
+		{"SND_CHN", 0x4015, 1},
+		{"APU_FRAME", 0x4017, 1},
+	},
+}
+
+// Names of the known memory maps
+func Machines() []string {
+	var names []string
+	for name := range hardware {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// Table with the hardware registers of the known memory map
+func Hardware(machine string) (*Table, error) {
+	symbols, found := hardware[strings.ToLower(machine)]
+	if !found {
+		return nil, fmt.Errorf("unknown memory map %q, known: %s", machine, strings.Join(Machines(), ", "))
+	}
+
+	table := New()
+	for _, symbol := range symbols {
+		table.Add(symbol)
+	}
+
+	return table, nil
+}
+
+// Loads the known memory map by name or the label file at the path
+func Open(source string) (*Table, error) {
+	if _, found := hardware[strings.ToLower(source)]; found {
+		return Hardware(source)
+	}
+
+	return Load(source)
+}
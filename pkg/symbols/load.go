@@ -0,0 +1,123 @@
+package symbols
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Loads a label file adding its symbols to the table, the format is detected by line:
+//
+//	al C:8000 .reset        VICE monitor labels, also written by ld65 -Ln (.lbl files)
+//	SCREEN = $0400          assignments, the values can be $hex, 0xhex, %binary or decimal
+//
+// Empty lines and the comments starting with ; or # are ignored
+func (table *Table) Load(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if err := table.Read(file); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	return nil
+}
+
+// Reads the label file format described in Load
+func (table *Table) Read(reader io.Reader) error {
+	scanner := bufio.NewScanner(reader)
+
+	for number := 1; scanner.Scan(); number++ {
+		line := scanner.Text()
+
+		if comment := strings.IndexAny(line, ";#"); comment != -1 {
+			line = line[:comment]
+		}
+
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		symbol, err := parseLine(line)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", number, err)
+		}
+
+		table.Add(symbol)
+	}
+
+	return scanner.Err()
+}
+
+// Loads the label file into a new table
+func Load(path string) (*Table, error) {
+	table := New()
+	return table, table.Load(path)
+}
+
+func parseLine(line string) (Symbol, error) {
+	fields := strings.Fields(line)
+
+	if fields[0] == "al" {
+		if len(fields) != 3 {
+			return Symbol{}, fmt.Errorf("expected \"al address .name\": %q", line)
+		}
+
+		// the address may have a memory space prefix, e.g. "C:"
+		address := fields[1]
+		if colon := strings.IndexByte(address, ':'); colon != -1 {
+			address = address[colon+1:]
+		}
+
+		value, err := strconv.ParseUint(address, 16, 32)
+		if err != nil || value > 0xFFFF {
+			return Symbol{}, fmt.Errorf("invalid address %q", fields[1])
+		}
+
+		return Symbol{Name: strings.TrimPrefix(fields[2], "."), Address: uint16(value)}, nil
+	}
+
+	name, value, found := strings.Cut(line, "=")
+	if !found {
+		return Symbol{}, fmt.Errorf("expected \"name = address\": %q", line)
+	}
+
+	name = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(name), ":"))
+	if name == "" || strings.ContainsAny(name, " \t") {
+		return Symbol{}, fmt.Errorf("invalid symbol name %q", name)
+	}
+
+	address, err := parseAddress(strings.TrimSpace(value))
+	if err != nil {
+		return Symbol{}, err
+	}
+
+	return Symbol{Name: name, Address: address}, nil
+}
+
+func parseAddress(value string) (uint16, error) {
+	base := 10
+
+	switch {
+	case strings.HasPrefix(value, "$"):
+		value, base = value[1:], 16
+	case strings.HasPrefix(value, "0x"), strings.HasPrefix(value, "0X"):
+		value, base = value[2:], 16
+	case strings.HasPrefix(value, "%"):
+		value, base = value[1:], 2
+	}
+
+	parsed, err := strconv.ParseUint(value, base, 16)
+	if err != nil {
+		return 0, fmt.Errorf("invalid address %q", value)
+	}
+
+	return uint16(parsed), nil
+}
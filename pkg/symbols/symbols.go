@@ -0,0 +1,140 @@
+// Symbol tables naming the addresses in the disassembly, traces and expressions
+package symbols
+
+import (
+	"fmt"
+	"sort"
+)
+
+// How far after a symbol without size an address is still named relative to it, e.g. "SCREEN+1"
+const DEFAULT_MAX_OFFSET = 2
+
+type Symbol struct {
+	Name    string
+	Address uint16
+	Size    int // Bytes covered by the symbol, 0 when unknown
+}
+
+type Table struct {
+	MaxOffset int // Used for the symbols without size
+
+	names   map[string]uint16
+	symbols []Symbol // Sorted by address
+	largest int      // Largest symbol size, limits how far back Name searches
+}
+
+func New() *Table {
+	return &Table{MaxOffset: DEFAULT_MAX_OFFSET, names: make(map[string]uint16)}
+}
+
+// Adds the symbol, a symbol with the same name is replaced
+func (table *Table) Add(symbol Symbol) {
+	if _, found := table.names[symbol.Name]; found {
+		table.Remove(symbol.Name)
+	}
+
+	table.names[symbol.Name] = symbol.Address
+
+	if symbol.Size > table.largest {
+		table.largest = symbol.Size
+	}
+
+	index := sort.Search(len(table.symbols), func(index int) bool {
+		current := table.symbols[index]
+		return current.Address > symbol.Address || current.Address == symbol.Address && current.Name > symbol.Name
+	})
+
+	table.symbols = append(table.symbols, Symbol{})
+	copy(table.symbols[index+1:], table.symbols[index:])
+	table.symbols[index] = symbol
+}
+
+// Adds a symbol without size
+func (table *Table) Set(name string, address uint16) {
+	table.Add(Symbol{Name: name, Address: address})
+}
+
+func (table *Table) Remove(name string) bool {
+	if _, found := table.names[name]; !found {
+		return false
+	}
+
+	delete(table.names, name)
+
+	for index, symbol := range table.symbols {
+		if symbol.Name == name {
+			table.symbols = append(table.symbols[:index], table.symbols[index+1:]...)
+			break
+		}
+	}
+
+	return true
+}
+
+// Adds all the symbols of the other table
+func (table *Table) Merge(other *Table) {
+	for _, symbol := range other.symbols {
+		table.Add(symbol)
+	}
+}
+
+// Symbols sorted by address
+func (table *Table) Symbols() []Symbol {
+	return append([]Symbol(nil), table.symbols...)
+}
+
+func (table *Table) Len() int {
+	return len(table.symbols)
+}
+
+// Address of the symbol, it implements expr.Symbols
+func (table *Table) Lookup(name string) (uint16, bool) {
+	address, found := table.names[name]
+	return address, found
+}
+
+// Name of the symbol exactly at the address
+func (table *Table) Label(address uint16) (string, bool) {
+	index := sort.Search(len(table.symbols), func(index int) bool {
+		return table.symbols[index].Address >= address
+	})
+
+	if index < len(table.symbols) && table.symbols[index].Address == address {
+		return table.symbols[index].Name, true
+	}
+
+	return "", false
+}
+
+// Names the address by the symbol at it or relative to the symbol before it, e.g. "SCREEN+1"
+func (table *Table) Name(address uint16) (string, bool) {
+	if label, found := table.Label(address); found {
+		return label, true
+	}
+
+	// the symbols before the address, from the nearest one
+	index := sort.Search(len(table.symbols), func(index int) bool {
+		return table.symbols[index].Address > address
+	})
+
+	for index--; index >= 0; index-- {
+		symbol := table.symbols[index]
+		offset := int(address) - int(symbol.Address)
+
+		limit := symbol.Size - 1
+		if symbol.Size == 0 {
+			limit = table.MaxOffset
+		}
+
+		if offset <= limit {
+			return fmt.Sprintf("%s+%d", symbol.Name, offset), true
+		}
+
+		// sized symbols may cover further than the nearest ones
+		if offset > table.largest && offset > table.MaxOffset {
+			break
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,79 @@
+package symbols
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRead(t *testing.T) {
+	table := New()
+
+	labels := `
+al C:8000 .reset
+al 008010 .print_char
+; assignments
+SCREEN = $0400
+counter := %00010000 # zero page
+LIMIT = 200
+`
+
+	if err := table.Read(strings.NewReader(labels)); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]uint16{"reset": 0x8000, "print_char": 0x8010, "SCREEN": 0x0400, "counter": 0x10, "LIMIT": 200}
+	for name, address := range expected {
+		if found, ok := table.Lookup(name); !ok || found != address {
+			t.Errorf("expected %s at $%04X, got $%04X", name, address, found)
+		}
+	}
+
+	if err := New().Read(strings.NewReader("bogus line")); err == nil {
+		t.Errorf("expected error for invalid line")
+	}
+}
+
+func TestName(t *testing.T) {
+	table := New()
+	table.Set("SCREEN", 0x0400)
+	table.Add(Symbol{Name: "buffer", Address: 0x0200, Size: 16})
+
+	tests := map[uint16]string{
+		0x0400: "SCREEN",
+		0x0401: "SCREEN+1",
+		0x0402: "SCREEN+2",
+		0x0403: "",
+		0x020F: "buffer+15",
+		0x0210: "",
+	}
+
+	for address, expected := range tests {
+		if name, _ := table.Name(address); name != expected {
+			t.Errorf("expected $%04X to be named %q, got %q", address, expected, name)
+		}
+	}
+
+	if _, found := table.Label(0x0401); found {
+		t.Errorf("labels should match only the exact address")
+	}
+
+	table.Set("SCREEN", 0x0800)
+	if name, _ := table.Name(0x0800); name != "SCREEN" || table.Len() != 2 {
+		t.Errorf("expected SCREEN to be replaced")
+	}
+}
+
+func TestHardware(t *testing.T) {
+	table, err := Open("c64")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if name, _ := table.Name(0xD020); name != "VIC_BORDERCOLOR" {
+		t.Errorf("expected VIC_BORDERCOLOR, got %q", name)
+	}
+
+	if _, err := Hardware("unknown"); err == nil {
+		t.Errorf("expected error for unknown memory map")
+	}
+}
@@ -0,0 +1,215 @@
+// Scheduler clocking the devices of a computer alongside the CPU
+//
+// The devices don't get a call per cycle: each one tells when it needs to run again and the
+// scheduler only runs it then, passing the time of its own clock (the CPU cycles divided by the
+// device divider). A device that is accessed by the CPU in between catches up with Sync, and one
+// that only reacts to writes returns NEVER and is woken with Wake. The registered devices can be
+// mapped on the bus, they are synced on each access:
+//
+//	dataBus.Map(0x6000, 0x600F, machine.Add(via, 1))
+package system
+
+import (
+	"math"
+
+	"github.com/costamauricio/6502-emulator/pkg/bus"
+	"github.com/costamauricio/6502-emulator/pkg/cpu6502"
+)
+
+// Returned by the devices that don't need to run until they are woken
+const NEVER uint64 = math.MaxUint64
+
+// Device advancing with the system clock
+type Clocked interface {
+	// Runs the device up to now, in cicles of its clock, returning the cycle it has to run
+	// again or NEVER when it only waits to be woken
+	Run(now uint64) uint64
+}
+
+// Device registered in the system
+type Device struct {
+	Clocked
+	Divider uint64 // CPU cicles per device cycle
+
+	system *System
+	next   uint64 // CPU cycle of the next run
+}
+
+// Current time of the device clock
+func (d *Device) Now() uint64 {
+	return d.system.Now() / d.Divider
+}
+
+// Runs the device up to now, used before the CPU accesses its registers
+func (d *Device) Sync() {
+	d.run(d.system.Now())
+}
+
+// Schedules the device to run at the next cycle
+func (d *Device) Wake() {
+	d.schedule(d.system.Now())
+}
+
+// Reads a register of the device, it must be a bus.Device
+func (d *Device) Read(register uint16) byte {
+	d.Sync()
+	return d.Clocked.(bus.Device).Read(register)
+}
+
+// Writes a register of the device, it must be a bus.Device
+// The device runs again after the write so it can reschedule, e.g. when a timer is started
+func (d *Device) Write(register uint16, data byte) {
+	d.Sync()
+	d.Clocked.(bus.Device).Write(register, data)
+	d.Sync()
+}
+
+// Reads a register without side effects when the device is a bus.Peeker
+func (d *Device) Peek(register uint16) byte {
+	d.Sync()
+
+	if peeker, ok := d.Clocked.(bus.Peeker); ok {
+		return peeker.Peek(register)
+	}
+
+	return d.Clocked.(bus.Device).Read(register)
+}
+
+func (d *Device) run(now uint64) {
+	next := d.Run(now / d.Divider)
+	if next == NEVER {
+		d.schedule(NEVER)
+		return
+	}
+
+	d.schedule(next * d.Divider)
+}
+
+func (d *Device) schedule(cycle uint64) {
+	d.next = cycle
+	if cycle < d.system.next {
+		d.system.next = cycle
+	}
+}
+
+type System struct {
+	Cpu *cpu6502.CPU
+	Bus cpu6502.Bus
+
+	devices []*Device
+	next    uint64 // CPU cycle when the first device runs
+
+	irq int // Sources holding the IRQ line
+	nmi int // Sources holding the NMI line
+
+	nmiPending bool
+}
+
+func New(cpu *cpu6502.CPU, bus cpu6502.Bus) *System {
+	return &System{Cpu: cpu, Bus: bus, next: NEVER}
+}
+
+// Registers the device clocked every divider CPU cicles, 0 or 1 clocks it with the CPU
+// The device first runs at the current cycle
+func (s *System) Add(device Clocked, divider uint64) *Device {
+	if divider == 0 {
+		divider = 1
+	}
+
+	registered := &Device{Clocked: device, Divider: divider, system: s}
+	s.devices = append(s.devices, registered)
+	registered.Wake()
+
+	return registered
+}
+
+// CPU cicles since it was created
+func (s *System) Now() uint64 {
+	return s.Cpu.Cycles()
+}
+
+// Returns the setter of a new IRQ source, the line is asserted while any source holds it
+// The CPU takes the interrupt between instructions while the I flag is clear
+func (s *System) IRQSource() func(asserted bool) {
+	return s.source(&s.irq)
+}
+
+// Returns the setter of a new NMI source, the interrupt is taken when the line goes asserted
+func (s *System) NMISource() func(asserted bool) {
+	return s.source(&s.nmi)
+}
+
+func (s *System) source(line *int) func(asserted bool) {
+	holding := false
+
+	return func(asserted bool) {
+		if asserted == holding {
+			return
+		}
+
+		holding = asserted
+		if !asserted {
+			*line--
+			return
+		}
+
+		if line == &s.nmi && s.nmi == 0 {
+			s.nmiPending = true
+		}
+
+		*line++
+	}
+}
+
+// Whether any source holds the IRQ line
+func (s *System) IRQ() bool {
+	return s.irq != 0
+}
+
+// Ticks the CPU and runs the devices due at the cycle
+func (s *System) Tick() {
+	if s.Cpu.InstructionCompleted() {
+		switch {
+		case s.nmiPending:
+			s.nmiPending = false
+			s.Cpu.NonMaskableInterrupt()
+		case s.irq != 0 && s.Cpu.GetFlag(cpu6502.FLAG_I) == 0:
+			s.Cpu.InterruptRequest()
+		}
+	}
+
+	s.Cpu.Tick()
+
+	if now := s.Now(); now >= s.next {
+		s.runDevices(now)
+	}
+}
+
+func (s *System) runDevices(now uint64) {
+	s.next = NEVER
+
+	for _, device := range s.devices {
+		if device.next <= now {
+			device.run(now)
+		} else if device.next < s.next {
+			s.next = device.next
+		}
+	}
+}
+
+// Ticks the cicles
+func (s *System) Run(cycles uint64) {
+	for index := uint64(0); index < cycles; index++ {
+		s.Tick()
+	}
+}
+
+// Ticks until the current instruction completes, running out the pending cicles first
+func (s *System) Step() {
+	for {
+		s.Tick()
+		if s.Cpu.InstructionCompleted() {
+			break
+		}
+	}
+}
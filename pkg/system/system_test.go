@@ -0,0 +1,124 @@
+package system
+
+import (
+	"testing"
+
+	"github.com/costamauricio/6502-emulator/pkg/bus"
+	"github.com/costamauricio/6502-emulator/pkg/cpu6502"
+)
+
+// Device running every period cicles of its clock
+type periodic struct {
+	period uint64
+	runs   []uint64
+	irq    func(bool)
+}
+
+func (p *periodic) Run(now uint64) uint64 {
+	p.runs = append(p.runs, now)
+
+	if p.irq != nil && len(p.runs) > 1 {
+		p.irq(true)
+	}
+
+	return now + p.period
+}
+
+// Acknowledges the interrupts when the CPU writes to $D000
+type ackBus struct {
+	*bus.Bus
+	ack func()
+}
+
+func (b *ackBus) Write(address uint16, data byte) {
+	if address == 0xD000 {
+		b.ack()
+		return
+	}
+
+	b.Bus.Write(address, data)
+}
+
+func TestDivider(t *testing.T) {
+	dataBus := &bus.Bus{}
+	dataBus.LoadRamFromString("4C 00 80", 0x8000)
+	dataBus.LoadRamFromString("00 80", 0xFFFC)
+
+	system := New(cpu6502.New(dataBus), dataBus)
+	device := &periodic{period: 10}
+	system.Add(device, 4)
+
+	system.Run(400)
+
+	if len(device.runs) != 11 || device.runs[1] != 10 || device.runs[10] != 100 {
+		t.Errorf("expected runs every 10 device cycles, got %v", device.runs)
+	}
+}
+
+func TestInterrupts(t *testing.T) {
+	dataBus := &ackBus{Bus: &bus.Bus{}}
+	// CLI; loop: JMP loop
+	dataBus.LoadRamFromString("58 4C 01 80", 0x8000)
+	// irq: INC $10; STA $D000; RTI
+	dataBus.LoadRamFromString("E6 10 8D 00 D0 40", 0x9000)
+	// nmi: JMP nmi
+	dataBus.LoadRamFromString("4C 00 A0", 0xA000)
+	dataBus.LoadRamFromString("00 A0 00 80 00 90", 0xFFFA)
+
+	system := New(cpu6502.New(dataBus), dataBus)
+	irq := system.IRQSource()
+	dataBus.ack = func() { irq(false) }
+
+	system.Add(&periodic{period: 100, irq: irq}, 1)
+	system.Run(1000)
+
+	// the timer runs at the cycles 1, 101, ... 901 and only interrupts from the second run
+	if dataBus.Read(0x0010) != 9 || system.IRQ() {
+		t.Errorf("expected 9 interrupts handled, got %d", dataBus.Read(0x0010))
+	}
+
+	for !system.Cpu.InstructionCompleted() {
+		system.Tick()
+	}
+
+	nmi := system.NMISource()
+	nmi(true)
+	system.Step()
+
+	if system.Cpu.PC != 0xA000 {
+		t.Errorf("expected the NMI to jump to the vector, PC = $%04X", system.Cpu.PC)
+	}
+
+	// a held line doesn't interrupt again
+	system.Cpu.PC = 0x8001
+	system.Step()
+
+	if system.Cpu.PC != 0x8001 {
+		t.Errorf("expected no new NMI while held, PC = $%04X", system.Cpu.PC)
+	}
+}
+
+func TestManySources(t *testing.T) {
+	system := New(cpu6502.New(&bus.Bus{}), &bus.Bus{})
+
+	var sources []func(bool)
+	for index := 0; index < 100; index++ {
+		sources = append(sources, system.IRQSource())
+	}
+
+	sources[99](true)
+	sources[99](true)
+	sources[70](true)
+	sources[99](false)
+
+	if !system.IRQ() {
+		t.Error("expected the 71st source holding the line")
+	}
+
+	sources[70](false)
+	sources[70](false)
+
+	if system.IRQ() {
+		t.Error("expected the line released")
+	}
+}
@@ -0,0 +1,91 @@
+// Go handlers for the calls to ROM entry points
+//
+// The machines without their full ROM, or with routines that are easier to serve from the host
+// (the bit-banged serial of the KIM-1, the KERNAL I/O of the C64), register a handler at the entry
+// address. When an instruction is about to be fetched there the handler runs instead and the
+// subroutine returns like with RTS:
+//
+//	traps := trap.New(cpu, dataBus)
+//	traps.Set(0xFFD2, func(cpu *cpu6502.CPU) bool {
+//		os.Stdout.Write([]byte{cpu.A})
+//		return true
+//	})
+//	for {
+//		traps.Check()
+//		machine.Tick()
+//	}
+package trap
+
+import (
+	"github.com/costamauricio/6502-emulator/pkg/cpu6502"
+)
+
+// Cicles taken by the return of a served call, like RTS
+const RETURN_CICLES = 6
+
+// Cicles waited before calling again a handler that isn't ready, e.g. waiting for a key
+const WAIT_CICLES = 100
+
+// Serves the call with the CPU registers, returning false to be called again later
+type Handler func(cpu *cpu6502.CPU) bool
+
+type Traps struct {
+	Cpu *cpu6502.CPU
+	Bus cpu6502.Bus
+
+	handlers map[uint16]Handler
+}
+
+func New(cpu *cpu6502.CPU, bus cpu6502.Bus) *Traps {
+	return &Traps{Cpu: cpu, Bus: bus, handlers: map[uint16]Handler{}}
+}
+
+// Registers the handler at the address, nil removes it
+func (t *Traps) Set(address uint16, handler Handler) {
+	if handler == nil {
+		delete(t.handlers, address)
+		return
+	}
+
+	t.handlers[address] = handler
+}
+
+// Runs the handler when the CPU is about to execute a trapped address
+// It must be called before each tick, returns whether a handler ran
+func (t *Traps) Check() bool {
+	if !t.Cpu.InstructionCompleted() {
+		return false
+	}
+
+	handler, found := t.handlers[t.Cpu.PC]
+	if !found {
+		return false
+	}
+
+	if !handler(t.Cpu) {
+		t.wait(WAIT_CICLES)
+		return true
+	}
+
+	Return(t.Cpu, t.Bus)
+	t.wait(RETURN_CICLES)
+
+	return true
+}
+
+// Keeps the CPU busy for the cicles, it stays at the same address
+func (t *Traps) wait(cicles int) {
+	state := t.Cpu.State()
+	state.Cicles = cicles
+	state.Address = state.PC
+	t.Cpu.Restore(state)
+}
+
+// Returns from the subroutine like RTS, pulling the address pushed by JSR
+func Return(cpu *cpu6502.CPU, bus cpu6502.Bus) {
+	low := uint16(bus.Read(0x0100 | uint16(cpu.S+1)))
+	high := uint16(bus.Read(0x0100 | uint16(cpu.S+2)))
+
+	cpu.S += 2
+	cpu.PC = (high<<8 | low) + 1
+}
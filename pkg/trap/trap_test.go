@@ -0,0 +1,91 @@
+package trap
+
+import (
+	"testing"
+
+	"github.com/costamauricio/6502-emulator/pkg/bus"
+	"github.com/costamauricio/6502-emulator/pkg/cpu6502"
+)
+
+// JSR $F000; LDX #$01; BRK with the trap at $F000
+func newTraps() *Traps {
+	dataBus := &bus.Bus{}
+	dataBus.LoadRamFromString("20 00 F0 A2 01 00", 0x8000)
+	dataBus.LoadRamFromString("00 80", 0xFFFC)
+
+	cpu := cpu6502.New(dataBus)
+	cpu.Reset()
+
+	return New(cpu, dataBus)
+}
+
+// Ticks until the instruction at the address completes, returning the handler calls by cycle
+func runTo(traps *Traps, address uint16) []uint64 {
+	var calls []uint64
+
+	for ticks := 0; ticks < 10000; ticks++ {
+		if traps.Cpu.InstructionCompleted() && traps.Cpu.PC == address {
+			break
+		}
+
+		if traps.Check() {
+			calls = append(calls, traps.Cpu.Cycles())
+		}
+
+		traps.Cpu.Tick()
+	}
+
+	return calls
+}
+
+func TestReturn(t *testing.T) {
+	traps := newTraps()
+	traps.Set(0xF000, func(cpu *cpu6502.CPU) bool {
+		cpu.A = 0x41
+		return true
+	})
+
+	stack := traps.Cpu.S
+
+	calls := runTo(traps, 0x8005)
+	if len(calls) != 1 || traps.Cpu.A != 0x41 || traps.Cpu.X != 0x01 {
+		t.Fatalf("expected one call returning to LDX, got %v with A = $%02X", calls, traps.Cpu.A)
+	}
+
+	if traps.Cpu.S != stack {
+		t.Errorf("expected the return address pulled, S = $%02X", traps.Cpu.S)
+	}
+
+	// the LDX after the RTS cicles
+	if cycles := traps.Cpu.Cycles() - calls[0]; cycles != RETURN_CICLES+2 {
+		t.Errorf("expected the return to take %d cicles, took %d", RETURN_CICLES, cycles-2)
+	}
+}
+
+func TestWait(t *testing.T) {
+	traps := newTraps()
+
+	ready := 3
+	traps.Set(0xF000, func(cpu *cpu6502.CPU) bool {
+		ready--
+		return ready == 0
+	})
+
+	calls := runTo(traps, 0x8005)
+	if len(calls) != 3 || traps.Cpu.X != 0x01 {
+		t.Fatalf("expected the handler called until ready, got %v", calls)
+	}
+
+	if calls[1]-calls[0] != WAIT_CICLES || calls[2]-calls[1] != WAIT_CICLES {
+		t.Errorf("expected the calls %d cicles apart, got %v", WAIT_CICLES, calls)
+	}
+
+	// removed, the call runs the code at the address
+	traps = newTraps()
+	traps.Set(0xF000, func(cpu *cpu6502.CPU) bool { return true })
+	traps.Set(0xF000, nil)
+
+	if calls := runTo(traps, 0xF000); len(calls) != 0 {
+		t.Errorf("expected no handler, got %v", calls)
+	}
+}
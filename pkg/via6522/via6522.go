@@ -0,0 +1,671 @@
+// MOS 6522 Versatile Interface Adapter
+//
+// Two 8-bit ports with data direction registers, the CA1/CA2/CB1/CB2 handshake lines, the T1 and
+// T2 timers, the shift register and the interrupt flags driving the IRQ output. It is mapped on
+// the bus with its 16 registers (mirrored over the mapped range) and clocked by the system:
+//
+//	via := via6522.New()
+//	via.IRQ = machine.IRQSource()
+//	dataBus.Map(0x6000, 0x600F, machine.Add(via, 1))
+//
+// The timers are advanced by the elapsed cicles when the VIA runs, it only asks to run again
+// when a timer underflows or the shift register shifts a bit. The pins are driven from Go with
+// SetInputA, SetInputB and the Set methods of the control lines.
+package via6522
+
+import (
+	"github.com/costamauricio/6502-emulator/pkg/system"
+)
+
+// Registers, mirrored every 16 bytes
+const (
+	REG_ORB   = 0x0 // Output / input register B
+	REG_ORA   = 0x1 // Output / input register A, with handshake
+	REG_DDRB  = 0x2 // Data direction B, 1 is output
+	REG_DDRA  = 0x3 // Data direction A, 1 is output
+	REG_T1CL  = 0x4 // T1 counter low, writes the latch low
+	REG_T1CH  = 0x5 // T1 counter high, writing starts T1
+	REG_T1LL  = 0x6 // T1 latch low
+	REG_T1LH  = 0x7 // T1 latch high
+	REG_T2CL  = 0x8 // T2 counter low, writes the latch low
+	REG_T2CH  = 0x9 // T2 counter high, writing starts T2
+	REG_SR    = 0xA // Shift register
+	REG_ACR   = 0xB // Auxiliary control
+	REG_PCR   = 0xC // Peripheral control
+	REG_IFR   = 0xD // Interrupt flags
+	REG_IER   = 0xE // Interrupt enable
+	REG_ORA_N = 0xF // Output / input register A, without handshake
+)
+
+// Interrupt flags of IFR and IER
+const (
+	IRQ_CA2 byte = 1 << iota
+	IRQ_CA1
+	IRQ_SR
+	IRQ_CB2
+	IRQ_CB1
+	IRQ_T2
+	IRQ_T1
+	IRQ_ANY // IFR: any enabled flag is set, IER: writing it set enables the flags
+)
+
+// ACR bits
+const (
+	ACR_PA_LATCH  byte = 0x01 // Latches the port A input on the CA1 active edge
+	ACR_PB_LATCH  byte = 0x02 // Latches the port B input on the CB1 active edge
+	ACR_SR_MODE   byte = 0x1C // Shift register mode
+	ACR_T2_COUNT  byte = 0x20 // T2 counts the PB6 falling edges instead of the cicles
+	ACR_T1_FREE   byte = 0x40 // T1 reloads from the latch on each underflow
+	ACR_T1_OUTPUT byte = 0x80 // T1 drives PB7
+)
+
+// Shift register modes, bits 2-4 of the ACR
+const (
+	SR_DISABLED = iota
+	SR_IN_T2
+	SR_IN_CLOCK
+	SR_IN_CB1
+	SR_OUT_FREE // Shifts out at the T2 rate forever, without interrupts
+	SR_OUT_T2
+	SR_OUT_CLOCK
+	SR_OUT_CB1
+)
+
+// CA2/CB2 modes of the PCR
+const (
+	CONTROL_IN_NEGATIVE = iota
+	CONTROL_IN_NEGATIVE_INDEPENDENT
+	CONTROL_IN_POSITIVE
+	CONTROL_IN_POSITIVE_INDEPENDENT
+	CONTROL_HANDSHAKE // Goes low on the port access until the CA1/CB1 active edge
+	CONTROL_PULSE     // Pulses low on the port access
+	CONTROL_LOW
+	CONTROL_HIGH
+)
+
+type port struct {
+	output    byte
+	direction byte
+	input     byte // Levels driven from outside
+	latch     byte
+}
+
+func (p *port) pins() byte {
+	return p.output&p.direction | p.input&^p.direction
+}
+
+type VIA struct {
+	IRQ     func(asserted bool) // Optional, the IRQ output, e.g. System.IRQSource
+	OnPortA func(pins byte)     // Optional, called when the port A pins driven by the VIA change
+	OnPortB func(pins byte)     // Optional, called when the port B pins driven by the VIA change
+	OnCA2   func(level bool)    // Optional, called when CA2 changes as output
+	OnCB2   func(level bool)    // Optional, called when CB2 changes as output, also the shifted out bits
+
+	a, b     port
+	acr, pcr byte
+	ifr, ier byte
+	irq      bool
+
+	ca1, ca2, cb1, cb2 bool // Input levels
+	ca2Out, cb2Out     bool // Output levels
+
+	t1       uint16
+	t1Latch  uint16
+	t1Armed  bool // One-shot interrupt pending
+	t1Reload bool // Free-running T1 underflowed and reloads the next cycle
+	pb7      bool // T1 output
+
+	t2         uint16
+	t2LatchLow byte
+	t2Armed    bool
+
+	sr       byte
+	srBits   int    // Bits left to shift
+	srTimer  uint64 // Cicles until the next bit, with internal clocks
+	srActive bool
+
+	last uint64 // Cycle the VIA ran last
+}
+
+func New() *VIA {
+	via := &VIA{}
+	via.Reset()
+
+	return via
+}
+
+// Clears the registers, the timers, their latches and the shift register keep running
+func (v *VIA) Reset() {
+	v.a.output, v.a.direction = 0, 0
+	v.b.output, v.b.direction = 0, 0
+	v.acr, v.pcr, v.ifr, v.ier = 0, 0, 0, 0
+	v.ca1, v.ca2, v.cb1, v.cb2 = true, true, true, true
+	v.ca2Out, v.cb2Out = true, true
+	v.a.input, v.b.input = 0xFF, 0xFF
+	v.t1Armed, v.t2Armed, v.srActive = false, false, false
+	v.pb7 = true
+	v.updateIRQ()
+}
+
+// Port A pins, the output bits driven by the VIA and the input bits from outside
+func (v *VIA) PinsA() byte {
+	return v.a.pins()
+}
+
+// Port B pins, PB7 is the T1 output when enabled
+func (v *VIA) PinsB() byte {
+	pins := v.b.pins()
+
+	if v.acr&ACR_T1_OUTPUT > 0 {
+		pins &^= 0x80
+		if v.pb7 {
+			pins |= 0x80
+		}
+	}
+
+	return pins
+}
+
+// Levels driven from outside on port A, used by the input bits
+func (v *VIA) SetInputA(levels byte) {
+	v.a.input = levels
+}
+
+// Levels driven from outside on port B, T2 counts the PB6 falling edges in pulse counting mode
+func (v *VIA) SetInputB(levels byte) {
+	falling := v.b.input&0x40 > 0 && levels&0x40 == 0
+	v.b.input = levels
+
+	if falling && v.acr&ACR_T2_COUNT > 0 {
+		v.t2--
+
+		if v.t2 == 0 && v.t2Armed {
+			v.t2Armed = false
+			v.flag(IRQ_T2)
+		}
+	}
+}
+
+func (v *VIA) CA2() bool {
+	if v.ca2Mode() >= CONTROL_HANDSHAKE {
+		return v.ca2Out
+	}
+
+	return v.ca2
+}
+
+func (v *VIA) CB2() bool {
+	if v.cb2Output() {
+		return v.cb2Out
+	}
+
+	return v.cb2
+}
+
+func (v *VIA) ca2Mode() byte {
+	return v.pcr >> 1 & 0x07
+}
+
+func (v *VIA) cb2Mode() byte {
+	return v.pcr >> 5 & 0x07
+}
+
+func (v *VIA) srMode() byte {
+	return v.acr & ACR_SR_MODE >> 2
+}
+
+// CB2 is the shift register data output or an output by the PCR
+func (v *VIA) cb2Output() bool {
+	return v.srMode() >= SR_OUT_FREE || v.cb2Mode() >= CONTROL_HANDSHAKE
+}
+
+func (v *VIA) SetCA1(level bool) {
+	if level == v.ca1 {
+		return
+	}
+
+	v.ca1 = level
+	if level != (v.pcr&0x01 > 0) {
+		return
+	}
+
+	v.flag(IRQ_CA1)
+
+	if v.acr&ACR_PA_LATCH > 0 {
+		v.a.latch = v.PinsA()
+	}
+
+	if v.ca2Mode() == CONTROL_HANDSHAKE {
+		v.setCA2(true)
+	}
+}
+
+func (v *VIA) SetCA2(level bool) {
+	if level == v.ca2 {
+		return
+	}
+
+	v.ca2 = level
+	if mode := v.ca2Mode(); mode < CONTROL_HANDSHAKE && level == (mode >= CONTROL_IN_POSITIVE) {
+		v.flag(IRQ_CA2)
+	}
+}
+
+// The CB1 edges also clock the shift register in the external clock modes
+func (v *VIA) SetCB1(level bool) {
+	if level == v.cb1 {
+		return
+	}
+
+	v.cb1 = level
+
+	switch mode := v.srMode(); {
+	case mode == SR_IN_CB1 && level, mode == SR_OUT_CB1 && !level:
+		if v.srActive {
+			v.shift()
+		}
+	}
+
+	if level != (v.pcr&0x10 > 0) {
+		return
+	}
+
+	v.flag(IRQ_CB1)
+
+	if v.acr&ACR_PB_LATCH > 0 {
+		v.b.latch = v.PinsB()
+	}
+
+	if v.cb2Mode() == CONTROL_HANDSHAKE {
+		v.setCB2(true)
+	}
+}
+
+func (v *VIA) SetCB2(level bool) {
+	if level == v.cb2 {
+		return
+	}
+
+	v.cb2 = level
+	if mode := v.cb2Mode(); !v.cb2Output() && level == (mode >= CONTROL_IN_POSITIVE) {
+		v.flag(IRQ_CB2)
+	}
+}
+
+func (v *VIA) setCA2(level bool) {
+	if level != v.ca2Out {
+		v.ca2Out = level
+		if v.OnCA2 != nil {
+			v.OnCA2(level)
+		}
+	}
+}
+
+func (v *VIA) setCB2(level bool) {
+	if level != v.cb2Out {
+		v.cb2Out = level
+		if v.OnCB2 != nil {
+			v.OnCB2(level)
+		}
+	}
+}
+
+// Runs the handshake of the CA2/CB2 output modes on a port access
+func handshake(mode byte, set func(bool)) {
+	switch mode {
+	case CONTROL_HANDSHAKE:
+		set(false)
+	case CONTROL_PULSE:
+		set(false)
+		set(true)
+	}
+}
+
+func (v *VIA) flag(flags byte) {
+	v.ifr |= flags
+	v.updateIRQ()
+}
+
+func (v *VIA) clear(flags byte) {
+	v.ifr &^= flags
+	v.updateIRQ()
+}
+
+func (v *VIA) updateIRQ() {
+	asserted := v.ifr&v.ier&0x7F != 0
+	if asserted != v.irq {
+		v.irq = asserted
+		if v.IRQ != nil {
+			v.IRQ(asserted)
+		}
+	}
+}
+
+// Port access clearing the control line flags, the independent CA2/CB2 inputs keep theirs
+func (v *VIA) accessA() {
+	flags := IRQ_CA1
+	if mode := v.ca2Mode(); mode != CONTROL_IN_NEGATIVE_INDEPENDENT && mode != CONTROL_IN_POSITIVE_INDEPENDENT {
+		flags |= IRQ_CA2
+	}
+
+	v.clear(flags)
+}
+
+func (v *VIA) accessB() {
+	flags := IRQ_CB1
+	if mode := v.cb2Mode(); mode != CONTROL_IN_NEGATIVE_INDEPENDENT && mode != CONTROL_IN_POSITIVE_INDEPENDENT {
+		flags |= IRQ_CB2
+	}
+
+	v.clear(flags)
+}
+
+// Calls the callback when the pins change
+func (v *VIA) notify(callback func(byte), before byte, after byte) {
+	if callback != nil && before != after {
+		callback(after)
+	}
+}
+
+func (v *VIA) Read(register uint16) byte {
+	switch register & 0x0F {
+	case REG_ORB:
+		v.accessB()
+	case REG_ORA:
+		v.accessA()
+		handshake(v.ca2Mode(), v.setCA2)
+	case REG_T1CL:
+		v.clear(IRQ_T1)
+	case REG_T2CL:
+		v.clear(IRQ_T2)
+	case REG_SR:
+		v.clear(IRQ_SR)
+		v.startShift()
+	}
+
+	return v.Peek(register)
+}
+
+// Reads the register without clearing flags or running handshakes
+func (v *VIA) Peek(register uint16) byte {
+	switch register & 0x0F {
+	case REG_ORB:
+		input := v.PinsB()
+		if v.acr&ACR_PB_LATCH > 0 {
+			input = v.b.latch
+		}
+
+		// the output bits read the output register, not the pins
+		return v.b.output&v.b.direction | input&^v.b.direction
+	case REG_ORA, REG_ORA_N:
+		if v.acr&ACR_PA_LATCH > 0 {
+			return v.a.latch
+		}
+
+		return v.PinsA()
+	case REG_DDRB:
+		return v.b.direction
+	case REG_DDRA:
+		return v.a.direction
+	case REG_T1CL:
+		return byte(v.t1)
+	case REG_T1CH:
+		return byte(v.t1 >> 8)
+	case REG_T1LL:
+		return byte(v.t1Latch)
+	case REG_T1LH:
+		return byte(v.t1Latch >> 8)
+	case REG_T2CL:
+		return byte(v.t2)
+	case REG_T2CH:
+		return byte(v.t2 >> 8)
+	case REG_SR:
+		return v.sr
+	case REG_ACR:
+		return v.acr
+	case REG_PCR:
+		return v.pcr
+	case REG_IFR:
+		if v.irq {
+			return v.ifr | IRQ_ANY
+		}
+
+		return v.ifr
+	case REG_IER:
+		return v.ier | IRQ_ANY
+	}
+
+	return 0x00
+}
+
+func (v *VIA) Write(register uint16, data byte) {
+	pinsA, pinsB := v.PinsA(), v.PinsB()
+
+	switch register & 0x0F {
+	case REG_ORB:
+		v.b.output = data
+		v.accessB()
+		if v.srMode() < SR_OUT_FREE {
+			handshake(v.cb2Mode(), v.setCB2)
+		}
+	case REG_ORA:
+		v.a.output = data
+		v.accessA()
+		handshake(v.ca2Mode(), v.setCA2)
+	case REG_ORA_N:
+		v.a.output = data
+	case REG_DDRB:
+		v.b.direction = data
+	case REG_DDRA:
+		v.a.direction = data
+	case REG_T1CL, REG_T1LL:
+		v.t1Latch = v.t1Latch&0xFF00 | uint16(data)
+	case REG_T1CH:
+		v.t1Latch = v.t1Latch&0x00FF | uint16(data)<<8
+		v.t1 = v.t1Latch
+		v.t1Armed, v.t1Reload = true, false
+		v.pb7 = false
+		v.clear(IRQ_T1)
+	case REG_T1LH:
+		v.t1Latch = v.t1Latch&0x00FF | uint16(data)<<8
+		v.clear(IRQ_T1)
+	case REG_T2CL:
+		v.t2LatchLow = data
+	case REG_T2CH:
+		v.t2 = uint16(data)<<8 | uint16(v.t2LatchLow)
+		v.t2Armed = true
+		v.clear(IRQ_T2)
+	case REG_SR:
+		v.sr = data
+		v.clear(IRQ_SR)
+		v.startShift()
+	case REG_ACR:
+		v.acr = data
+		if v.srMode() == SR_DISABLED {
+			v.srActive = false
+		}
+	case REG_PCR:
+		v.pcr = data
+
+		switch v.ca2Mode() {
+		case CONTROL_HANDSHAKE, CONTROL_PULSE, CONTROL_HIGH:
+			v.setCA2(true)
+		case CONTROL_LOW:
+			v.setCA2(false)
+		}
+
+		if v.srMode() < SR_OUT_FREE {
+			switch v.cb2Mode() {
+			case CONTROL_HANDSHAKE, CONTROL_PULSE, CONTROL_HIGH:
+				v.setCB2(true)
+			case CONTROL_LOW:
+				v.setCB2(false)
+			}
+		}
+	case REG_IFR:
+		v.clear(data & 0x7F)
+	case REG_IER:
+		if data&IRQ_ANY > 0 {
+			v.ier |= data & 0x7F
+		} else {
+			v.ier &^= data
+		}
+
+		v.updateIRQ()
+	}
+
+	v.notify(v.OnPortA, pinsA, v.PinsA())
+	v.notify(v.OnPortB, pinsB, v.PinsB())
+}
+
+func (v *VIA) startShift() {
+	if v.srMode() == SR_DISABLED {
+		return
+	}
+
+	v.srActive = true
+	v.srBits = 8
+	v.srTimer = v.shiftPeriod()
+}
+
+// Cicles per bit with the internal clocks, the T2 modes shift a bit every two T2 low byte timeouts
+func (v *VIA) shiftPeriod() uint64 {
+	switch v.srMode() {
+	case SR_IN_T2, SR_OUT_FREE, SR_OUT_T2:
+		return 2 * (uint64(v.t2LatchLow) + 2)
+	case SR_IN_CLOCK, SR_OUT_CLOCK:
+		return 2
+	}
+
+	return 0
+}
+
+func (v *VIA) shift() {
+	mode := v.srMode()
+
+	if mode >= SR_OUT_FREE {
+		// shifting out rotates the register
+		bit := v.sr >> 7
+		v.sr = v.sr<<1 | bit
+		v.setCB2(bit > 0)
+	} else {
+		v.sr <<= 1
+		if v.CB2() {
+			v.sr |= 0x01
+		}
+	}
+
+	if mode == SR_OUT_FREE {
+		return
+	}
+
+	if v.srBits--; v.srBits == 0 {
+		v.srActive = false
+		v.flag(IRQ_SR)
+	}
+}
+
+// Advances the timers and the shift register to now, returns the next underflow or shift
+func (v *VIA) Run(now uint64) uint64 {
+	if now > v.last {
+		elapsed := now - v.last
+
+		v.advanceT1(elapsed)
+		v.advanceT2(elapsed)
+		v.advanceShift(elapsed)
+	}
+
+	v.last = now
+
+	next := system.NEVER
+
+	// the free-running T1 toggles PB7 even without interrupts
+	if v.t1Armed || v.acr&ACR_T1_FREE > 0 {
+		until := uint64(v.t1) + 1
+		if v.t1Reload {
+			until = uint64(v.t1Latch) + 2
+		}
+
+		next = now + until
+	}
+
+	if v.t2Armed && v.acr&ACR_T2_COUNT == 0 {
+		if until := now + uint64(v.t2) + 1; until < next {
+			next = until
+		}
+	}
+
+	if v.srActive && v.shiftPeriod() > 0 {
+		if until := now + v.srTimer; until < next {
+			next = until
+		}
+	}
+
+	return next
+}
+
+func (v *VIA) advanceT1(cycles uint64) {
+	for cycles > 0 {
+		if v.t1Reload {
+			v.t1Reload = false
+			v.t1 = v.t1Latch
+			cycles--
+			continue
+		}
+
+		if cycles <= uint64(v.t1) {
+			v.t1 -= uint16(cycles)
+			return
+		}
+
+		// it reaches 0 and underflows to $FFFF the next cycle
+		cycles -= uint64(v.t1) + 1
+		v.t1 = 0xFFFF
+		v.underflowT1()
+	}
+}
+
+func (v *VIA) underflowT1() {
+	pinsB := v.PinsB()
+
+	if v.acr&ACR_T1_FREE > 0 {
+		v.t1Reload = true
+		v.pb7 = !v.pb7
+		v.flag(IRQ_T1)
+	} else if v.t1Armed {
+		v.t1Armed = false
+		v.pb7 = true
+		v.flag(IRQ_T1)
+	}
+
+	v.notify(v.OnPortB, pinsB, v.PinsB())
+}
+
+func (v *VIA) advanceT2(cycles uint64) {
+	if v.acr&ACR_T2_COUNT > 0 {
+		return
+	}
+
+	if v.t2Armed && cycles > uint64(v.t2) {
+		v.t2Armed = false
+		v.flag(IRQ_T2)
+	}
+
+	// it keeps counting after the underflow
+	v.t2 -= uint16(cycles)
+}
+
+func (v *VIA) advanceShift(cycles uint64) {
+	period := v.shiftPeriod()
+
+	for v.srActive && period > 0 && cycles > 0 {
+		if cycles < v.srTimer {
+			v.srTimer -= cycles
+			return
+		}
+
+		cycles -= v.srTimer
+		v.srTimer = period
+		v.shift()
+	}
+}
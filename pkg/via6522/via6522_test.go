@@ -0,0 +1,149 @@
+package via6522
+
+import (
+	"testing"
+
+	"github.com/costamauricio/6502-emulator/pkg/bus"
+	"github.com/costamauricio/6502-emulator/pkg/cpu6502"
+	"github.com/costamauricio/6502-emulator/pkg/system"
+)
+
+func TestTimer1(t *testing.T) {
+	via := New()
+	var irq bool
+	via.IRQ = func(asserted bool) { irq = asserted }
+
+	via.Write(REG_IER, IRQ_ANY|IRQ_T1)
+	via.Write(REG_T1CL, 0x10)
+	via.Write(REG_T1CH, 0x00)
+
+	if next := via.Run(0); next != 0x11 {
+		t.Errorf("expected the underflow at cycle $11, got %d", next)
+	}
+
+	via.Run(0x10)
+	if irq || via.Read(REG_T1CL) != 0x00 {
+		t.Errorf("expected the counter at 0 without interrupt")
+	}
+
+	via.Run(0x11)
+	if !irq || via.Peek(REG_IFR) != IRQ_ANY|IRQ_T1 {
+		t.Errorf("expected the T1 interrupt, IFR = $%02X", via.Peek(REG_IFR))
+	}
+
+	// reading the counter low clears the flag, the one-shot doesn't interrupt again
+	via.Read(REG_T1CL)
+	if irq || via.Run(0x20000) != system.NEVER || irq {
+		t.Errorf("expected a single interrupt in one-shot mode")
+	}
+}
+
+func TestTimer1FreeRunning(t *testing.T) {
+	via := New()
+	var toggles []byte
+	via.OnPortB = func(pins byte) { toggles = append(toggles, pins&0x80) }
+
+	via.Write(REG_ACR, ACR_T1_FREE|ACR_T1_OUTPUT)
+	via.Write(REG_T1CL, 0x04)
+	via.Write(REG_T1CH, 0x00)
+
+	// the period is the latch + 2
+	for now := uint64(0); now <= 18; now++ {
+		via.Run(now)
+	}
+
+	if len(toggles) != 4 || toggles[0] != 0x00 || toggles[1] != 0x80 || toggles[2] != 0x00 || toggles[3] != 0x80 {
+		t.Errorf("expected PB7 to toggle every 6 cicles, got %v", toggles)
+	}
+
+	if via.Peek(REG_IFR)&IRQ_T1 == 0 {
+		t.Errorf("expected the T1 flag set")
+	}
+}
+
+func TestTimer2PulseCounting(t *testing.T) {
+	via := New()
+	via.Write(REG_ACR, ACR_T2_COUNT)
+	via.Write(REG_T2CL, 0x02)
+	via.Write(REG_T2CH, 0x00)
+
+	for pulse := 0; pulse < 2; pulse++ {
+		via.SetInputB(0xBF)
+		via.SetInputB(0xFF)
+	}
+
+	if via.Peek(REG_IFR)&IRQ_T2 == 0 || via.Peek(REG_T2CL) != 0 {
+		t.Errorf("expected the T2 flag after 2 pulses, IFR = $%02X", via.Peek(REG_IFR))
+	}
+}
+
+func TestPortsAndHandshake(t *testing.T) {
+	via := New()
+	var ca2 []bool
+	via.OnCA2 = func(level bool) { ca2 = append(ca2, level) }
+
+	via.Write(REG_DDRA, 0x0F)
+	via.Write(REG_ORA, 0xAA)
+	via.SetInputA(0x50)
+
+	if via.PinsA() != 0x5A || via.Read(REG_ORA) != 0x5A {
+		t.Errorf("expected the output and input bits mixed, got $%02X", via.PinsA())
+	}
+
+	// read handshake: CA2 goes low on the read and back high on the CA1 edge
+	via.Write(REG_PCR, CONTROL_HANDSHAKE<<1)
+	via.Read(REG_ORA)
+	via.SetCA1(false)
+
+	if len(ca2) != 2 || ca2[0] || !ca2[1] || via.Peek(REG_IFR) != IRQ_CA1 {
+		t.Errorf("unexpected handshake %v, IFR = $%02X", ca2, via.Peek(REG_IFR))
+	}
+
+	via.Read(REG_ORA_N)
+	if via.Peek(REG_IFR) != IRQ_CA1 {
+		t.Errorf("expected the no handshake register to keep the flag")
+	}
+}
+
+func TestShiftOut(t *testing.T) {
+	via := New()
+	var bits []bool
+	via.OnCB2 = func(level bool) { bits = append(bits, level) }
+
+	via.Write(REG_ACR, SR_OUT_CLOCK<<2)
+	via.Write(REG_SR, 0xA5)
+
+	for now := uint64(0); now <= 16; now++ {
+		via.Run(now)
+	}
+
+	if via.Peek(REG_IFR)&IRQ_SR == 0 || via.Peek(REG_SR) != 0xA5 {
+		t.Errorf("expected the 8 bits shifted out, SR = $%02X", via.Peek(REG_SR))
+	}
+
+	// the changes of 1 0 1 0 0 1 0 1
+	if len(bits) != 6 {
+		t.Errorf("unexpected CB2 changes %v", bits)
+	}
+}
+
+func TestSystemInterrupt(t *testing.T) {
+	dataBus := &bus.Bus{}
+	// LDA #$C0; STA $600E; LDA #$40; STA $600B; LDA #$30; STA $6004; LDA #$00; STA $6005; CLI; loop: JMP loop
+	dataBus.LoadRamFromString("A9 C0 8D 0E 60 A9 40 8D 0B 60 A9 30 8D 04 60 A9 00 8D 05 60 58 4C 15 80", 0x8000)
+	// irq: INC $10; BIT $6004; RTI
+	dataBus.LoadRamFromString("E6 10 2C 04 60 40", 0x9000)
+	dataBus.LoadRamFromString("00 80 00 90", 0xFFFC)
+
+	machine := system.New(cpu6502.New(dataBus), dataBus)
+	via := New()
+	via.IRQ = machine.IRQSource()
+	dataBus.Map(0x6000, 0x600F, machine.Add(via, 1))
+
+	machine.Run(5000)
+
+	// the free-running T1 interrupts every 50 cicles once the setup starts it, around the cycle 30
+	if count := dataBus.Read(0x0010); count != 99 {
+		t.Errorf("expected an interrupt every 50 cicles, counted %d", count)
+	}
+}
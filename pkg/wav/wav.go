@@ -0,0 +1,55 @@
+// Writer of mono 16-bit PCM WAV files
+//
+// The audio devices produce their samples from -1 to 1, they're written offline so no audio
+// device is needed:
+//
+//	wav.Write(file, apu.Samples(), 44100)
+package wav
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+)
+
+const (
+	HEADER_SIZE     = 44
+	BITS_PER_SAMPLE = 16
+	FORMAT_PCM      = 1
+)
+
+// Writes the header and the samples, clipped to -1 and 1
+func Write(w io.Writer, samples []float32, rate int) error {
+	size := uint32(len(samples) * BITS_PER_SAMPLE / 8)
+
+	header := struct {
+		Riff       [4]byte
+		Size       uint32
+		Wave       [4]byte
+		Fmt        [4]byte
+		FmtSize    uint32
+		Format     uint16
+		Channels   uint16
+		Rate       uint32
+		ByteRate   uint32
+		BlockAlign uint16
+		Bits       uint16
+		Data       [4]byte
+		DataSize   uint32
+	}{
+		[4]byte{'R', 'I', 'F', 'F'}, HEADER_SIZE - 8 + size, [4]byte{'W', 'A', 'V', 'E'},
+		[4]byte{'f', 'm', 't', ' '}, 16, FORMAT_PCM, 1, uint32(rate), uint32(rate * BITS_PER_SAMPLE / 8),
+		BITS_PER_SAMPLE / 8, BITS_PER_SAMPLE, [4]byte{'d', 'a', 't', 'a'}, size,
+	}
+
+	if err := binary.Write(w, binary.LittleEndian, header); err != nil {
+		return err
+	}
+
+	data := make([]int16, len(samples))
+	for index, sample := range samples {
+		data[index] = int16(math.Round(math.Max(-1, math.Min(1, float64(sample))) * math.MaxInt16))
+	}
+
+	return binary.Write(w, binary.LittleEndian, data)
+}